@@ -0,0 +1,10 @@
+// Package api holds the OpenAPI document describing flho's HTTP API, which
+// flho serves at /openapi.json.
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3.1 document of the API.
+//
+//go:embed openapi.json
+var OpenAPI []byte
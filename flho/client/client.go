@@ -0,0 +1,394 @@
+// Package client is a Go client for flho's HTTP API, described by the OpenAPI
+// document flho serves at /openapi.json.
+//
+// Requests are retried when they fail to reach flho or are answered with 429,
+// 502, 503 or 504, waiting as long as the Retry-After header asks, or backing
+// off exponentially. POST requests are sent with an Idempotency-Key, the same
+// one on every attempt, so that retrying them is safe; set it yourself with
+// WithIdempotencyKey to make retries of your own safe too.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultRetries is how many times a failed request is retried by default.
+	defaultRetries = 3
+	// defaultMinBackoff and defaultMaxBackoff bound the wait between attempts
+	// when flho does not say how long to wait.
+	defaultMinBackoff = 200 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+)
+
+// Client calls flho's API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	apiKey     string
+	httpClient *http.Client
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with an API key secret.
+func WithAPIKey(secret string) Option {
+	return func(c *Client) {
+		c.apiKey = secret
+	}
+}
+
+// WithHTTPClient sends requests with hc instead of http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithRetries sets how many times a failed request is retried, 3 by default.
+// Zero disables retries.
+func WithRetries(n int) Option {
+	return func(c *Client) {
+		c.retries = max(0, n)
+	}
+}
+
+// WithBackoff sets the wait before the first retry, doubled on every further
+// retry up to max, when flho does not say how long to wait.
+func WithBackoff(minWait, maxWait time.Duration) Option {
+	return func(c *Client) {
+		c.minBackoff = minWait
+		c.maxBackoff = max(minWait, maxWait)
+	}
+}
+
+// New returns a client of the flho at baseURL, such as
+// "http://localhost:4000".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: must be an absolute http or https URL", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retries:    defaultRetries,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey returns a context sending key as the Idempotency-Key of
+// the POST request made with it, so that flho applies the request once
+// however many times it is made, for 24 hours. Without one, every call gets
+// a key of its own.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// Health returns flho's status, "available" when it is up.
+func (c *Client) Health(ctx context.Context) (string, error) {
+	var resp struct {
+		Status string `json:"status"`
+	}
+	err := c.do(ctx, http.MethodGet, "/health", nil, nil, &resp)
+	return resp.Status, err
+}
+
+// InitiateWorkflow starts a run of a workflow and returns its ID.
+func (c *Client) InitiateWorkflow(ctx context.Context, req InitiateRequest) (string, error) {
+	var resp struct {
+		RunID string `json:"run_id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/initiateWorkflow", nil, req, &resp)
+	return resp.RunID, err
+}
+
+// UpdateWorkflowRun advances a run to its next step, setting labels on it;
+// an empty label value removes the label.
+func (c *Client) UpdateWorkflowRun(ctx context.Context, runID string, labels map[string]string) error {
+	body := struct {
+		RunID  string            `json:"run_id"`
+		Labels map[string]string `json:"labels,omitempty"`
+	}{runID, labels}
+	return c.do(ctx, http.MethodPost, "/updateWorkflowRun", nil, body, nil)
+}
+
+// CompleteWorkflowRun completes a run.
+func (c *Client) CompleteWorkflowRun(ctx context.Context, runID string) error {
+	return c.do(ctx, http.MethodPost, "/completeWorkflowRun", nil, runRequest{runID}, nil)
+}
+
+// CancelWorkflowRun cancels a run, passing reason on to its workflow's
+// on_cancel callback.
+func (c *Client) CancelWorkflowRun(ctx context.Context, runID, reason string) error {
+	body := struct {
+		RunID  string `json:"run_id"`
+		Reason string `json:"reason,omitempty"`
+	}{runID, reason}
+	return c.do(ctx, http.MethodPost, "/cancelWorkflowRun", nil, body, nil)
+}
+
+// ResumeWorkflowRun restarts a failed or timed out run at the step it stopped
+// on.
+func (c *Client) ResumeWorkflowRun(ctx context.Context, runID string) error {
+	return c.do(ctx, http.MethodPost, "/resumeWorkflowRun", nil, runRequest{runID}, nil)
+}
+
+// Heartbeat restarts the retry countdown of a run's current step, reporting
+// its progress.
+func (c *Client) Heartbeat(ctx context.Context, runID string, req HeartbeatRequest) error {
+	return c.do(ctx, http.MethodPost, "/runs/"+url.PathEscape(runID)+"/heartbeat", nil, req, nil)
+}
+
+// GetRun returns a run with its history.
+func (c *Client) GetRun(ctx context.Context, runID string) (*Run, error) {
+	var resp struct {
+		Run *Run `json:"run"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/runs/"+url.PathEscape(runID), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Run, nil
+}
+
+// ExportRuns streams the runs matching filter. Iteration stops at the first
+// error, which is yielded with a zero Run.
+func (c *Client) ExportRuns(ctx context.Context, filter RunsFilter) iter.Seq2[Run, error] {
+	return func(yield func(Run, error) bool) {
+		query := filter.values()
+		query.Set("format", "jsonl")
+
+		resp, err := c.send(ctx, http.MethodGet, "/api/runs/export", query, nil)
+		if err != nil {
+			yield(Run{}, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var run Run
+			if err := dec.Decode(&run); err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield(Run{}, fmt.Errorf("reading runs: %w", err))
+				}
+				return
+			}
+			if !yield(run, nil) {
+				return
+			}
+		}
+	}
+}
+
+// StartBulk starts a bulk action. A dry run returns its finished preview;
+// otherwise the job runs in the background, to be followed with BulkJob.
+func (c *Client) StartBulk(ctx context.Context, req BulkRequest) (*BulkJob, error) {
+	var resp struct {
+		Job *BulkJob `json:"job"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/runs/bulk", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Job, nil
+}
+
+// BulkJob returns a bulk job with its results.
+func (c *Client) BulkJob(ctx context.Context, id string) (*BulkJob, error) {
+	var resp struct {
+		Job *BulkJob `json:"job"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/runs/bulk/"+url.PathEscape(id), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Job, nil
+}
+
+// BulkJobs returns the recent bulk jobs, newest first.
+func (c *Client) BulkJobs(ctx context.Context) ([]BulkJob, error) {
+	var resp struct {
+		Jobs []BulkJob `json:"jobs"`
+	}
+	err := c.do(ctx, http.MethodGet, "/api/runs/bulk", nil, nil, &resp)
+	return resp.Jobs, err
+}
+
+// Workflows returns the workflows the API key may see, sorted by name.
+func (c *Client) Workflows(ctx context.Context) ([]Workflow, error) {
+	var resp struct {
+		Workflows []Workflow `json:"workflows"`
+	}
+	err := c.do(ctx, http.MethodGet, "/api/workflows", nil, nil, &resp)
+	return resp.Workflows, err
+}
+
+type runRequest struct {
+	RunID string `json:"run_id"`
+}
+
+// do sends a request and decodes its JSON response into out, if given.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// send sends a request, retrying it as the package documentation describes,
+// and returns its response if it succeeded. The caller closes its body.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	idempotencyKey := ""
+	if method == http.MethodPost {
+		idempotencyKey, _ = ctx.Value(idempotencyKeyContextKey{}).(string)
+		if idempotencyKey == "" {
+			idempotencyKey = uuid.NewString()
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", "flho-go-client")
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.retries {
+				return nil, err
+			}
+			if err := sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+
+		if !retryable(resp.StatusCode) || attempt >= c.retries {
+			defer resp.Body.Close()
+			return nil, responseError(resp)
+		}
+
+		wait, ok := retryAfter(resp.Header.Get("Retry-After"))
+		if !ok {
+			wait = c.backoff(attempt)
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body.Close()
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns how long to wait before the retry following attempt.
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.minBackoff
+	for range attempt {
+		if wait >= c.maxBackoff/2 {
+			return c.maxBackoff
+		}
+		wait *= 2
+	}
+	return min(wait, c.maxBackoff)
+}
+
+// retryable reports whether a request answered with status may succeed if
+// retried.
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as a date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, time.Until(t)), true
+	}
+	return 0, false
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
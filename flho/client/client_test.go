@@ -0,0 +1,351 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recorded is a request received by a test server.
+type recorded struct {
+	method, path, query string
+	header              http.Header
+	body                map[string]any
+}
+
+// testServer serves handler, recording every request it receives.
+func testServer(t *testing.T, handler http.HandlerFunc) (*Client, func() []recorded) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []recorded
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := recorded{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, header: r.Header.Clone()}
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			require.NoError(t, json.Unmarshal(b, &rec.body))
+		}
+		mu.Lock()
+		requests = append(requests, rec)
+		mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL+"/", WithAPIKey("secret"), WithBackoff(time.Millisecond, 4*time.Millisecond))
+	require.NoError(t, err)
+
+	return c, func() []recorded {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recorded(nil), requests...)
+	}
+}
+
+func respond(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:4000", "ftp://example.com", "http://"} {
+		_, err := New(baseURL)
+		require.Error(t, err, baseURL)
+	}
+
+	c, err := New("https://flho.example.com/api/", WithRetries(-1))
+	require.NoError(t, err)
+	require.Equal(t, "/api", c.baseURL.Path)
+	require.Equal(t, 0, c.retries)
+}
+
+func TestClient_Requests(t *testing.T) {
+	c, requests := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/initiateWorkflow":
+			respond(w, http.StatusCreated, `{"run_id": "run-1"}`)
+		case "/api/runs/run-1":
+			respond(w, http.StatusOK, `{"run": {"id": "run-1", "workflow_name": "billing", "namespace": "default", "status": "ongoing",
+				"current_step": 1, "duration": 1500000000, "priority": 2, "labels": {"tier": "gold"},
+				"history": [{"time": "2024-01-01T00:00:00Z", "type": "created", "step": 0, "actor": "ci"}]}}`)
+		case "/api/runs/bulk":
+			if r.Method == http.MethodGet {
+				respond(w, http.StatusOK, `{"jobs": [{"id": "job-1", "action": "cancel", "state": "done", "total": 1, "succeeded": 1}]}`)
+				return
+			}
+			respond(w, http.StatusAccepted, `{"job": {"id": "job-1", "action": "cancel", "state": "running", "total": 1}}`)
+		case "/api/runs/bulk/job-1":
+			respond(w, http.StatusOK, `{"job": {"id": "job-1", "action": "cancel", "state": "done", "total": 1, "succeeded": 1,
+				"results": [{"run_id": "run-1", "status": "cancelled"}]}}`)
+		case "/api/workflows":
+			respond(w, http.StatusOK, `{"workflows": [{"name": "billing", "namespace": "default", "steps": ["charge", "ship"]}]}`)
+		default:
+			respond(w, http.StatusOK, `{"success": "ok"}`)
+		}
+	})
+	ctx := context.Background()
+
+	runID, err := c.InitiateWorkflow(ctx, InitiateRequest{Name: "billing", StartAfter: 90 * time.Minute, Priority: 2, Labels: map[string]string{"tier": "gold"}})
+	require.NoError(t, err)
+	require.Equal(t, "run-1", runID)
+
+	require.NoError(t, c.UpdateWorkflowRun(ctx, "run-1", map[string]string{"tier": ""}))
+	require.NoError(t, c.CompleteWorkflowRun(ctx, "run-1"))
+	require.NoError(t, c.CancelWorkflowRun(ctx, "run-1", "duplicate"))
+	require.NoError(t, c.ResumeWorkflowRun(ctx, "run-1"))
+	progress := 50.0
+	require.NoError(t, c.Heartbeat(ctx, "run-1", HeartbeatRequest{Progress: &progress, Message: "halfway"}))
+
+	run, err := c.GetRun(ctx, "run-1")
+	require.NoError(t, err)
+	require.Equal(t, "billing", run.WorkflowName)
+	require.Equal(t, RunStatusOngoing, run.Status)
+	require.Equal(t, 1500*time.Millisecond, *run.Duration)
+	require.Equal(t, map[string]string{"tier": "gold"}, run.Labels)
+	require.Len(t, run.History, 1)
+	require.Equal(t, "ci", run.History[0].Actor)
+
+	job, err := c.StartBulk(ctx, BulkRequest{Action: BulkCancel, Filter: &RunsFilter{Status: RunStatusFailed}, Reason: "cleanup"})
+	require.NoError(t, err)
+	require.Equal(t, BulkJobRunning, job.State)
+
+	job, err = c.BulkJob(ctx, "job-1")
+	require.NoError(t, err)
+	require.Equal(t, []BulkResult{{RunID: "run-1", Status: RunStatusCancelled}}, job.Results)
+
+	jobs, err := c.BulkJobs(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	workflows, err := c.Workflows(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []Workflow{{Name: "billing", Namespace: "default", Steps: []string{"charge", "ship"}}}, workflows)
+
+	got := requests()
+	expected := []struct {
+		method, path string
+		body         map[string]any
+	}{
+		{"POST", "/initiateWorkflow", map[string]any{"name": "billing", "start_after": "1h30m0s", "priority": 2.0, "labels": map[string]any{"tier": "gold"}}},
+		{"POST", "/updateWorkflowRun", map[string]any{"run_id": "run-1", "labels": map[string]any{"tier": ""}}},
+		{"POST", "/completeWorkflowRun", map[string]any{"run_id": "run-1"}},
+		{"POST", "/cancelWorkflowRun", map[string]any{"run_id": "run-1", "reason": "duplicate"}},
+		{"POST", "/resumeWorkflowRun", map[string]any{"run_id": "run-1"}},
+		{"POST", "/runs/run-1/heartbeat", map[string]any{"progress": 50.0, "message": "halfway"}},
+		{"GET", "/api/runs/run-1", nil},
+		{"POST", "/api/runs/bulk", map[string]any{"action": "cancel", "filter": map[string]any{"status": "failed"}, "reason": "cleanup"}},
+		{"GET", "/api/runs/bulk/job-1", nil},
+		{"GET", "/api/runs/bulk", nil},
+		{"GET", "/api/workflows", nil},
+	}
+	require.Len(t, got, len(expected))
+	for i, e := range expected {
+		require.Equal(t, e.method, got[i].method)
+		require.Equal(t, e.path, got[i].path)
+		require.Equal(t, e.body, got[i].body, e.path)
+		require.Equal(t, "Bearer secret", got[i].header.Get("Authorization"))
+		if e.method == "POST" {
+			require.NotEmpty(t, got[i].header.Get("Idempotency-Key"), e.path)
+			require.Equal(t, "application/json", got[i].header.Get("Content-Type"))
+		} else {
+			require.Empty(t, got[i].header.Get("Idempotency-Key"), e.path)
+		}
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	c, _ := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/completeWorkflowRun":
+			respond(w, http.StatusNotFound, `{"error": {"code": "not_found", "message": "no data found for run ID: run-9",
+				"details": {"run_id": "run-9"}, "request_id": "req-1"}}`)
+		default:
+			w.Header().Set("X-Request-ID", "req-2")
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		}
+	})
+	ctx := context.Background()
+
+	err := c.CompleteWorkflowRun(ctx, "run-9")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NotErrorIs(t, err, ErrConflict)
+
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	require.Equal(t, map[string]any{"run_id": "run-9"}, apiErr.Details)
+	require.EqualError(t, err, "flho: 404 not_found: no data found for run ID: run-9 (request req-1)")
+
+	// responses not from flho keep their status and body
+	_, err = c.Health(ctx)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	require.Empty(t, apiErr.Code)
+	require.EqualError(t, err, "flho: 502: bad gateway (request req-2)")
+}
+
+func TestClient_Retries(t *testing.T) {
+	t.Run("retryable statuses are retried with the same idempotency key", func(t *testing.T) {
+		attempts := 0
+		c, requests := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			switch attempts {
+			case 1:
+				w.Header().Set("Retry-After", "0")
+				respond(w, http.StatusTooManyRequests, `{"error": {"code": "rate_limited", "message": "slow down"}}`)
+			case 2:
+				respond(w, http.StatusServiceUnavailable, ``)
+			default:
+				respond(w, http.StatusCreated, `{"run_id": "run-1"}`)
+			}
+		})
+
+		runID, err := c.InitiateWorkflow(WithIdempotencyKey(context.Background(), "key-1"), InitiateRequest{Name: "billing"})
+		require.NoError(t, err)
+		require.Equal(t, "run-1", runID)
+
+		got := requests()
+		require.Len(t, got, 3)
+		for _, r := range got {
+			require.Equal(t, "key-1", r.header.Get("Idempotency-Key"))
+			require.Equal(t, map[string]any{"name": "billing"}, r.body)
+		}
+	})
+
+	t.Run("retries are limited", func(t *testing.T) {
+		c, requests := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			respond(w, http.StatusServiceUnavailable, `{"error": {"code": "internal_error", "message": "down"}}`)
+		})
+		c.retries = 2
+
+		_, err := c.Health(context.Background())
+		require.ErrorIs(t, err, ErrInternal)
+		require.Len(t, requests(), 3)
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		c, requests := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			respond(w, http.StatusInternalServerError, `{"error": {"code": "internal_error", "message": "internal server error"}}`)
+		})
+
+		err := c.ResumeWorkflowRun(context.Background(), "run-1")
+		require.ErrorIs(t, err, ErrInternal)
+		require.Len(t, requests(), 1)
+	})
+
+	t.Run("network errors are retried", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.Close()
+
+		attempts := 0
+		c, err := New(srv.URL, WithRetries(2), WithBackoff(time.Millisecond, time.Millisecond),
+			WithHTTPClient(&http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+				attempts++
+				return http.DefaultTransport.RoundTrip(r)
+			})}))
+		require.NoError(t, err)
+
+		_, err = c.Health(context.Background())
+		require.Error(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("waiting stops when the context is done", func(t *testing.T) {
+		c, requests := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			respond(w, http.StatusTooManyRequests, `{"error": {"code": "rate_limited", "message": "slow down"}}`)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := c.CancelWorkflowRun(ctx, "run-1", "")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 10*time.Second)
+		require.Len(t, requests(), 1)
+	})
+
+	t.Run("every call gets an idempotency key of its own", func(t *testing.T) {
+		c, requests := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			respond(w, http.StatusOK, `{"success": "run completed"}`)
+		})
+
+		require.NoError(t, c.CompleteWorkflowRun(context.Background(), "run-1"))
+		require.NoError(t, c.CompleteWorkflowRun(context.Background(), "run-1"))
+		got := requests()
+		require.NotEqual(t, got[0].header.Get("Idempotency-Key"), got[1].header.Get("Idempotency-Key"))
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestClient_backoff(t *testing.T) {
+	c := &Client{minBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+	var waits []time.Duration
+	for attempt := range 6 {
+		waits = append(waits, c.backoff(attempt))
+	}
+	require.Equal(t, []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second,
+	}, waits)
+
+	wait, ok := retryAfter("3")
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, wait)
+	_, ok = retryAfter("soon")
+	require.False(t, ok)
+}
+
+func TestClient_ExportRuns(t *testing.T) {
+	c, requests := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jsonl")
+		_, _ = io.WriteString(w, `{"id": "run-1", "workflow_name": "billing", "status": "failed"}`+"\n")
+		_, _ = io.WriteString(w, `{"id": "run-2", "workflow_name": "billing", "status": "failed"}`+"\n")
+	})
+
+	step := 2
+	filter := RunsFilter{
+		Status:       RunStatusFailed,
+		Workflow:     "bill",
+		Labels:       "tier=gold",
+		Step:         &step,
+		StartedAfter: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		MinDuration:  time.Minute,
+		Sort:         "duration",
+	}
+
+	var ids []string
+	for run, err := range c.ExportRuns(context.Background(), filter) {
+		require.NoError(t, err)
+		ids = append(ids, run.ID)
+	}
+	require.Equal(t, []string{"run-1", "run-2"}, ids)
+
+	got := requests()
+	require.Len(t, got, 1)
+	require.Equal(t, "/api/runs/export", got[0].path)
+	require.Equal(t, "format=jsonl&labels=tier%3Dgold&min_duration=1m0s&sort=duration&started_after=2024-01-02T03%3A04%3A05Z&status=failed&step=2&workflow=bill", got[0].query)
+
+	t.Run("errors end the iteration", func(t *testing.T) {
+		c, _ := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			respond(w, http.StatusForbidden, `{"error": {"code": "forbidden", "message": "missing scope runs:read"}}`)
+		})
+
+		var errs []error
+		for _, err := range c.ExportRuns(context.Background(), RunsFilter{}) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		require.True(t, errors.Is(errs[0], ErrForbidden))
+	})
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error is an error response of the API. Branch on it with errors.Is and the
+// sentinels below, or with errors.As for its details:
+//
+//	if errors.Is(err, client.ErrNotFound) { ... }
+type Error struct {
+	StatusCode int
+	Code       string         // such as "not_found", or empty if the response was not from flho
+	Message    string         // meant for people, not for branching on
+	Details    map[string]any // depend on the error, such as the run_id of a run that was not found
+	RequestID  string         // the X-Request-ID of the request, to find it in flho's logs
+}
+
+// Errors of the API, matched by code with errors.Is.
+var (
+	ErrBadRequest       = &Error{Code: "bad_request"}
+	ErrUnauthorized     = &Error{Code: "unauthorized"}
+	ErrForbidden        = &Error{Code: "forbidden"}
+	ErrNotFound         = &Error{Code: "not_found"}
+	ErrMethodNotAllowed = &Error{Code: "method_not_allowed"}
+	ErrConflict         = &Error{Code: "conflict"}
+	ErrPayloadTooLarge  = &Error{Code: "payload_too_large"}
+	ErrValidationFailed = &Error{Code: "validation_failed"}
+	ErrRateLimited      = &Error{Code: "rate_limited"}
+	ErrInternal         = &Error{Code: "internal_error"}
+)
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("flho: %d", e.StatusCode)
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Is reports whether target is an *Error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// maxErrorBody bounds how much of an error response is read.
+const maxErrorBody = 64 << 10
+
+// responseError returns the error of a response with an error status, from
+// its envelope or, for responses not from flho such as those of a proxy, from
+// its status and body.
+func responseError(resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	var envelope struct {
+		Error *struct {
+			Code      string         `json:"code"`
+			Message   string         `json:"message"`
+			Details   map[string]any `json:"details"`
+			RequestID string         `json:"request_id"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error != nil {
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Code:       envelope.Error.Code,
+			Message:    envelope.Error.Message,
+			Details:    envelope.Error.Details,
+			RequestID:  envelope.Error.RequestID,
+		}
+	}
+
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		msg = http.StatusText(resp.StatusCode)
+	}
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Message:    msg,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+}
@@ -0,0 +1,237 @@
+package client
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RunStatus is the status of a run.
+type RunStatus string
+
+// Statuses of a run.
+const (
+	RunStatusScheduled RunStatus = "scheduled"
+	RunStatusQueued    RunStatus = "queued"
+	RunStatusOngoing   RunStatus = "ongoing"
+	RunStatusCompleted RunStatus = "completed"
+	RunStatusFailed    RunStatus = "failed"
+	RunStatusTimedOut  RunStatus = "timed_out"
+	RunStatusCancelled RunStatus = "cancelled"
+)
+
+// Run is a run of a workflow.
+type Run struct {
+	ID            string            `json:"id"`
+	WorkflowName  string            `json:"workflow_name"`
+	Namespace     string            `json:"namespace"`
+	Status        RunStatus         `json:"status"`
+	CurrentStep   int               `json:"current_step"`
+	StartTime     *time.Time        `json:"start_time,omitempty"`
+	EndTime       *time.Time        `json:"end_time,omitempty"`
+	Duration      *time.Duration    `json:"duration,omitempty"`
+	LastHeartbeat *Heartbeat        `json:"last_heartbeat,omitempty"`
+	Compensation  *Compensation     `json:"compensation,omitempty"`
+	ParentRunID   string            `json:"parent_run_id,omitempty"`
+	ChildRunIDs   []string          `json:"child_run_ids,omitempty"`
+	ScheduledFor  *time.Time        `json:"scheduled_for,omitempty"`
+	Priority      int               `json:"priority"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	History       []RunEvent        `json:"history,omitempty"` // oldest first, only set by GetRun
+}
+
+// RunEvent is an event in the history of a run, such as "advanced" or
+// "failed".
+type RunEvent struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	Step   int       `json:"step"`
+	Actor  string    `json:"actor,omitempty"` // the API key that caused the event, or empty for flho itself
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Heartbeat is the last heartbeat of a run.
+type Heartbeat struct {
+	At       time.Time `json:"at"`
+	Step     int       `json:"step"`
+	Progress *float64  `json:"progress,omitempty"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// Compensation is the progress of undoing a failed or cancelled run's
+// completed steps.
+type Compensation struct {
+	Phase     string   `json:"phase"` // compensating, compensated or compensation_failed
+	Steps     []string `json:"steps"`
+	Completed int      `json:"completed"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Workflow is a workflow flho is configured with.
+type Workflow struct {
+	Name              string   `json:"name"`
+	Namespace         string   `json:"namespace"`
+	Steps             []string `json:"steps"`              // step keys, in order
+	Schedule          string   `json:"schedule,omitempty"` // cron expression or interval the workflow is initiated on
+	MaxConcurrentRuns int      `json:"max_concurrent_runs,omitempty"`
+}
+
+// InitiateRequest starts a run of a workflow, now or later.
+type InitiateRequest struct {
+	Name       string
+	StartAt    time.Time         // optional, starts the run at this time
+	StartAfter time.Duration     // optional, starts the run after this delay
+	Priority   int               // optional, higher runs ahead of lower when queued or deferred
+	Labels     map[string]string // optional, e.g. {"customer_id": "42"}
+}
+
+func (r InitiateRequest) MarshalJSON() ([]byte, error) {
+	body := struct {
+		Name       string            `json:"name"`
+		StartAt    *time.Time        `json:"start_at,omitempty"`
+		StartAfter string            `json:"start_after,omitempty"`
+		Priority   int               `json:"priority,omitempty"`
+		Labels     map[string]string `json:"labels,omitempty"`
+	}{Name: r.Name, Priority: r.Priority, Labels: r.Labels}
+
+	if !r.StartAt.IsZero() {
+		body.StartAt = &r.StartAt
+	}
+	if r.StartAfter != 0 {
+		body.StartAfter = r.StartAfter.String()
+	}
+	return json.Marshal(body)
+}
+
+// HeartbeatRequest reports the progress of a run's current step.
+type HeartbeatRequest struct {
+	Progress *float64 `json:"progress,omitempty"` // optional percentage complete, 0-100
+	Message  string   `json:"message,omitempty"`
+}
+
+// RunsFilter selects runs. The zero value selects every run.
+type RunsFilter struct {
+	Status        RunStatus
+	Workflow      string // partial match on workflow name
+	Namespace     string
+	Priority      *int
+	Labels        string // a label selector, such as "customer_id=42,region!=eu,!trial"
+	Step          *int
+	StartedAfter  time.Time
+	StartedBefore time.Time
+	EndedAfter    time.Time
+	EndedBefore   time.Time
+	MinDuration   time.Duration // finished runs that took at least this long
+	Sort          string        // start_time, end_time, duration or priority
+	Order         string        // asc or desc
+}
+
+// values returns the filter as the query parameters of the API.
+func (f RunsFilter) values() url.Values {
+	v := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+
+	set("status", string(f.Status))
+	set("workflow", f.Workflow)
+	set("namespace", f.Namespace)
+	if f.Priority != nil {
+		v.Set("priority", strconv.Itoa(*f.Priority))
+	}
+	set("labels", f.Labels)
+	if f.Step != nil {
+		v.Set("step", strconv.Itoa(*f.Step))
+	}
+	for key, t := range map[string]time.Time{
+		"started_after":  f.StartedAfter,
+		"started_before": f.StartedBefore,
+		"ended_after":    f.EndedAfter,
+		"ended_before":   f.EndedBefore,
+	} {
+		if !t.IsZero() {
+			v.Set(key, t.Format(time.RFC3339Nano))
+		}
+	}
+	if f.MinDuration != 0 {
+		v.Set("min_duration", f.MinDuration.String())
+	}
+	set("sort", f.Sort)
+	set("order", f.Order)
+	return v
+}
+
+// BulkAction is the action a bulk job applies to its runs.
+type BulkAction string
+
+// Actions of a bulk job.
+const (
+	BulkCancel   BulkAction = "cancel"
+	BulkResume   BulkAction = "resume"
+	BulkAdvance  BulkAction = "advance"
+	BulkComplete BulkAction = "complete"
+)
+
+// BulkRequest applies an action to many runs, given by ID or selected by a
+// filter.
+type BulkRequest struct {
+	Action BulkAction
+	RunIDs []string    // the runs to apply the action to, or
+	Filter *RunsFilter // the filter selecting them
+	Reason string      // optional, passed on when cancelling
+	DryRun bool        // report what the action would do without applying it
+}
+
+func (r BulkRequest) MarshalJSON() ([]byte, error) {
+	body := struct {
+		Action BulkAction        `json:"action"`
+		RunIDs []string          `json:"run_ids,omitempty"`
+		Filter map[string]string `json:"filter,omitempty"`
+		Reason string            `json:"reason,omitempty"`
+		DryRun bool              `json:"dry_run,omitempty"`
+	}{Action: r.Action, RunIDs: r.RunIDs, Reason: r.Reason, DryRun: r.DryRun}
+
+	if r.Filter != nil {
+		// an empty filter selects every run, so it is sent rather than omitted
+		body.Filter = map[string]string{}
+		for key, values := range r.Filter.values() {
+			body.Filter[key] = values[0]
+		}
+	}
+	return json.Marshal(body)
+}
+
+// BulkJobState is the progress of a bulk job.
+type BulkJobState string
+
+// States of a bulk job.
+const (
+	BulkJobRunning BulkJobState = "running"
+	BulkJobDone    BulkJobState = "done"
+	BulkJobStopped BulkJobState = "stopped" // cut short by flho shutting down
+)
+
+// BulkJob is the progress and results of a bulk action.
+type BulkJob struct {
+	ID         string       `json:"id"`
+	Action     BulkAction   `json:"action"`
+	DryRun     bool         `json:"dry_run"`
+	State      BulkJobState `json:"state"`
+	Total      int          `json:"total"`
+	Succeeded  int          `json:"succeeded"`
+	Failed     int          `json:"failed"`
+	Actor      string       `json:"actor,omitempty"`
+	Results    []BulkResult `json:"results,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+// BulkResult is the outcome of a bulk action for one run.
+type BulkResult struct {
+	RunID  string    `json:"run_id"`
+	Status RunStatus `json:"status,omitempty"` // after the action, or before it in a dry run
+	Error  string    `json:"error,omitempty"`  // why the action was not, or would not be, applied
+}
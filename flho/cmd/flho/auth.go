@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/windevkay/forge/flho/internal/auth"
+	"github.com/windevkay/forge/flho/internal/service"
+)
+
+// sessionCookie holds the login session of the runs UI.
+const sessionCookie = "flho_session"
+
+type keyContextKey struct{}
+
+// requestKey returns the API key the request was authenticated with, if any.
+func requestKey(r *http.Request) *auth.Key {
+	return contextKey(r.Context())
+}
+
+// contextKey returns the API key of the request or call whose context ctx is,
+// if any.
+func contextKey(ctx context.Context) *auth.Key {
+	key, _ := ctx.Value(keyContextKey{}).(*auth.Key)
+	return key
+}
+
+// withKey returns ctx carrying the API key, which is also named as the actor
+// of the run events caused with it.
+func withKey(ctx context.Context, key *auth.Key) context.Context {
+	return service.WithActor(context.WithValue(ctx, keyContextKey{}, key), key.ID)
+}
+
+// authenticate identifies the API key of every request, from its bearer token
+// or else its login cookie, and names the key as the actor of the run events
+// the request causes. A request with an invalid bearer token is rejected;
+// whether a request needs a key at all is left to the route. Nothing is
+// checked when no keys are configured.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	if app.keys == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var key *auth.Key
+
+		if header := r.Header.Get("Authorization"); header != "" {
+			secret, ok := strings.CutPrefix(header, "Bearer ")
+			if ok {
+				key, ok = app.keys.Authenticate(strings.TrimSpace(secret))
+			}
+			if !ok {
+				app.unauthorized(w, r)
+				return
+			}
+		} else if cookie, err := r.Cookie(sessionCookie); err == nil {
+			key, _ = app.sessions.Lookup(cookie.Value)
+		}
+
+		if key != nil {
+			r = r.WithContext(withKey(r.Context(), key))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestNamespaces returns the namespaces the request's key is bound to, or
+// nil if the request reaches every namespace.
+func requestNamespaces(r *http.Request) []string {
+	return contextNamespaces(r.Context())
+}
+
+// contextNamespaces returns the namespaces the key of the request or call
+// whose context ctx is bound to, or nil if it reaches every namespace.
+func contextNamespaces(ctx context.Context) []string {
+	if key := contextKey(ctx); key != nil {
+		return key.NamespaceFilter()
+	}
+	return nil
+}
+
+// allowRun reports whether the run belongs to a namespace of the request's
+// key, responding with 404 as if the run did not exist when it does not.
+func (app *application) allowRun(w http.ResponseWriter, r *http.Request, runID string) bool {
+	namespaces := requestNamespaces(r)
+	if namespaces == nil {
+		return true
+	}
+
+	if run, err := app.service.GetRun(runID); err == nil && service.InNamespaces(run.Namespace, namespaces) {
+		return true
+	}
+
+	app.errorResponse(w, r, http.StatusNotFound, codeNotFound, "no data found for run ID: "+runID, details{"run_id": runID})
+	return false
+}
+
+func (app *application) unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="flho"`)
+	app.errorResponse(w, r, http.StatusUnauthorized, codeUnauthorized, "a valid API key is required", nil)
+}
+
+// allow reports whether the request's key grants the scope, responding with
+// 401 or 403 when it does not.
+func (app *application) allow(w http.ResponseWriter, r *http.Request, scope auth.Scope) bool {
+	if app.keys == nil {
+		return true
+	}
+
+	key := requestKey(r)
+	if key == nil {
+		app.unauthorized(w, r)
+		return false
+	}
+	if !key.Allows(scope) {
+		app.errorResponse(w, r, http.StatusForbidden, codeForbidden,
+			"API key "+key.ID+" lacks the "+string(scope)+" scope", details{"scope": scope})
+		return false
+	}
+
+	return true
+}
+
+// require serves the API route only to keys granting the scope.
+func (app *application) require(scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.allow(w, r, scope) {
+			next(w, r)
+		}
+	}
+}
+
+// requirePage serves the UI page only to keys granting the scope, sending
+// visitors who have not logged in to the login page.
+func (app *application) requirePage(scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.keys != nil {
+			key := requestKey(r)
+			if key == nil {
+				http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+				return
+			}
+			if !key.Allows(scope) {
+				http.Error(w, "API key "+key.ID+" lacks the "+string(scope)+" scope", http.StatusForbidden)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// loginPage is the data of the login page.
+type loginPage struct {
+	Next  string
+	Error string
+}
+
+// showLogin renders the login form of the runs UI.
+func (app *application) showLogin(w http.ResponseWriter, r *http.Request) {
+	if app.keys == nil {
+		http.Redirect(w, r, "/runs", http.StatusSeeOther)
+		return
+	}
+
+	app.renderHTML(w, "login.html", loginPage{Next: localPath(r.URL.Query().Get("next"))})
+}
+
+// login starts a session for the API key entered in the login form, setting
+// its cookie, then goes on to the page the visitor came from.
+func (app *application) login(w http.ResponseWriter, r *http.Request) {
+	if app.keys == nil {
+		http.Redirect(w, r, "/runs", http.StatusSeeOther)
+		return
+	}
+
+	next := localPath(r.PostFormValue("next"))
+	key, ok := app.keys.Authenticate(r.PostFormValue("api_key"))
+	if !ok {
+		app.renderHTML(w, "login.html", loginPage{Next: next, Error: "Invalid API key"})
+		return
+	}
+
+	token, err := app.sessions.Create(key)
+	if err != nil {
+		app.logger.Error("error creating session", "error", err.Error())
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(auth.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	app.logger.Info("logged in", "key_id", key.ID)
+
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// logout ends the login session and clears its cookie.
+func (app *application) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil && app.sessions != nil {
+		app.sessions.Delete(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// localPath returns next if it is a path on this server, so that the login
+// page cannot be used to redirect elsewhere, or the runs page otherwise.
+func localPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/runs"
+	}
+	return next
+}
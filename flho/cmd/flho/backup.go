@@ -0,0 +1,31 @@
+package main
+
+import "time"
+
+// backupData backs up the datastore every interval until the application
+// shuts down, counting every backup that succeeds or fails.
+func (app *application) backupData(interval time.Duration) {
+	defer app.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			app.backup()
+		case <-app.ctx.Done():
+			return
+		}
+	}
+}
+
+// backup makes a single backup of the datastore.
+func (app *application) backup() {
+	if err := app.datastore.Backup(); err != nil {
+		app.backups.With("failure").Inc()
+		app.logger.Warn("error backing up data", "error", err.Error())
+		return
+	}
+	app.backups.With("success").Inc()
+}
@@ -4,29 +4,86 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/windevkay/forge/flho/internal/auth"
+	"github.com/windevkay/forge/flho/internal/metrics"
+	"github.com/windevkay/forge/flho/internal/ratelimit"
 	"github.com/windevkay/forge/flho/internal/service"
 	"github.com/windevkay/forge/flho/internal/workflow"
 	"github.com/windevkay/forge/genie/v2"
 )
 
 type config struct {
-	dataBackupInterval time.Duration // data backup interval for genie (in-memory store)
-	port               int           // HTTP Port
-	workflowConfig     string        // path to the workflows YAML config
+	dataBackupInterval time.Duration      // data backup interval for genie (in-memory store)
+	deliveryAttempts   int                // attempts per notification before it is dead-lettered
+	deliveryBackoff    time.Duration      // initial backoff between notification attempts
+	breakerThreshold   int                // consecutive failures that open a host's circuit breaker
+	breakerCooldown    time.Duration      // how long an open circuit breaker waits before probing
+	hostConcurrency    int                // maximum in-flight notifications per target host
+	retention          workflow.Retention // how long finished runs are kept, per final status
+	archiveDir         string             // where runs are archived before they are purged
+	traceExporter      string             // where spans are exported: none, otlp, stdout or file
+	traceFile          string             // the file spans are exported to by the file exporter
+	apiKeys            string             // path to the API keys YAML, authentication is off if empty
+	signingSecretFile  string             // path to the secret outbound notifications are signed with, unsigned if empty
+	rateLimit          float64            // requests per second allowed per client IP, unlimited if 0
+	rateBurst          int                // requests a client IP may make at once
+	keyRateLimit       float64            // requests per second allowed per API key, unlimited if 0
+	keyRateBurst       int                // requests an API key may make at once
+	maxBodyBytes       int64              // largest request body read, in bytes
+	port               int                // HTTP Port
+	grpcPort           int                // gRPC port, the gRPC API is off if 0
+	workflowConfig     string             // path to the workflows YAML config
 }
 
 type application struct {
-	ctx        context.Context
-	cancelFunc context.CancelFunc
-	config     config
-	datastore  *genie.Store
-	logger     *slog.Logger
-	service    *service.WorkflowService
-	workflows  *workflow.ConfigStore
-	wg         sync.WaitGroup
+	ctx          context.Context
+	cancelFunc   context.CancelFunc
+	config       config
+	datastore    *genie.Store
+	keys         *auth.Keys          // API keys requests are authenticated with, if set
+	sessions     *auth.Sessions      // login sessions of the runs UI
+	clientLimits *ratelimit.Limiter  // rate limits requests per client IP, if set
+	keyLimits    *ratelimit.Limiter  // rate limits requests per API key, if set
+	rateLimited  *metrics.CounterVec // rate limited requests, by limit
+	idempotency  *idempotencyStore   // responses replayed to retried requests, if set
+	logger       *slog.Logger
+	metrics      *metrics.Registry   // served on /metrics, if set
+	backups      *metrics.CounterVec // datastore backups, by result
+	traces       *sdktrace.TracerProvider
+	tracer       trace.Tracer // traces incoming requests, if set
+	service      *service.WorkflowService
+	workflows    *workflow.ConfigStore
+	wg           sync.WaitGroup
+}
+
+// minSigningSecretLength is the shortest secret outbound notifications are
+// signed with, that of a SHA-256 key.
+const minSigningSecretLength = 32
+
+// loadSigningSecret reads the secret outbound notifications are signed with
+// from the file at path, ignoring surrounding whitespace such as a trailing
+// newline.
+func loadSigningSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	secret := bytes.TrimSpace(data)
+	if len(secret) < minSigningSecretLength {
+		return nil, fmt.Errorf("the secret in %s must be at least %d bytes long", path, minSigningSecretLength)
+	}
+	return secret, nil
 }
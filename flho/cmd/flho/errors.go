@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/windevkay/forge/flho/internal/service"
+)
+
+// Codes of the API's error responses. Clients should branch on the code, not
+// on the message, which is meant for people.
+const (
+	codeBadRequest       = "bad_request"        // 400, the body or query is malformed
+	codeUnauthorized     = "unauthorized"       // 401, a valid API key is required
+	codeForbidden        = "forbidden"          // 403, the API key does not allow the request
+	codeNotFound         = "not_found"          // 404
+	codeMethodNotAllowed = "method_not_allowed" // 405
+	codeConflict         = "conflict"           // 409, the run's status does not allow the request
+	codePayloadTooLarge  = "payload_too_large"  // 413
+	codeValidationFailed = "validation_failed"  // 422, the body is well-formed but its values are not valid
+	codeRateLimited      = "rate_limited"       // 429
+	codeInternalError    = "internal_error"     // 500
+)
+
+// requestIDHeader carries the ID of a request, echoed in its error responses.
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID matches the request IDs a client may choose itself.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// details holds the fields of an error response that depend on the error,
+// such as the ID of a run that was not found.
+type details map[string]any
+
+// apiError is the body of every error response of the API, under "error":
+//
+//	{"error": {"code": "not_found", "message": "no data found for run ID: 42", "details": {"run_id": "42"}, "request_id": "..."}}
+type apiError struct {
+	Code      string  `json:"code"`
+	Message   string  `json:"message"`
+	Details   details `json:"details,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+}
+
+type requestIDContextKey struct{}
+
+// requestID returns the ID of the request, if it was given one.
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// assignRequestIDs gives every request an ID, keeping the one the client sent
+// in X-Request-ID if it is valid, and returns it in the same header.
+func (app *application) assignRequestIDs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id)))
+	})
+}
+
+// errorResponse responds with the error envelope.
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, code, message string, d details) {
+	app.writeResponse(w, status, envelope{
+		"error": apiError{
+			Code:      code,
+			Message:   message,
+			Details:   d,
+			RequestID: requestID(r),
+		},
+	})
+}
+
+// serviceError responds to an error of the service with the status of its
+// kind. Errors of no known kind are logged and reported as internal errors,
+// without their message.
+func (app *application) serviceError(w http.ResponseWriter, r *http.Request, err error, d details) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		app.errorResponse(w, r, http.StatusNotFound, codeNotFound, err.Error(), d)
+	case errors.Is(err, service.ErrConflict):
+		app.errorResponse(w, r, http.StatusConflict, codeConflict, err.Error(), d)
+	case errors.Is(err, service.ErrInvalid):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, codeValidationFailed, err.Error(), d)
+	default:
+		app.logger.Error("request failed", "method", r.Method, "path", r.URL.Path, "request_id", requestID(r), "error", err.Error())
+		app.errorResponse(w, r, http.StatusInternalServerError, codeInternalError, "internal server error", nil)
+	}
+}
+
+// validationFailed responds to a well-formed body whose values are not valid.
+func (app *application) validationFailed(w http.ResponseWriter, r *http.Request, message string, d details) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, codeValidationFailed, message, d)
+}
+
+// routeErrors answers the requests no route matches with the error envelope:
+// 405 with the Allow header when the path has routes for other methods, and
+// 404 otherwise.
+func (app *application) routeErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// the mux works out which methods the path allows
+		probe := &statusRecorder{header: make(http.Header)}
+		mux.ServeHTTP(probe, r)
+
+		if probe.status == http.StatusMethodNotAllowed {
+			allow := probe.header.Get("Allow")
+			w.Header().Set("Allow", allow)
+			app.errorResponse(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed,
+				"method "+r.Method+" is not allowed on "+r.URL.Path, details{"allowed_methods": strings.Split(allow, ", ")})
+			return
+		}
+
+		app.errorResponse(w, r, http.StatusNotFound, codeNotFound, "no route for "+r.URL.Path, nil)
+	})
+}
+
+// statusRecorder records the status and headers of a response, discarding
+// its body.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusRecorder) Header() http.Header { return s.header }
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+}
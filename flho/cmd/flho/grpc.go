@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	flhov1 "github.com/windevkay/forge/flho/api/flho/v1"
+	"github.com/windevkay/forge/flho/internal/auth"
+	"github.com/windevkay/forge/flho/internal/label"
+	"github.com/windevkay/forge/flho/internal/service"
+)
+
+// newGRPCServer returns the gRPC server of the API, backed by the same service,
+// API keys and rate limits as the HTTP API.
+func (app *application) newGRPCServer() *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := app.grpcAuthenticate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := app.grpcAuthenticate(ss.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		}),
+	)
+	flhov1.RegisterWorkflowServiceServer(srv, &grpcServer{app: app})
+	return srv
+}
+
+// serverStream is a stream whose context carries what the interceptor found
+// out about the call.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+// grpcAuthenticate rate limits a call by client, identifies its API key from
+// its "authorization: Bearer" metadata and rate limits it by key, as the HTTP
+// API does. Unlike HTTP routes, every call needs a key when keys are
+// configured.
+func (app *application) grpcAuthenticate(ctx context.Context) (context.Context, error) {
+	if app.clientLimits != nil {
+		if ok, wait := app.clientLimits.Allow(peerIP(ctx)); !ok {
+			return nil, app.grpcRateLimited("client", wait)
+		}
+	}
+
+	if app.keys == nil {
+		return ctx, nil
+	}
+
+	var secret string
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		secret, _ = strings.CutPrefix(values[0], "Bearer ")
+	}
+	key, ok := app.keys.Authenticate(strings.TrimSpace(secret))
+	if secret == "" || !ok {
+		return nil, status.Error(codes.Unauthenticated, "a valid API key is required")
+	}
+
+	if app.keyLimits != nil {
+		if ok, wait := app.keyLimits.Allow(key.ID); !ok {
+			return nil, app.grpcRateLimited("key", wait)
+		}
+	}
+
+	return withKey(ctx, key), nil
+}
+
+// grpcRateLimited refuses a rate limited call, telling the client how many
+// seconds to wait before retrying.
+func (app *application) grpcRateLimited(limit string, wait time.Duration) error {
+	if app.rateLimited != nil {
+		app.rateLimited.With(limit).Inc()
+	}
+
+	seconds := max(1, int(math.Ceil(wait.Seconds())))
+	return status.Errorf(codes.ResourceExhausted, "%s rate limit exceeded, retry in %ds", limit, seconds)
+}
+
+// peerIP returns the IP address a call came from.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// grpcServer implements the gRPC API.
+type grpcServer struct {
+	flhov1.UnimplementedWorkflowServiceServer
+	app *application
+}
+
+// allow returns an error unless the call's key grants the scope.
+func (s *grpcServer) allow(ctx context.Context, scope auth.Scope) error {
+	if s.app.keys == nil {
+		return nil
+	}
+	key := contextKey(ctx)
+	if key == nil {
+		return status.Error(codes.Unauthenticated, "a valid API key is required")
+	}
+	if !key.Allows(scope) {
+		return status.Errorf(codes.PermissionDenied, "API key %s lacks the %s scope", key.ID, scope)
+	}
+	return nil
+}
+
+// allowRun returns a NotFound error, as if the run did not exist, unless it
+// belongs to a namespace of the call's key.
+func (s *grpcServer) allowRun(ctx context.Context, runID string) error {
+	namespaces := contextNamespaces(ctx)
+	if namespaces == nil {
+		return nil
+	}
+	if run, err := s.app.service.GetRun(runID); err == nil && service.InNamespaces(run.Namespace, namespaces) {
+		return nil
+	}
+	return status.Error(codes.NotFound, "no data found for run ID: "+runID)
+}
+
+// error returns the status of an error of the service. Errors of no known kind
+// are logged and reported as internal errors, without their message.
+func (s *grpcServer) error(method string, err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrInvalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		s.app.logger.Error("call failed", "method", method, "error", err.Error())
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+func (s *grpcServer) InitiateWorkflow(ctx context.Context, req *flhov1.InitiateWorkflowRequest) (*flhov1.InitiateWorkflowResponse, error) {
+	if err := s.allow(ctx, auth.InitiateScope(req.GetName())); err != nil {
+		return nil, err
+	}
+	if namespace := s.app.service.WorkflowNamespace(req.GetName()); !service.InNamespaces(namespace, contextNamespaces(ctx)) {
+		return nil, status.Errorf(codes.PermissionDenied, "workflow %s is in namespace %s, which the API key is not bound to", req.GetName(), namespace)
+	}
+	if err := s.app.service.CheckWorkflow(req.GetName()); err != nil {
+		return nil, s.error("InitiateWorkflow", err)
+	}
+
+	if err := label.Validate(req.GetLabels()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	opts := service.RunOptions{Priority: int(req.GetPriority()), Labels: req.GetLabels()}
+	if req.StartAt != nil && req.StartAfter != nil {
+		return nil, status.Error(codes.InvalidArgument, "only one of start_at and start_after may be given")
+	}
+	if req.StartAt != nil {
+		if err := req.StartAt.CheckValid(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid start_at: "+err.Error())
+		}
+		opts.StartAt = req.StartAt.AsTime()
+	}
+	if req.StartAfter != nil {
+		if err := req.StartAfter.CheckValid(); err != nil || req.StartAfter.AsDuration() < 0 {
+			return nil, status.Error(codes.InvalidArgument, "start_after must be a positive duration")
+		}
+		opts.StartAfter = req.StartAfter.AsDuration()
+	}
+
+	runID := s.app.service.InitiateWorkflowWithOptions(ctx, req.GetName(), opts)
+	return &flhov1.InitiateWorkflowResponse{RunId: runID}, nil
+}
+
+func (s *grpcServer) UpdateWorkflow(ctx context.Context, req *flhov1.UpdateWorkflowRequest) (*flhov1.UpdateWorkflowResponse, error) {
+	if err := s.allow(ctx, auth.ScopeRunsAdvance); err != nil {
+		return nil, err
+	}
+
+	// labels set to an empty value are removed, so only the others are
+	// validated
+	set := maps.Clone(req.GetLabels())
+	maps.DeleteFunc(set, func(_, v string) bool { return v == "" })
+	if err := label.Validate(set); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.allowRun(ctx, req.GetRunId()); err != nil {
+		return nil, err
+	}
+	if err := s.app.service.UpdateWorkflowWithOptions(ctx, req.GetRunId(), service.UpdateOptions{Labels: req.GetLabels()}); err != nil {
+		return nil, s.error("UpdateWorkflow", err)
+	}
+	return &flhov1.UpdateWorkflowResponse{}, nil
+}
+
+func (s *grpcServer) CompleteWorkflow(ctx context.Context, req *flhov1.CompleteWorkflowRequest) (*flhov1.CompleteWorkflowResponse, error) {
+	if err := s.allow(ctx, auth.ScopeRunsAdvance); err != nil {
+		return nil, err
+	}
+	if err := s.allowRun(ctx, req.GetRunId()); err != nil {
+		return nil, err
+	}
+	if err := s.app.service.CompleteWorkflow(ctx, req.GetRunId()); err != nil {
+		return nil, s.error("CompleteWorkflow", err)
+	}
+	return &flhov1.CompleteWorkflowResponse{}, nil
+}
+
+func (s *grpcServer) GetRun(ctx context.Context, req *flhov1.GetRunRequest) (*flhov1.GetRunResponse, error) {
+	if err := s.allow(ctx, auth.ScopeRunsRead); err != nil {
+		return nil, err
+	}
+	if err := s.allowRun(ctx, req.GetRunId()); err != nil {
+		return nil, err
+	}
+
+	run, err := s.app.service.GetRun(req.GetRunId())
+	if err != nil {
+		return nil, s.error("GetRun", err)
+	}
+	return &flhov1.GetRunResponse{Run: runProto(run)}, nil
+}
+
+func (s *grpcServer) ListRuns(ctx context.Context, req *flhov1.ListRunsRequest) (*flhov1.ListRunsResponse, error) {
+	if err := s.allow(ctx, auth.ScopeRunsRead); err != nil {
+		return nil, err
+	}
+
+	filter, err := listRunsFilter(req)
+	if err == nil {
+		err = filter.Validate()
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	filter.Namespaces = contextNamespaces(ctx)
+
+	page := s.app.service.GetRuns(filter)
+	resp := &flhov1.ListRunsResponse{
+		Runs:          make([]*flhov1.Run, 0, len(page.Runs)),
+		NextPageToken: page.NextCursor,
+		TotalCount:    int32(page.TotalCount),
+	}
+	for _, run := range page.Runs {
+		resp.Runs = append(resp.Runs, runProto(run))
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) WatchRun(req *flhov1.WatchRunRequest, stream grpc.ServerStreamingServer[flhov1.WatchRunResponse]) error {
+	ctx := stream.Context()
+	if err := s.allow(ctx, auth.ScopeRunsRead); err != nil {
+		return err
+	}
+	if err := s.allowRun(ctx, req.GetRunId()); err != nil {
+		return err
+	}
+
+	for run, err := range s.app.service.WatchRun(ctx, req.GetRunId()) {
+		if err != nil {
+			return s.error("WatchRun", err)
+		}
+		if err := stream.Send(&flhov1.WatchRunResponse{Run: runProto(run)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listRunsFilter returns the service's filter of a ListRuns request.
+func listRunsFilter(req *flhov1.ListRunsRequest) (service.RunsFilter, error) {
+	filter := service.RunsFilter{
+		WorkflowName: req.GetWorkflow(),
+		Namespace:    req.GetNamespace(),
+		Sort:         req.GetSort(),
+		Order:        req.GetOrder(),
+		Cursor:       req.GetPageToken(),
+		Page:         1,
+		PageSize:     int(req.GetPageSize()),
+	}
+
+	if req.GetStatus() != flhov1.RunStatus_RUN_STATUS_UNSPECIFIED {
+		s, ok := runStatuses[req.GetStatus()]
+		if !ok {
+			return filter, errors.New("invalid status " + strconv.Itoa(int(req.GetStatus())))
+		}
+		filter.Status = string(s)
+	}
+	if req.Priority != nil {
+		p := int(req.GetPriority())
+		filter.Priority = &p
+	}
+	if req.Step != nil {
+		if req.GetStep() < 0 {
+			return filter, errors.New("invalid step " + strconv.Itoa(int(req.GetStep())))
+		}
+		step := int(req.GetStep())
+		filter.Step = &step
+	}
+
+	selector, err := label.ParseSelector(req.GetLabels())
+	if err != nil {
+		return filter, err
+	}
+	filter.Labels = selector
+
+	for name, t := range map[string]struct {
+		from *timestamppb.Timestamp
+		to   *time.Time
+	}{
+		"started_after":  {req.StartedAfter, &filter.StartedAfter},
+		"started_before": {req.StartedBefore, &filter.StartedBefore},
+		"ended_after":    {req.EndedAfter, &filter.EndedAfter},
+		"ended_before":   {req.EndedBefore, &filter.EndedBefore},
+	} {
+		if t.from == nil {
+			continue
+		}
+		if err := t.from.CheckValid(); err != nil {
+			return filter, errors.New("invalid " + name + ": " + err.Error())
+		}
+		*t.to = t.from.AsTime()
+	}
+
+	if req.MinDuration != nil {
+		if err := req.MinDuration.CheckValid(); err != nil {
+			return filter, errors.New("invalid min_duration: " + err.Error())
+		}
+		filter.MinDuration = req.MinDuration.AsDuration()
+	}
+
+	return filter, nil
+}
+
+// runStatuses maps the statuses of the gRPC API to those of the service.
+var runStatuses = map[flhov1.RunStatus]service.RunStatus{
+	flhov1.RunStatus_RUN_STATUS_SCHEDULED: service.RunStatusScheduled,
+	flhov1.RunStatus_RUN_STATUS_QUEUED:    service.RunStatusQueued,
+	flhov1.RunStatus_RUN_STATUS_ONGOING:   service.RunStatusOngoing,
+	flhov1.RunStatus_RUN_STATUS_COMPLETED: service.RunStatusCompleted,
+	flhov1.RunStatus_RUN_STATUS_FAILED:    service.RunStatusFailed,
+	flhov1.RunStatus_RUN_STATUS_TIMED_OUT: service.RunStatusTimedOut,
+	flhov1.RunStatus_RUN_STATUS_CANCELLED: service.RunStatusCancelled,
+}
+
+// runStatusProto returns the gRPC API's status of a run.
+func runStatusProto(s service.RunStatus) flhov1.RunStatus {
+	for status, ss := range runStatuses {
+		if ss == s {
+			return status
+		}
+	}
+	return flhov1.RunStatus_RUN_STATUS_UNSPECIFIED
+}
+
+// runProto returns a run as a message of the gRPC API.
+func runProto(run service.RunInfo) *flhov1.Run {
+	pb := &flhov1.Run{
+		Id:           run.ID,
+		WorkflowName: run.WorkflowName,
+		Namespace:    run.Namespace,
+		Status:       runStatusProto(run.Status),
+		CurrentStep:  int32(run.CurrentStep),
+		StartTime:    timestampProto(run.StartTime),
+		EndTime:      timestampProto(run.EndTime),
+		ParentRunId:  run.ParentRunID,
+		ChildRunIds:  run.ChildRunIDs,
+		ScheduledFor: timestampProto(run.ScheduledFor),
+		Priority:     int32(run.Priority),
+		Labels:       run.Labels,
+	}
+	if run.Duration != nil {
+		pb.Duration = durationpb.New(*run.Duration)
+	}
+	if hb := run.LastHeartbeat; hb != nil {
+		pb.LastHeartbeat = &flhov1.Heartbeat{
+			At:       timestamppb.New(hb.At),
+			Step:     int32(hb.Step),
+			Progress: hb.Progress,
+			Message:  hb.Message,
+		}
+	}
+	if c := run.Compensation; c != nil {
+		pb.Compensation = &flhov1.Compensation{
+			Phase:     string(c.Phase),
+			Steps:     c.Steps,
+			Completed: int32(c.Completed),
+			Error:     c.Error,
+		}
+	}
+	for _, event := range run.History {
+		pb.History = append(pb.History, &flhov1.RunEvent{
+			Time:   timestamppb.New(event.Time),
+			Type:   string(event.Type),
+			Step:   int32(event.Step),
+			Actor:  event.Actor,
+			Detail: event.Detail,
+		})
+	}
+	return pb
+}
+
+func timestampProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	flhov1 "github.com/windevkay/forge/flho/api/flho/v1"
+	"github.com/windevkay/forge/flho/internal/auth"
+	"github.com/windevkay/forge/flho/internal/ratelimit"
+	"github.com/windevkay/forge/flho/internal/service"
+	"github.com/windevkay/forge/flho/internal/workflow"
+	"github.com/windevkay/forge/genie/v2"
+)
+
+// setupGRPC serves the gRPC API of app over an in-memory connection and
+// returns a client of it.
+func setupGRPC(t *testing.T, app *application) flhov1.WorkflowServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := app.newGRPCServer()
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return flhov1.NewWorkflowServiceClient(conn)
+}
+
+// withSecret returns ctx sending the API key secret with the calls made with it.
+func withSecret(ctx context.Context, secret string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+secret)
+}
+
+// expectCode fails the test unless err has the gRPC code.
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+
+	if s, _ := status.FromError(err); s.Code() != code {
+		t.Fatalf("Expected code %s, got %v", code, err)
+	}
+}
+
+func newGRPCTestApp(t *testing.T) *application {
+	t.Helper()
+
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.NewKeys([]auth.Key{
+		{ID: "billing-service", Hash: auth.HashSecret("billing-secret"), Scopes: []auth.Scope{auth.ScopeRunsRead, auth.ScopeRunsAdvance, auth.InitiateScope("*")}, Namespaces: []string{"billing"}},
+		{ID: "ops", Hash: auth.HashSecret("ops-secret"), Scopes: []auth.Scope{auth.ScopeAdmin}},
+		{ID: "viewer", Hash: auth.HashSecret("viewer-secret"), Scopes: []auth.Scope{auth.ScopeRunsRead}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"invoice": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		},
+		"export": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+	}, map[string]workflow.Settings{"invoice": {Namespace: "billing"}})
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		logger:   logger,
+		keys:     keys,
+		sessions: auth.NewSessions(),
+	}
+	app.service = service.NewWorkflowService(config, store, &app.wg, logger)
+
+	return app
+}
+
+func TestGRPCRuns(t *testing.T) {
+	app := newGRPCTestApp(t)
+	c := setupGRPC(t, app)
+	ctx := withSecret(context.Background(), "billing-secret")
+
+	resp, err := c.InitiateWorkflow(ctx, &flhov1.InitiateWorkflowRequest{Name: "invoice", Priority: 2, Labels: map[string]string{"tier": "gold"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runID := resp.GetRunId()
+
+	if _, err := c.UpdateWorkflow(ctx, &flhov1.UpdateWorkflowRequest{RunId: runID, Labels: map[string]string{"region": "eu"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetRun(ctx, &flhov1.GetRunRequest{RunId: runID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := got.GetRun()
+	if run.GetStatus() != flhov1.RunStatus_RUN_STATUS_ONGOING || run.GetCurrentStep() != 1 || run.GetPriority() != 2 || run.GetNamespace() != "billing" {
+		t.Errorf("Expected an ongoing billing run of priority 2 on step 1, got %v", run)
+	}
+	if !maps.Equal(run.GetLabels(), map[string]string{"tier": "gold", "region": "eu"}) {
+		t.Errorf("Expected the run's labels, got %v", run.GetLabels())
+	}
+	if run.GetStartTime() == nil {
+		t.Error("Expected the run's start time")
+	}
+	if history := run.GetHistory(); len(history) != 3 || history[2].GetType() != "advanced" || history[2].GetActor() != "billing-service" {
+		t.Errorf("Expected the run's history, got %v", history)
+	}
+
+	scheduled, err := c.InitiateWorkflow(ctx, &flhov1.InitiateWorkflowRequest{Name: "invoice", StartAfter: durationpb.New(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := c.ListRuns(ctx, &flhov1.ListRunsRequest{Status: flhov1.RunStatus_RUN_STATUS_SCHEDULED})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetRuns()) != 1 || list.GetRuns()[0].GetId() != scheduled.GetRunId() || list.GetRuns()[0].GetScheduledFor() == nil {
+		t.Errorf("Expected the scheduled run, got %v", list.GetRuns())
+	}
+	if list.GetRuns()[0].GetHistory() != nil {
+		t.Error("Expected histories only for a single run")
+	}
+
+	list, err = c.ListRuns(ctx, &flhov1.ListRunsRequest{Labels: "tier=gold"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetRuns()) != 1 || list.GetRuns()[0].GetId() != runID {
+		t.Errorf("Expected the labelled run, got %v", list.GetRuns())
+	}
+
+	t.Run("pages follow each other", func(t *testing.T) {
+		first, err := c.ListRuns(ctx, &flhov1.ListRunsRequest{PageSize: 1, Sort: "priority"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(first.GetRuns()) != 1 || first.GetRuns()[0].GetId() != runID || first.GetNextPageToken() == "" {
+			t.Fatalf("Expected the first run and a next page, got %v", first)
+		}
+		next, err := c.ListRuns(ctx, &flhov1.ListRunsRequest{PageSize: 1, Sort: "priority", PageToken: first.GetNextPageToken()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(next.GetRuns()) != 1 || next.GetRuns()[0].GetId() != scheduled.GetRunId() {
+			t.Errorf("Expected the second run, got %v", next.GetRuns())
+		}
+	})
+
+	if _, err := c.CompleteWorkflow(ctx, &flhov1.CompleteWorkflowRequest{RunId: runID}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.CompleteWorkflow(ctx, &flhov1.CompleteWorkflowRequest{RunId: runID})
+	expectCode(t, err, codes.FailedPrecondition)
+	_, err = c.GetRun(ctx, &flhov1.GetRunRequest{RunId: "missing"})
+	expectCode(t, err, codes.NotFound)
+	// an unknown workflow is refused like those of other namespaces
+	_, err = c.InitiateWorkflow(ctx, &flhov1.InitiateWorkflowRequest{Name: "missing"})
+	expectCode(t, err, codes.PermissionDenied)
+
+	if err := app.service.CancelWorkflow(context.Background(), scheduled.GetRunId(), ""); err != nil {
+		t.Fatal(err)
+	}
+	app.wg.Wait()
+}
+
+func TestGRPCValidation(t *testing.T) {
+	app := newGRPCTestApp(t)
+	c := setupGRPC(t, app)
+	ctx := withSecret(context.Background(), "ops-secret")
+
+	invalid := []struct {
+		name string
+		call func() error
+	}{
+		{"start_at and start_after", func() error {
+			_, err := c.InitiateWorkflow(ctx, &flhov1.InitiateWorkflowRequest{Name: "export", StartAt: timestamppb.Now(), StartAfter: durationpb.New(time.Hour)})
+			return err
+		}},
+		{"negative start_after", func() error {
+			_, err := c.InitiateWorkflow(ctx, &flhov1.InitiateWorkflowRequest{Name: "export", StartAfter: durationpb.New(-time.Hour)})
+			return err
+		}},
+		{"invalid labels", func() error {
+			_, err := c.InitiateWorkflow(ctx, &flhov1.InitiateWorkflowRequest{Name: "export", Labels: map[string]string{"not valid": "x"}})
+			return err
+		}},
+		{"invalid label update", func() error {
+			_, err := c.UpdateWorkflow(ctx, &flhov1.UpdateWorkflowRequest{RunId: "run", Labels: map[string]string{"not valid": "x"}})
+			return err
+		}},
+		{"unknown sort key", func() error {
+			_, err := c.ListRuns(ctx, &flhov1.ListRunsRequest{Sort: "size"})
+			return err
+		}},
+		{"invalid label selector", func() error {
+			_, err := c.ListRuns(ctx, &flhov1.ListRunsRequest{Labels: "=x"})
+			return err
+		}},
+		{"negative step", func() error {
+			step := int32(-1)
+			_, err := c.ListRuns(ctx, &flhov1.ListRunsRequest{Step: &step})
+			return err
+		}},
+		{"unknown status", func() error {
+			_, err := c.ListRuns(ctx, &flhov1.ListRunsRequest{Status: flhov1.RunStatus(42)})
+			return err
+		}},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			expectCode(t, tt.call(), codes.InvalidArgument)
+		})
+	}
+}
+
+func TestGRPCAuthentication(t *testing.T) {
+	app := newGRPCTestApp(t)
+	c := setupGRPC(t, app)
+	background := context.Background()
+
+	exportRun, err := c.InitiateWorkflow(withSecret(background, "ops-secret"), &flhov1.InitiateWorkflowRequest{Name: "export"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetRun(background, &flhov1.GetRunRequest{RunId: exportRun.GetRunId()})
+	expectCode(t, err, codes.Unauthenticated)
+	_, err = c.GetRun(withSecret(background, "wrong-secret"), &flhov1.GetRunRequest{RunId: exportRun.GetRunId()})
+	expectCode(t, err, codes.Unauthenticated)
+
+	stream, err := c.WatchRun(background, &flhov1.WatchRunRequest{RunId: exportRun.GetRunId()})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	expectCode(t, err, codes.Unauthenticated)
+
+	viewer := withSecret(background, "viewer-secret")
+	_, err = c.CompleteWorkflow(viewer, &flhov1.CompleteWorkflowRequest{RunId: exportRun.GetRunId()})
+	expectCode(t, err, codes.PermissionDenied)
+	_, err = c.InitiateWorkflow(viewer, &flhov1.InitiateWorkflowRequest{Name: "export"})
+	expectCode(t, err, codes.PermissionDenied)
+
+	// keys bound to namespaces see nothing of the others
+	billing := withSecret(background, "billing-secret")
+	_, err = c.InitiateWorkflow(billing, &flhov1.InitiateWorkflowRequest{Name: "export"})
+	expectCode(t, err, codes.PermissionDenied)
+	_, err = c.GetRun(billing, &flhov1.GetRunRequest{RunId: exportRun.GetRunId()})
+	expectCode(t, err, codes.NotFound)
+	_, err = c.CompleteWorkflow(billing, &flhov1.CompleteWorkflowRequest{RunId: exportRun.GetRunId()})
+	expectCode(t, err, codes.NotFound)
+	list, err := c.ListRuns(billing, &flhov1.ListRunsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetRuns()) != 0 {
+		t.Errorf("Expected no runs outside the key's namespaces, got %v", list.GetRuns())
+	}
+
+	if _, err := c.GetRun(viewer, &flhov1.GetRunRequest{RunId: exportRun.GetRunId()}); err != nil {
+		t.Errorf("Expected a key without namespaces to read every run, got %v", err)
+	}
+
+	t.Run("calls are rate limited by key", func(t *testing.T) {
+		limits, err := ratelimit.New(0.001, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		app.keyLimits = limits
+		defer func() { app.keyLimits = nil }()
+
+		if _, err := c.ListRuns(viewer, &flhov1.ListRunsRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.ListRuns(viewer, &flhov1.ListRunsRequest{})
+		expectCode(t, err, codes.ResourceExhausted)
+
+		// other keys have their own limit
+		if _, err := c.ListRuns(billing, &flhov1.ListRunsRequest{}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if err := app.service.CancelWorkflow(background, exportRun.GetRunId(), ""); err != nil {
+		t.Fatal(err)
+	}
+	app.wg.Wait()
+}
+
+func TestGRPCWatchRun(t *testing.T) {
+	app := newGRPCTestApp(t)
+	c := setupGRPC(t, app)
+	ctx := withSecret(context.Background(), "billing-secret")
+
+	resp, err := c.InitiateWorkflow(ctx, &flhov1.InitiateWorkflowRequest{Name: "invoice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runID := resp.GetRunId()
+
+	stream, err := c.WatchRun(ctx, &flhov1.WatchRunRequest{RunId: runID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// recv waits for the watch to send a state of the run matching cond
+	recv := func(cond func(*flhov1.Run) bool) *flhov1.Run {
+		t.Helper()
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("Expected the run, got %v", err)
+			}
+			if cond(msg.GetRun()) {
+				return msg.GetRun()
+			}
+		}
+	}
+
+	if run := recv(func(*flhov1.Run) bool { return true }); run.GetId() != runID || len(run.GetHistory()) == 0 {
+		t.Errorf("Expected the run with its history first, got %v", run)
+	}
+
+	if _, err := c.UpdateWorkflow(ctx, &flhov1.UpdateWorkflowRequest{RunId: runID}); err != nil {
+		t.Fatal(err)
+	}
+	recv(func(r *flhov1.Run) bool { return r.GetCurrentStep() == 1 })
+
+	if _, err := c.CompleteWorkflow(ctx, &flhov1.CompleteWorkflowRequest{RunId: runID}); err != nil {
+		t.Fatal(err)
+	}
+	recv(func(r *flhov1.Run) bool { return r.GetStatus() == flhov1.RunStatus_RUN_STATUS_COMPLETED })
+
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the watch to end once the run finished, got %v", err)
+	}
+
+	missing, err := c.WatchRun(ctx, &flhov1.WatchRunRequest{RunId: "missing"})
+	if err == nil {
+		_, err = missing.Recv()
+	}
+	expectCode(t, err, codes.NotFound)
+
+	app.wg.Wait()
+}
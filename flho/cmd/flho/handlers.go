@@ -1,12 +1,24 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"io"
+	"maps"
 	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/windevkay/forge/flho/api"
+	"github.com/windevkay/forge/flho/internal/auth"
+	"github.com/windevkay/forge/flho/internal/label"
 	"github.com/windevkay/forge/flho/internal/service"
 )
 
@@ -14,12 +26,38 @@ type envelope map[string]any
 
 // InitiateWorkflowRequest represents the request body for initiating a workflow
 type InitiateWorkflowRequest struct {
-	Name string `json:"name"`
+	Name       string            `json:"name"`
+	StartAt    *time.Time        `json:"start_at"`    // optional RFC 3339 time to start the run at
+	StartAfter string            `json:"start_after"` // optional delay before the run starts, e.g. "72h"
+	Priority   int               `json:"priority"`    // optional, higher runs ahead of lower when queued or deferred
+	Labels     map[string]string `json:"labels"`      // optional, e.g. {"customer_id": "42"}
 }
 
 // UpdateWorkflowRequest represents the request body for updating a workflow
 type UpdateWorkflowRequest struct {
-	RunID string `json:"run_id"`
+	RunID  string            `json:"run_id"`
+	Labels map[string]string `json:"labels"` // optional labels to set on the run when updating it; an empty value removes one
+}
+
+// CancelWorkflowRequest represents the request body for cancelling a workflow
+type CancelWorkflowRequest struct {
+	RunID  string `json:"run_id"`
+	Reason string `json:"reason"`
+}
+
+// BulkRunsRequest represents the request body for a bulk action on runs
+type BulkRunsRequest struct {
+	Action string            `json:"action"`  // cancel, resume, advance or complete
+	RunIDs []string          `json:"run_ids"` // the runs to apply the action to, or
+	Filter map[string]string `json:"filter"`  // the runs page's query parameters selecting them, e.g. {"status": "failed"}
+	Reason string            `json:"reason"`  // optional, passed on when cancelling
+	DryRun bool              `json:"dry_run"` // report what the action would do without applying it
+}
+
+// HeartbeatRequest represents the optional request body for a run heartbeat
+type HeartbeatRequest struct {
+	Progress *float64 `json:"progress"`
+	Message  string   `json:"message"`
 }
 
 func (app *application) writeResponse(w http.ResponseWriter, statusCode int, data envelope) {
@@ -31,6 +69,94 @@ func (app *application) writeResponse(w http.ResponseWriter, statusCode int, dat
 	}
 }
 
+// errEmptyBody is returned by decodeJSON when the request has no body.
+var errEmptyBody = errors.New("body must not be empty")
+
+// decodeJSON decodes the request body into dst. The body must hold a single
+// JSON value with no fields dst does not have, and errors say what is wrong
+// with it in terms a client can act on.
+func decodeJSON(r *http.Request, dst any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &typeError):
+			if typeError.Field != "" {
+				return fmt.Errorf("body contains a %s for field %q, which must be a %s", typeError.Value, typeError.Field, jsonType(typeError.Type.Kind()))
+			}
+			return fmt.Errorf("body contains a %s (at character %d)", typeError.Value, typeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errEmptyBody
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return fmt.Errorf("body contains unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		case errors.As(err, &maxBytesError):
+			return maxBytesError
+		default:
+			return fmt.Errorf("body contains invalid JSON: %w", err)
+		}
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return maxBytesError
+		}
+		return errors.New("body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// jsonType names the JSON type a Go kind is decoded from.
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// badJSON responds to a request whose body could not be decoded, with 413 if
+// it was too large and 400 otherwise.
+func (app *application) badJSON(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		app.errorResponse(w, r, http.StatusRequestEntityTooLarge, codePayloadTooLarge,
+			fmt.Sprintf("body must not be larger than %d bytes", maxBytesError.Limit), details{"max_bytes": maxBytesError.Limit})
+		return
+	}
+
+	app.errorResponse(w, r, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
+}
+
+// readJSON decodes the request body into dst, responding with what is wrong
+// with the body when it cannot.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := decodeJSON(r, dst); err != nil {
+		app.badJSON(w, r, err)
+		return false
+	}
+	return true
+}
+
 func (app *application) healthcheck(w http.ResponseWriter, _ *http.Request) {
 	app.writeResponse(w, http.StatusOK, envelope{
 		"status": "available",
@@ -40,14 +166,48 @@ func (app *application) healthcheck(w http.ResponseWriter, _ *http.Request) {
 func (app *application) initiateWorkflow(w http.ResponseWriter, r *http.Request) {
 	var request InitiateWorkflowRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		app.writeResponse(w, http.StatusBadRequest, envelope{
-			"error": "Invalid JSON",
-		})
+	if !app.readJSON(w, r, &request) {
+		return
+	}
+
+	// the scope and namespace are checked before the workflow is looked up,
+	// so that a key cannot tell which workflows exist outside its reach
+	if !app.allow(w, r, auth.InitiateScope(request.Name)) {
+		return
+	}
+	if namespace := app.service.WorkflowNamespace(request.Name); !service.InNamespaces(namespace, requestNamespaces(r)) {
+		app.errorResponse(w, r, http.StatusForbidden, codeForbidden,
+			"workflow "+request.Name+" is in namespace "+namespace+", which the API key is not bound to", details{"namespace": namespace})
+		return
+	}
+	if err := app.service.CheckWorkflow(request.Name); err != nil {
+		app.validationFailed(w, r, err.Error(), details{"field": "name", "workflow": request.Name})
+		return
+	}
+
+	if err := label.Validate(request.Labels); err != nil {
+		app.validationFailed(w, r, err.Error(), details{"field": "labels"})
+		return
+	}
+
+	opts := service.RunOptions{Priority: request.Priority, Labels: request.Labels}
+	if request.StartAt != nil && request.StartAfter != "" {
+		app.validationFailed(w, r, "only one of start_at and start_after may be given", details{"field": "start_after"})
 		return
 	}
+	if request.StartAt != nil {
+		opts.StartAt = *request.StartAt
+	}
+	if request.StartAfter != "" {
+		delay, err := time.ParseDuration(request.StartAfter)
+		if err != nil || delay < 0 {
+			app.validationFailed(w, r, "start_after must be a positive duration such as 30m or 72h", details{"field": "start_after"})
+			return
+		}
+		opts.StartAfter = delay
+	}
 
-	runID := app.service.InitiateWorkflow(r.Context(), request.Name)
+	runID := app.service.InitiateWorkflowWithOptions(r.Context(), request.Name, opts)
 	app.writeResponse(w, http.StatusCreated, envelope{
 		"run_id": runID,
 	})
@@ -56,22 +216,30 @@ func (app *application) initiateWorkflow(w http.ResponseWriter, r *http.Request)
 func (app *application) updateWorkflow(w http.ResponseWriter, r *http.Request) {
 	var request UpdateWorkflowRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		app.writeResponse(w, http.StatusBadRequest, envelope{
-			"error": "Invalid JSON",
-		})
+	if !app.readJSON(w, r, &request) {
+		return
+	}
+
+	// labels set to an empty value are removed, so only the others are
+	// validated
+	set := maps.Clone(request.Labels)
+	maps.DeleteFunc(set, func(_, v string) bool { return v == "" })
+	if err := label.Validate(set); err != nil {
+		app.validationFailed(w, r, err.Error(), details{"field": "labels"})
+		return
+	}
+
+	if !app.allowRun(w, r, request.RunID) {
 		return
 	}
 
-	err := app.service.UpdateWorkflow(r.Context(), request.RunID)
+	err := app.service.UpdateWorkflowWithOptions(r.Context(), request.RunID, service.UpdateOptions{Labels: request.Labels})
 	if err != nil {
-		app.writeResponse(w, http.StatusBadRequest, envelope{
-			"error": err.Error(),
-		})
+		app.serviceError(w, r, err, details{"run_id": request.RunID})
 		return
 	}
 
-	app.writeResponse(w, http.StatusBadRequest, envelope{
+	app.writeResponse(w, http.StatusOK, envelope{
 		"success": "run updated",
 	})
 }
@@ -79,56 +247,467 @@ func (app *application) updateWorkflow(w http.ResponseWriter, r *http.Request) {
 func (app *application) completeWorkflow(w http.ResponseWriter, r *http.Request) {
 	var request UpdateWorkflowRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		app.writeResponse(w, http.StatusBadRequest, envelope{
-			"error": "Invalid JSON",
-		})
+	if !app.readJSON(w, r, &request) {
+		return
+	}
+
+	if !app.allowRun(w, r, request.RunID) {
 		return
 	}
 
-	err := app.service.CompleteWorkflow(request.RunID)
+	err := app.service.CompleteWorkflow(r.Context(), request.RunID)
 	if err != nil {
-		app.writeResponse(w, http.StatusBadRequest, envelope{
-			"error": err.Error(),
-		})
+		app.serviceError(w, r, err, details{"run_id": request.RunID})
 		return
 	}
 
-	app.writeResponse(w, http.StatusBadRequest, envelope{
+	app.writeResponse(w, http.StatusOK, envelope{
 		"success": "run completed",
 	})
 }
 
-func (app *application) listRuns(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	defaultInt := 20
+func (app *application) cancelWorkflow(w http.ResponseWriter, r *http.Request) {
+	var request CancelWorkflowRequest
 
-	// Extract filters from query parameters
-	status := query.Get("status")
-	workflowName := query.Get("workflow")
-	page := 1
-	pageSize := 20
+	if !app.readJSON(w, r, &request) {
+		return
+	}
 
-	if p := query.Get("page"); p != "" {
-		page = parseInt(p, 1)
+	if !app.allowRun(w, r, request.RunID) {
+		return
 	}
-	if ps := query.Get("pageSize"); ps != "" {
-		pageSize = parseInt(ps, defaultInt)
+
+	err := app.service.CancelWorkflow(r.Context(), request.RunID, request.Reason)
+	if err != nil {
+		app.serviceError(w, r, err, details{"run_id": request.RunID})
+		return
 	}
 
-	// Create filter based on query params
-	filter := service.RunsFilter{
-		Status:       status,
-		WorkflowName: workflowName,
-		Page:         page,
-		PageSize:     pageSize,
+	app.writeResponse(w, http.StatusOK, envelope{
+		"success": "run cancelled",
+	})
+}
+
+func (app *application) resumeWorkflow(w http.ResponseWriter, r *http.Request) {
+	var request UpdateWorkflowRequest
+
+	if !app.readJSON(w, r, &request) {
+		return
+	}
+
+	if !app.allowRun(w, r, request.RunID) {
+		return
+	}
+
+	err := app.service.ResumeWorkflow(r.Context(), request.RunID)
+	if err != nil {
+		app.serviceError(w, r, err, details{"run_id": request.RunID})
+		return
+	}
+
+	app.writeResponse(w, http.StatusOK, envelope{
+		"success": "run resumed",
+	})
+}
+
+// bulkRuns starts a bulk action on the runs given by ID or selected by a
+// filter. A dry run is answered with every run's preview; otherwise the job
+// is accepted and its progress is read from its own endpoint.
+func (app *application) bulkRuns(w http.ResponseWriter, r *http.Request) {
+	var request BulkRunsRequest
+
+	if !app.readJSON(w, r, &request) {
+		return
+	}
+
+	bulk := service.BulkRequest{
+		Action:     service.BulkAction(request.Action),
+		RunIDs:     request.RunIDs,
+		Reason:     request.Reason,
+		DryRun:     request.DryRun,
+		Namespaces: requestNamespaces(r),
+	}
+	if request.Filter != nil {
+		query := make(url.Values, len(request.Filter))
+		for param, v := range request.Filter {
+			query.Set(param, v)
+		}
+		filter, err := runsFilter(query)
+		if err != nil {
+			app.validationFailed(w, r, err.Error(), details{"field": "filter"})
+			return
+		}
+		bulk.Filter = &filter
+	}
+
+	job, err := app.service.StartBulk(r.Context(), bulk)
+	if err != nil {
+		app.serviceError(w, r, err, nil)
+		return
+	}
+
+	status := http.StatusAccepted
+	if job.DryRun {
+		status = http.StatusOK
+	} else {
+		w.Header().Set("Location", "/api/runs/bulk/"+job.ID)
 	}
+	app.writeResponse(w, status, envelope{
+		"job": job,
+	})
+}
+
+// listBulkJobs lists the recent bulk jobs. A key bound to namespaces only
+// sees the jobs it started, since they may act on any of its runs.
+func (app *application) listBulkJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := app.service.BulkJobs()
+	if requestNamespaces(r) != nil {
+		key := requestKey(r)
+		jobs = slices.DeleteFunc(jobs, func(job service.BulkJob) bool { return job.Actor != key.ID })
+	}
+
+	app.writeResponse(w, http.StatusOK, envelope{
+		"jobs": jobs,
+	})
+}
+
+func (app *application) showBulkJob(w http.ResponseWriter, r *http.Request) {
+	job, err := app.service.BulkJob(r.PathValue("id"))
+	if err == nil && requestNamespaces(r) != nil && job.Actor != requestKey(r).ID {
+		err = service.ErrBulkJobNotFound
+	}
+	if err != nil {
+		app.serviceError(w, r, err, details{"job_id": r.PathValue("id")})
+		return
+	}
+
+	app.writeResponse(w, http.StatusOK, envelope{
+		"job": job,
+	})
+}
+
+func (app *application) heartbeat(w http.ResponseWriter, r *http.Request) {
+	var request HeartbeatRequest
+
+	// the body is optional, a bare POST simply records a heartbeat
+	if err := decodeJSON(r, &request); err != nil && !errors.Is(err, errEmptyBody) {
+		app.badJSON(w, r, err)
+		return
+	}
+
+	if !app.allowRun(w, r, r.PathValue("id")) {
+		return
+	}
+
+	err := app.service.Heartbeat(r.PathValue("id"), request.Progress, request.Message)
+	if err != nil {
+		app.serviceError(w, r, err, details{"run_id": r.PathValue("id")})
+		return
+	}
+
+	app.writeResponse(w, http.StatusOK, envelope{
+		"success": "heartbeat recorded",
+	})
+}
+
+func (app *application) listRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter, err := runsFilter(query)
+	if err == nil {
+		err = filter.Validate()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Namespaces = requestNamespaces(r)
 
 	// Retrieve runs based on the filter
 	runsResponse := app.service.GetRuns(filter)
 
+	// the pages link back to the first page and on to the next, keeping the
+	// rest of the query
+	data := struct {
+		service.RunsResponse
+		Query      url.Values
+		FirstURL   string
+		NextURL    string
+		ExportURLs map[string]string // by format
+	}{RunsResponse: runsResponse, Query: query, ExportURLs: make(map[string]string)}
+
+	pageQuery := maps.Clone(query)
+	pageQuery.Del("page")
+	pageQuery.Del("cursor")
+
+	// the export covers every page of the filtered runs
+	exportQuery := maps.Clone(pageQuery)
+	exportQuery.Del("pageSize")
+	for _, format := range []string{exportCSV, exportJSONL} {
+		exportQuery.Set("format", format)
+		data.ExportURLs[format] = "/api/runs/export?" + exportQuery.Encode()
+	}
+	if filter.Cursor != "" || filter.Page > 1 {
+		data.FirstURL = "/runs?" + pageQuery.Encode()
+	}
+	if runsResponse.NextCursor != "" {
+		pageQuery.Set("cursor", runsResponse.NextCursor)
+		data.NextURL = "/runs?" + pageQuery.Encode()
+	}
+
 	// Render template with Bootstrap styling
-	app.renderHTML(w, "runs.page.html", runsResponse)
+	app.renderHTML(w, "runs.html", data)
+}
+
+// Formats runs can be exported in.
+const (
+	exportCSV   = "csv"
+	exportJSONL = "jsonl"
+)
+
+// exportRowTimeout bounds how long writing a row of an export may take. The
+// write deadline is extended by it with every row, so that an export is not
+// cut off by the server's WriteTimeout while the client keeps reading.
+const exportRowTimeout = 10 * time.Second
+
+// exportColumns are the columns of a CSV export.
+var exportColumns = []string{
+	"id", "workflow_name", "status", "current_step", "start_time", "end_time",
+	"duration_seconds", "priority", "labels", "parent_run_id", "scheduled_for",
+	"namespace",
+}
+
+// exportRuns streams every run matching the same query parameters as the runs
+// page, except for the page and page size, as CSV or JSON Lines.
+func (app *application) exportRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = exportCSV
+	}
+	if format != exportCSV && format != exportJSONL {
+		app.errorResponse(w, r, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("invalid format %q", format), details{"param": "format"})
+		return
+	}
+
+	filter, err := runsFilter(query)
+	if err == nil {
+		err = filter.Validate()
+	}
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
+		return
+	}
+	filter.Namespaces = requestNamespaces(r)
+
+	// writers that cannot set deadlines, such as test recorders, have none
+	// to extend
+	rc := http.NewResponseController(w)
+	extendDeadline := func() {
+		_ = rc.SetWriteDeadline(time.Now().Add(exportRowTimeout))
+	}
+
+	runs := app.service.AllRuns(filter)
+	filename := fmt.Sprintf("runs-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// the status is sent with the first row, so errors past that point can
+	// only be logged
+	if format == exportJSONL {
+		w.Header().Set("Content-Type", "application/jsonl")
+		enc := json.NewEncoder(w)
+		for run := range runs {
+			extendDeadline()
+			if err = enc.Encode(run); err != nil {
+				break
+			}
+		}
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		extendDeadline()
+		err = cw.Write(exportColumns)
+		for run := range runs {
+			if err != nil {
+				break
+			}
+			extendDeadline()
+			err = cw.Write(csvRecord(run))
+		}
+		cw.Flush()
+		err = errors.Join(err, cw.Error())
+	}
+	if err != nil {
+		app.logger.Error("failed to export runs", "error", err.Error())
+	}
+}
+
+// csvRecord returns the run's row in a CSV export, in the order of
+// exportColumns. Labels are written as a selector matching them, such as
+// "customer_id=42,region=us".
+func csvRecord(run service.RunInfo) []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	duration := ""
+	if run.Duration != nil {
+		duration = strconv.FormatFloat(run.Duration.Seconds(), 'f', -1, 64)
+	}
+
+	labels := make([]string, 0, len(run.Labels))
+	for _, key := range slices.Sorted(maps.Keys(run.Labels)) {
+		labels = append(labels, key+"="+run.Labels[key])
+	}
+
+	return []string{
+		run.ID,
+		run.WorkflowName,
+		string(run.Status),
+		strconv.Itoa(run.CurrentStep),
+		formatTime(run.StartTime),
+		formatTime(run.EndTime),
+		duration,
+		strconv.Itoa(run.Priority),
+		strings.Join(labels, ","),
+		run.ParentRunID,
+		formatTime(run.ScheduledFor),
+		run.Namespace,
+	}
+}
+
+// runsFilter builds the filter of the runs page from its query parameters.
+// Times are RFC 3339, or the "2006-01-02T15:04" of a datetime-local input in
+// UTC, and durations are Go durations such as "90s" or "2h".
+func runsFilter(query url.Values) (service.RunsFilter, error) {
+	filter := service.RunsFilter{
+		Status:       query.Get("status"),
+		WorkflowName: query.Get("workflow"),
+		Namespace:    query.Get("namespace"),
+		Sort:         query.Get("sort"),
+		Order:        query.Get("order"),
+		Cursor:       query.Get("cursor"),
+		Page:         parseInt(query.Get("page"), 1),
+		PageSize:     parseInt(query.Get("pageSize"), 20),
+	}
+
+	if v := query.Get("priority"); v != "" {
+		priority, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid priority %q", v)
+		}
+		filter.Priority = &priority
+	}
+	if v := query.Get("step"); v != "" {
+		step, err := strconv.Atoi(v)
+		if err != nil || step < 0 {
+			return filter, fmt.Errorf("invalid step %q", v)
+		}
+		filter.Step = &step
+	}
+
+	selector, err := label.ParseSelector(query.Get("labels"))
+	if err != nil {
+		return filter, err
+	}
+	filter.Labels = selector
+
+	for param, t := range map[string]*time.Time{
+		"started_after":  &filter.StartedAfter,
+		"started_before": &filter.StartedBefore,
+		"ended_after":    &filter.EndedAfter,
+		"ended_before":   &filter.EndedBefore,
+	} {
+		v := query.Get(param)
+		if v == "" {
+			continue
+		}
+		if *t, err = parseQueryTime(v); err != nil {
+			return filter, fmt.Errorf("invalid %s %q", param, v)
+		}
+	}
+
+	if v := query.Get("min_duration"); v != "" {
+		if filter.MinDuration, err = time.ParseDuration(v); err != nil {
+			return filter, fmt.Errorf("invalid min_duration %q", v)
+		}
+	}
+
+	return filter, nil
+}
+
+func parseQueryTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02T15:04", v)
+}
+
+// getRun returns a single run with its history.
+func (app *application) getRun(w http.ResponseWriter, r *http.Request) {
+	runID := r.PathValue("id")
+	if !app.allowRun(w, r, runID) {
+		return
+	}
+
+	run, err := app.service.GetRun(runID)
+	if err != nil {
+		app.serviceError(w, r, err, details{"run_id": runID})
+		return
+	}
+
+	app.writeResponse(w, http.StatusOK, envelope{
+		"run": run,
+	})
+}
+
+// listWorkflows returns the workflows of the namespaces the request may see.
+func (app *application) listWorkflows(w http.ResponseWriter, r *http.Request) {
+	app.writeResponse(w, http.StatusOK, envelope{
+		"workflows": app.service.Workflows(requestNamespaces(r)),
+	})
+}
+
+// openAPI serves the OpenAPI document describing the API.
+func (app *application) openAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(api.OpenAPI); err != nil {
+		app.logger.Error(err.Error())
+	}
+}
+
+// showRun renders a single run along with its parent and child runs.
+func (app *application) showRun(w http.ResponseWriter, r *http.Request) {
+	namespaces := requestNamespaces(r)
+	run, err := app.service.GetRun(r.PathValue("id"))
+	if err != nil || !service.InNamespaces(run.Namespace, namespaces) {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := struct {
+		Run      service.RunInfo
+		Parent   *service.RunInfo
+		Children []service.RunInfo
+	}{Run: run}
+
+	// related runs are only shown in namespaces the request may see
+	if run.ParentRunID != "" {
+		if parent, err := app.service.GetRun(run.ParentRunID); err == nil && service.InNamespaces(parent.Namespace, namespaces) {
+			data.Parent = &parent
+		}
+	}
+	for _, childID := range run.ChildRunIDs {
+		if child, err := app.service.GetRun(childID); err == nil && service.InNamespaces(child.Namespace, namespaces) {
+			data.Children = append(data.Children, child)
+		}
+	}
+
+	app.renderHTML(w, "run.html", data)
 }
 
 func parseInt(val string, defaultInt int) int {
@@ -139,15 +718,80 @@ func parseInt(val string, defaultInt int) int {
 	return result
 }
 
-func (app *application) renderHTML(w http.ResponseWriter, _ string, data any) {
+func (app *application) listDeadLetters(w http.ResponseWriter, _ *http.Request) {
+	app.renderHTML(w, "deadletters.html", struct {
+		DeadLetters []service.DeadLetter
+		Replaying   bool
+	}{app.service.DeadLetters(), app.service.ReplayingDeadLetters()})
+}
+
+// replayDeadLetters starts replaying the dead letter named in the path, or
+// every dead letter when no ID is given, in the background. A single replay
+// is acknowledged with the dead-letter page; replaying every dead letter
+// returns to it.
+func (app *application) replayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if id := r.PathValue("id"); id != "" {
+		if err := app.service.StartDeadLetterReplay(id); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusAccepted)
+		app.listDeadLetters(w, r)
+		return
+	}
+
+	replaying := app.service.ReplayDeadLetters()
+	app.logger.Info("replaying dead letters in the background", "count", replaying)
+
+	http.Redirect(w, r, "/deadletters", http.StatusSeeOther)
+}
+
+// purgeDeadLetters discards the dead letter named in the path, or every dead
+// letter when no ID is given, then returns to the dead-letter page.
+func (app *application) purgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if id := r.PathValue("id"); id != "" {
+		if err := app.service.PurgeDeadLetter(id); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+	} else {
+		purged := app.service.PurgeDeadLetters()
+		app.logger.Info("purged dead letters", "purged", purged)
+	}
+
+	http.Redirect(w, r, "/deadletters", http.StatusSeeOther)
+}
+
+func (app *application) listSchedules(w http.ResponseWriter, r *http.Request) {
+	namespaces := requestNamespaces(r)
+	schedules := slices.DeleteFunc(app.service.Schedules(), func(s service.ScheduleStatus) bool {
+		return !service.InNamespaces(s.Namespace, namespaces)
+	})
+	app.renderHTML(w, "schedules.html", schedules)
+}
+
+func (app *application) listBreakers(w http.ResponseWriter, _ *http.Request) {
+	app.renderHTML(w, "breakers.html", app.service.Breakers())
+}
+
+func (app *application) renderHTML(w http.ResponseWriter, page string, data any) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	tmpl, err := template.New("runs.html").Funcs(template.FuncMap{
-		"formatTime": func(t *time.Time) string {
-			if t == nil {
-				return "-"
+	tmpl, err := template.New(page).Funcs(template.FuncMap{
+		"formatTime": func(t any) string {
+			switch v := t.(type) {
+			case *time.Time:
+				if v != nil {
+					return v.Format("2006-01-02 15:04:05")
+				}
+			case time.Time:
+				if !v.IsZero() {
+					return v.Format("2006-01-02 15:04:05")
+				}
 			}
-			return t.Format("2006-01-02 15:04:05")
+			return "-"
 		},
 		"formatDuration": func(d *time.Duration) string {
 			if d == nil {
@@ -163,23 +807,52 @@ func (app *application) renderHTML(w http.ResponseWriter, _ string, data any) {
 				return "bg-success"
 			case service.RunStatusFailed:
 				return "bg-danger"
+			case service.RunStatusTimedOut:
+				return "bg-warning"
+			case service.RunStatusCancelled:
+				return "bg-secondary"
+			case service.RunStatusScheduled:
+				return "bg-info"
+			case service.RunStatusQueued:
+				return "bg-dark"
+			default:
+				return "bg-secondary"
+			}
+		},
+		"phaseBadge": func(phase service.CompensationPhase) string {
+			switch phase {
+			case service.CompensationRunning:
+				return "bg-info"
+			case service.CompensationDone:
+				return "bg-success"
+			case service.CompensationFailed:
+				return "bg-danger"
+			default:
+				return "bg-secondary"
+			}
+		},
+		"breakerBadge": func(state service.BreakerState) string {
+			switch state {
+			case service.BreakerClosed:
+				return "bg-success"
+			case service.BreakerHalfOpen:
+				return "bg-warning"
+			case service.BreakerOpen:
+				return "bg-danger"
 			default:
 				return "bg-secondary"
 			}
 		},
-		"add": func(a, b int) int { return a + b },
-		"sub": func(a, b int) int { return a - b },
-		"iterate": func(count int) []int {
-			var items []int
-			for i := 0; i < count; i++ {
-				items = append(items, i)
+		"deref": func(f *float64) float64 {
+			if f == nil {
+				return 0
 			}
-			return items
+			return *f
 		},
 		"eq": func(a, b interface{}) bool { return a == b },
 		"gt": func(a, b int) bool { return a > b },
 		"lt": func(a, b int) bool { return a < b },
-	}).ParseFiles("../../web/templates/runs.html")
+	}).ParseFiles("../../web/templates/" + page)
 
 	if err != nil {
 		app.logger.Error("Template parse error: " + err.Error())
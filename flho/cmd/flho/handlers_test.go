@@ -1,13 +1,35 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"maps"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
-	"log/slog"
-	"os"
+	"time"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/windevkay/forge/flho/client"
+	"github.com/windevkay/forge/flho/internal/auth"
+	"github.com/windevkay/forge/flho/internal/metrics"
+	"github.com/windevkay/forge/flho/internal/ratelimit"
 	"github.com/windevkay/forge/flho/internal/service"
 	"github.com/windevkay/forge/flho/internal/workflow"
 	"github.com/windevkay/forge/genie/v2"
@@ -20,10 +42,10 @@ func TestListRunsHandler(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	
+
 	wg := &sync.WaitGroup{}
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	
+
 	app := &application{
 		service: service.NewWorkflowService(config, store, wg, logger),
 		logger:  logger,
@@ -61,10 +83,10 @@ func TestListRunsHandlerWithFilters(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	
+
 	wg := &sync.WaitGroup{}
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	
+
 	app := &application{
 		service: service.NewWorkflowService(config, store, wg, logger),
 		logger:  logger,
@@ -82,3 +104,1939 @@ func TestListRunsHandlerWithFilters(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 }
+
+func TestDeadLetterHandlers(t *testing.T) {
+	config := &workflow.ConfigStore{}
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	t.Run("list", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/deadletters", nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "No dead letters") {
+			t.Error("Expected empty dead-letter page")
+		}
+	})
+
+	t.Run("replay unknown", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/deadletters/missing/replay", nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("purge all", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/deadletters/purge", nil))
+
+		if w.Code != http.StatusSeeOther {
+			t.Errorf("Expected status 303, got %d", w.Code)
+		}
+		if loc := w.Header().Get("Location"); loc != "/deadletters" {
+			t.Errorf("Expected redirect to /deadletters, got %q", loc)
+		}
+	})
+}
+
+func TestReplayDeadLetterHandler(t *testing.T) {
+	// the host holds the replayed delivery until the test lets it through
+	release := make(chan struct{})
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer host.Close()
+
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("flho:deadletters", []any{
+		map[string]any{"id": "undelivered", "url": host.URL, "attempts": float64(3)},
+	})
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	wg := &sync.WaitGroup{}
+	app := &application{
+		service: service.NewWorkflowService(&workflow.ConfigStore{}, store, wg, logger),
+		logger:  logger,
+	}
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/deadletters/undelivered/replay", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "undelivered") {
+		t.Error("Expected the dead letter on the dead-letter page")
+	}
+
+	close(release)
+	wg.Wait()
+	if deadLetters := app.service.DeadLetters(); len(deadLetters) != 0 {
+		t.Errorf("Expected the replayed dead letter to be removed, got %d", len(deadLetters))
+	}
+}
+
+func TestBreakersHandler(t *testing.T) {
+	config := &workflow.ConfigStore{}
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/breakers", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Circuit Breakers") {
+		t.Error("Expected circuit breaker page")
+	}
+}
+
+func TestHeartbeatHandler(t *testing.T) {
+	config := &workflow.ConfigStore{}
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{name: "invalid JSON", body: "{", expectedCode: http.StatusBadRequest},
+		{name: "unknown run", body: `{"progress": 50, "message": "halfway"}`, expectedCode: http.StatusNotFound},
+		{name: "unknown run without body", body: "", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/runs/missing/heartbeat", strings.NewReader(tt.body)))
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+
+	t.Run("GET is not allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runs/missing/heartbeat", nil))
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status 405, got %d", w.Code)
+		}
+	})
+}
+
+func TestCancelWorkflowHandler(t *testing.T) {
+	config := &workflow.ConfigStore{}
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	runID := app.service.InitiateWorkflow(t.Context(), "test")
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{name: "invalid JSON", body: "{", expectedCode: http.StatusBadRequest},
+		{name: "unknown run", body: `{"run_id": "missing"}`, expectedCode: http.StatusNotFound},
+		{name: "cancels the run", body: `{"run_id": "` + runID + `", "reason": "no longer needed"}`, expectedCode: http.StatusOK},
+		{name: "already cancelled", body: `{"run_id": "` + runID + `"}`, expectedCode: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/cancelWorkflowRun", strings.NewReader(tt.body)))
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestShowRunHandler(t *testing.T) {
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"onboarding": {{"step0": {Workflow: "verify"}}},
+		"verify":     {{"step0": {Name: "verify"}}},
+	}, nil)
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	runID := app.service.InitiateWorkflow(t.Context(), "onboarding")
+
+	var childID string
+	for range 100 {
+		if run, err := app.service.GetRun(runID); err == nil && len(run.ChildRunIDs) == 1 {
+			childID = run.ChildRunIDs[0]
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if childID == "" {
+		t.Fatal("child run was not started")
+	}
+
+	t.Run("parent links to its child", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runs/"+runID, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `href="/runs/`+childID+`"`) {
+			t.Error("Expected a link to the child run")
+		}
+	})
+
+	t.Run("child links to its parent", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runs/"+childID, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `href="/runs/`+runID+`"`) {
+			t.Error("Expected a link to the parent run")
+		}
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runs/missing", nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestSchedulesHandler(t *testing.T) {
+	config := workflow.NewConfigStore(
+		workflow.Workflows{"nightly": {{"step0": {Name: "report"}}}},
+		map[string]workflow.Settings{"nightly": {Schedule: "0 2 * * *", Timezone: "Europe/London"}},
+	)
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/schedules", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "0 2 * * *") || !strings.Contains(body, "Europe/London") {
+		t.Error("Expected the nightly schedule to be listed")
+	}
+}
+
+func TestInitiateWorkflowDelayedStart(t *testing.T) {
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"trial": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+	}, nil)
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedCode   int
+		expectedStatus service.RunStatus
+	}{
+		{name: "start after a delay", body: `{"name": "trial", "start_after": "72h"}`, expectedCode: http.StatusCreated, expectedStatus: service.RunStatusScheduled},
+		{name: "start at a time", body: `{"name": "trial", "start_at": "2999-01-01T00:00:00Z"}`, expectedCode: http.StatusCreated, expectedStatus: service.RunStatusScheduled},
+		{name: "start immediately", body: `{"name": "trial"}`, expectedCode: http.StatusCreated, expectedStatus: service.RunStatusOngoing},
+		{name: "both start options", body: `{"name": "trial", "start_at": "2999-01-01T00:00:00Z", "start_after": "1h"}`, expectedCode: http.StatusUnprocessableEntity},
+		{name: "invalid delay", body: `{"name": "trial", "start_after": "tomorrow"}`, expectedCode: http.StatusUnprocessableEntity},
+		{name: "invalid time", body: `{"name": "trial", "start_at": "tomorrow"}`, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/initiateWorkflow", strings.NewReader(tt.body)))
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+			if tt.expectedStatus == "" {
+				return
+			}
+
+			var response struct {
+				RunID string `json:"run_id"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			run, err := app.service.GetRun(response.RunID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Status != tt.expectedStatus {
+				t.Errorf("Expected run status %s, got %s", tt.expectedStatus, run.Status)
+			}
+		})
+	}
+}
+
+func TestListRunsHandlerShowsQueues(t *testing.T) {
+	config := workflow.NewConfigStore(
+		workflow.Workflows{"export": {{"step0": {Name: "export", RetryAfter: time.Hour}}}},
+		map[string]workflow.Settings{"export": {MaxConcurrentRuns: 1}},
+	)
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+
+	app.service.InitiateWorkflow(t.Context(), "export")
+	app.service.InitiateWorkflow(t.Context(), "export")
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runs?status=queued", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "Concurrency") || !strings.Contains(body, `<span class="badge bg-dark">1</span>`) {
+		t.Error("Expected the queue depth of the export workflow")
+	}
+}
+
+func TestRunPriorityHandlers(t *testing.T) {
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"urgent":  {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+		"routine": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+	}, nil)
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/initiateWorkflow", strings.NewReader(`{"name": "urgent", "priority": 7}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	var response struct {
+		RunID string `json:"run_id"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	run, err := app.service.GetRun(response.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Priority != 7 {
+		t.Errorf("Expected priority 7, got %d", run.Priority)
+	}
+
+	lowID := app.service.InitiateWorkflow(t.Context(), "routine")
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runs?priority=7&sort=priority", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, response.RunID) {
+		t.Error("Expected the priority 7 run to be listed")
+	}
+	if strings.Contains(body, lowID) {
+		t.Error("Expected runs of other priorities to be filtered out")
+	}
+}
+
+func TestRunLabelHandlers(t *testing.T) {
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"billing": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+	}, nil)
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/initiateWorkflow", strings.NewReader(`{"name": "billing", "labels": {"customer_id": "42"}}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	var response struct {
+		RunID string `json:"run_id"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	otherID := app.service.InitiateWorkflow(t.Context(), "billing")
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/initiateWorkflow", strings.NewReader(`{"name": "billing", "labels": {"customer id": "42"}}`)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for an invalid label, got %d", w.Code)
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+		expectRun    bool
+		expectOther  bool
+	}{
+		{name: "matching selector", url: "/runs?labels=customer_id%3D42", expectedCode: http.StatusOK, expectRun: true},
+		{name: "negated selector", url: "/runs?labels=customer_id!%3D42", expectedCode: http.StatusOK, expectOther: true},
+		{name: "invalid selector", url: "/runs?labels=customer_id%3D42,", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			body := w.Body.String()
+			if strings.Contains(body, response.RunID) != tt.expectRun {
+				t.Errorf("Expected labelled run listed: %v", tt.expectRun)
+			}
+			if strings.Contains(body, otherID) != tt.expectOther {
+				t.Errorf("Expected unlabelled run listed: %v", tt.expectOther)
+			}
+		})
+	}
+}
+
+func TestListRunsHandlerQueries(t *testing.T) {
+	config := &workflow.ConfigStore{}
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	var ids []string
+	for range 3 {
+		ids = append(ids, app.service.InitiateWorkflow(t.Context(), "billing"))
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+	}{
+		{name: "all filters", url: "/runs?step=0&started_after=2020-01-01T00:00&ended_before=2999-01-01T00:00:00Z&min_duration=1s&sort=end_time&order=asc", expectedCode: http.StatusOK},
+		{name: "invalid step", url: "/runs?step=first", expectedCode: http.StatusBadRequest},
+		{name: "invalid priority", url: "/runs?priority=abc", expectedCode: http.StatusBadRequest},
+		{name: "invalid time", url: "/runs?started_after=yesterday", expectedCode: http.StatusBadRequest},
+		{name: "invalid duration", url: "/runs?min_duration=long", expectedCode: http.StatusBadRequest},
+		{name: "unknown sort key", url: "/runs?sort=name", expectedCode: http.StatusBadRequest},
+		{name: "invalid cursor", url: "/runs?cursor=abc", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("next links page through every run", func(t *testing.T) {
+		nextLink := regexp.MustCompile(`href="(/runs\?[^"]*cursor=[^"]*)"`)
+
+		seen := map[string]bool{}
+		url := "/runs?workflow=billing&pageSize=1"
+		for range len(ids) + 1 {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+
+			body := w.Body.String()
+			for _, id := range ids {
+				if strings.Contains(body, id) {
+					seen[id] = true
+				}
+			}
+
+			m := nextLink.FindStringSubmatch(body)
+			if m == nil {
+				break
+			}
+			url = html.UnescapeString(m[1])
+			if !strings.Contains(url, "workflow=billing") {
+				t.Errorf("Expected the next link to keep the filter, got %s", url)
+			}
+		}
+
+		if len(seen) != len(ids) {
+			t.Errorf("Expected to page through %d runs, saw %d", len(ids), len(seen))
+		}
+	})
+}
+
+func TestExportRunsHandler(t *testing.T) {
+	config := &workflow.ConfigStore{}
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		service: service.NewWorkflowService(config, store, &sync.WaitGroup{}, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	// more runs than fit in one page of the service
+	for range 501 {
+		app.service.InitiateWorkflow(t.Context(), "billing")
+	}
+	completedID := app.service.InitiateWorkflowWithOptions(t.Context(), "billing", service.RunOptions{Labels: map[string]string{"region": "us", "customer_id": "42"}})
+	if err := app.service.CompleteWorkflow(t.Context(), completedID); err != nil {
+		t.Fatal(err)
+	}
+	app.service.InitiateWorkflow(t.Context(), "export")
+
+	export := func(t *testing.T, url string, expectedCode int) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != expectedCode {
+			t.Fatalf("Expected status %d, got %d: %s", expectedCode, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	t.Run("csv of every matching run", func(t *testing.T) {
+		w := export(t, "/api/runs/export?workflow=billing&pageSize=1", http.StatusOK)
+		if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+			t.Errorf("Expected CSV content type, got %s", ct)
+		}
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 503 {
+			t.Fatalf("Expected a header and 502 runs, got %d records", len(records))
+		}
+		if strings.Join(records[0], ",") != "id,workflow_name,status,current_step,start_time,end_time,duration_seconds,priority,labels,parent_run_id,scheduled_for,namespace" {
+			t.Errorf("Unexpected header %v", records[0])
+		}
+	})
+
+	t.Run("csv of filtered runs", func(t *testing.T) {
+		w := export(t, "/api/runs/export?format=csv&status=completed", http.StatusOK)
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("Expected a header and 1 run, got %d records", len(records))
+		}
+		record := records[1]
+		if record[0] != completedID || record[2] != "completed" || record[6] == "" || record[8] != "customer_id=42,region=us" {
+			t.Errorf("Unexpected record %v", record)
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		w := export(t, "/api/runs/export?format=jsonl&labels=customer_id=42", http.StatusOK)
+
+		var runs []service.RunInfo
+		dec := json.NewDecoder(w.Body)
+		for dec.More() {
+			var run service.RunInfo
+			if err := dec.Decode(&run); err != nil {
+				t.Fatal(err)
+			}
+			runs = append(runs, run)
+		}
+		if len(runs) != 1 || runs[0].ID != completedID || runs[0].Labels["region"] != "us" || runs[0].EndTime == nil {
+			t.Errorf("Unexpected runs %+v", runs)
+		}
+	})
+
+	t.Run("invalid queries", func(t *testing.T) {
+		export(t, "/api/runs/export?format=xml", http.StatusBadRequest)
+		export(t, "/api/runs/export?min_duration=long", http.StatusBadRequest)
+	})
+
+	t.Run("runs page links to the export of its filters", func(t *testing.T) {
+		w := export(t, "/runs?workflow=billing&pageSize=5", http.StatusOK)
+		if !strings.Contains(w.Body.String(), `href="/api/runs/export?format=jsonl&amp;workflow=billing"`) {
+			t.Errorf("Expected an export link keeping the filters")
+		}
+	})
+
+	t.Run("exports outlive the server's write timeout", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(mux)
+		srv.Listener = slowListener{srv.Listener}
+		srv.Config.WriteTimeout = 50 * time.Millisecond
+		srv.Start()
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/api/runs/export?format=csv&workflow=billing")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Expected the whole export, got %d records: %v", len(records), err)
+		}
+		if len(records) != 503 {
+			t.Errorf("Expected a header and 502 runs, got %d records", len(records))
+		}
+	})
+}
+
+// slowListener accepts connections whose writes are slowed down, as over a
+// slow network.
+type slowListener struct {
+	net.Listener
+}
+
+func (l slowListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return slowConn{conn}, nil
+}
+
+type slowConn struct {
+	net.Conn
+}
+
+func (c slowConn) Write(b []byte) (int, error) {
+	time.Sleep(10 * time.Millisecond)
+	return c.Conn.Write(b)
+}
+
+func TestBulkRunsHandlers(t *testing.T) {
+	retries := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer retries.Close()
+
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"billing": {{"step0": {RetryAfter: 10 * time.Millisecond, RetryURL: retries.URL}}},
+		"export":  {{"step0": {RetryAfter: time.Hour, RetryURL: retries.URL}}},
+	}, nil)
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	wg := &sync.WaitGroup{}
+	app := &application{
+		service: service.NewWorkflowService(config, store, wg, logger),
+		logger:  logger,
+	}
+	mux := app.routes()
+
+	serve := func(method, url, body string, expectedCode int) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+		if w.Code != expectedCode {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", method, url, expectedCode, w.Code, w.Body.String())
+		}
+		return w
+	}
+	decodeJob := func(w *httptest.ResponseRecorder) service.BulkJob {
+		var response struct {
+			Job service.BulkJob `json:"job"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Job
+	}
+
+	// billing runs fail as soon as their retry notification is sent
+	failedID := app.service.InitiateWorkflow(t.Context(), "billing")
+	var exportIDs []string
+	for range 2 {
+		exportIDs = append(exportIDs, app.service.InitiateWorkflow(t.Context(), "export"))
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if run, _ := app.service.GetRun(failedID); run.Status == service.RunStatusFailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("run did not fail")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	serve(http.MethodPost, "/resumeWorkflowRun", `{"run_id": "`+exportIDs[0]+`"}`, http.StatusConflict)
+	serve(http.MethodPost, "/resumeWorkflowRun", `{"run_id": "`+failedID+`"}`, http.StatusOK)
+
+	t.Run("dry run", func(t *testing.T) {
+		job := decodeJob(serve(http.MethodPost, "/api/runs/bulk", `{"action": "cancel", "filter": {"workflow": "export"}, "dry_run": true}`, http.StatusOK))
+		if job.State != service.BulkJobDone || job.Total != 2 || job.Succeeded != 2 || len(job.Results) != 2 {
+			t.Errorf("Unexpected dry run %+v", job)
+		}
+		if run, _ := app.service.GetRun(exportIDs[0]); run.Status != service.RunStatusOngoing {
+			t.Errorf("Expected the dry run to leave the run ongoing, got %s", run.Status)
+		}
+	})
+
+	t.Run("job progress", func(t *testing.T) {
+		w := serve(http.MethodPost, "/api/runs/bulk", `{"action": "cancel", "run_ids": ["`+exportIDs[0]+`", "missing"], "reason": "duplicate"}`, http.StatusAccepted)
+		location := w.Header().Get("Location")
+		if !strings.HasPrefix(location, "/api/runs/bulk/") {
+			t.Fatalf("Expected the job's location, got %q", location)
+		}
+
+		var job service.BulkJob
+		for range 1000 {
+			if job = decodeJob(serve(http.MethodGet, location, "", http.StatusOK)); job.State != service.BulkJobRunning {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if job.Succeeded != 1 || job.Failed != 1 || job.Results[0].Status != service.RunStatusCancelled {
+			t.Errorf("Unexpected job %+v", job)
+		}
+
+		var list struct {
+			Jobs []service.BulkJob `json:"jobs"`
+		}
+		if err := json.NewDecoder(serve(http.MethodGet, "/api/runs/bulk", "", http.StatusOK).Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Jobs) != 2 || list.Jobs[0].ID != job.ID {
+			t.Errorf("Expected the newest job first, got %+v", list.Jobs)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		serve(http.MethodPost, "/api/runs/bulk", `{"action": "restart", "run_ids": ["a"]}`, http.StatusUnprocessableEntity)
+		serve(http.MethodPost, "/api/runs/bulk", `{"action": "cancel"}`, http.StatusUnprocessableEntity)
+		serve(http.MethodPost, "/api/runs/bulk", `{"action": "cancel", "filter": {"min_duration": "long"}}`, http.StatusUnprocessableEntity)
+		serve(http.MethodPost, "/api/runs/bulk", `not json`, http.StatusBadRequest)
+		serve(http.MethodGet, "/api/runs/bulk/unknown", "", http.StatusNotFound)
+	})
+}
+
+func TestMetricsHandler(t *testing.T) {
+	// backups are written to the home directory
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := metrics.NewRegistry()
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"export": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+	}, nil)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		datastore: store,
+		logger:    logger,
+		metrics:   registry,
+		backups:   registry.Counter("flho_store_backups_total", "Backups of the datastore, by result.", "result"),
+	}
+	app.service = service.NewWorkflowService(config, store, &app.wg, logger, service.WithMetrics(registry))
+
+	runID := app.service.InitiateWorkflow(t.Context(), "export")
+	app.backup()
+
+	// backups fail once the home directory is gone
+	if err := os.RemoveAll(home); err != nil {
+		t.Fatal(err)
+	}
+	app.backup()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != metrics.ContentType {
+		t.Errorf("Expected content type %q, got %q", metrics.ContentType, contentType)
+	}
+	for _, line := range []string{
+		`flho_runs_started_total{workflow="export"} 1`,
+		`flho_store_backups_total{result="failure"} 1`,
+		`flho_store_backups_total{result="success"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), line) {
+			t.Errorf("Expected %q in metrics, got:\n%s", line, w.Body.String())
+		}
+	}
+
+	if err := app.service.CancelWorkflow(t.Context(), runID, ""); err != nil {
+		t.Fatal(err)
+	}
+	app.wg.Wait()
+}
+
+func TestTraceRequests(t *testing.T) {
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"billing": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+	}, nil)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		logger: logger,
+		tracer: tp.Tracer("test"),
+	}
+	app.service = service.NewWorkflowService(config, store, &app.wg, logger, service.WithTracerProvider(tp))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/initiateWorkflow", strings.NewReader(`{"name": "billing"}`))
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	var response struct {
+		RunID string `json:"run_id"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.service.CompleteWorkflow(t.Context(), response.RunID); err != nil {
+		t.Fatal(err)
+	}
+	app.wg.Wait()
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	server, ok := spans["POST /initiateWorkflow"]
+	if !ok {
+		t.Fatalf("Expected a span for the request, got %+v", spans)
+	}
+	if server.SpanContext.TraceID().String() != traceID || server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the request to continue the caller's trace, got %s with parent %s", server.SpanContext.TraceID(), server.Parent.SpanID())
+	}
+
+	run, ok := spans["run billing"]
+	if !ok {
+		t.Fatalf("Expected a span for the run, got %+v", spans)
+	}
+	if run.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("Expected the run span to be a child of the request span")
+	}
+}
+
+func TestNewTracerProvider(t *testing.T) {
+	path := t.TempDir() + "/traces.jsonl"
+
+	tp, err := newTracerProvider(t.Context(), config{traceExporter: traceExporterFile, traceFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := tp.Tracer("test").Start(t.Context(), "exported span")
+	span.End()
+	if err := tp.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Name":"exported span"`) {
+		t.Errorf("Expected the span in the trace file, got %s", data)
+	}
+
+	if tp, err := newTracerProvider(t.Context(), config{traceExporter: traceExporterNone}); tp != nil || err != nil {
+		t.Errorf("Expected no tracer provider, got %v, %v", tp, err)
+	}
+	if _, err := newTracerProvider(t.Context(), config{traceExporter: "jaeger"}); err == nil {
+		t.Error("Expected an unknown exporter to be rejected")
+	}
+	if _, err := newTracerProvider(t.Context(), config{traceExporter: traceExporterFile}); err == nil {
+		t.Error("Expected the file exporter to need a file")
+	}
+}
+
+func TestLoadSigningSecret(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	secret, err := loadSigningSecret(write("secret", "0123456789abcdef0123456789abcdef\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(secret) != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("Expected the secret without its trailing newline, got %q", secret)
+	}
+
+	if _, err := loadSigningSecret(write("short", "too short\n")); err == nil {
+		t.Error("Expected a short secret to be rejected")
+	}
+	if _, err := loadSigningSecret(dir + "/missing"); err == nil {
+		t.Error("Expected a missing file to be rejected")
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.NewKeys([]auth.Key{
+		{ID: "billing-service", Hash: auth.HashSecret("billing-secret"), Scopes: []auth.Scope{auth.ScopeRunsRead, auth.ScopeRunsAdvance, auth.InitiateScope("billing")}},
+		{ID: "viewer", Hash: auth.HashSecret("viewer-secret"), Scopes: []auth.Scope{auth.ScopeRunsRead}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"billing": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		},
+		"export": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+	}, nil)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		logger:   logger,
+		keys:     keys,
+		sessions: auth.NewSessions(),
+		metrics:  metrics.NewRegistry(),
+	}
+	app.service = service.NewWorkflowService(config, store, &app.wg, logger)
+	mux := app.routes()
+
+	serve := func(method, url, secret, body string, expectedCode int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != expectedCode {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", method, url, expectedCode, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	serve(http.MethodGet, "/health", "", "", http.StatusOK)
+
+	w := serve(http.MethodPost, "/initiateWorkflow", "", `{"name": "billing"}`, http.StatusUnauthorized)
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected a WWW-Authenticate header")
+	}
+	serve(http.MethodPost, "/initiateWorkflow", "wrong-secret", `{"name": "billing"}`, http.StatusUnauthorized)
+	serve(http.MethodPost, "/initiateWorkflow", "billing-secret", `{"name": "export"}`, http.StatusForbidden)
+	serve(http.MethodPost, "/initiateWorkflow", "viewer-secret", `{"name": "billing"}`, http.StatusForbidden)
+	serve(http.MethodGet, "/metrics", "billing-secret", "", http.StatusForbidden)
+	serve(http.MethodGet, "/api/runs/export", "viewer-secret", "", http.StatusOK)
+
+	var response struct {
+		RunID string `json:"run_id"`
+	}
+	w = serve(http.MethodPost, "/initiateWorkflow", "billing-secret", `{"name": "billing"}`, http.StatusCreated)
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	serve(http.MethodPost, "/updateWorkflowRun", "viewer-secret", `{"run_id": "`+response.RunID+`"}`, http.StatusForbidden)
+	serve(http.MethodPost, "/updateWorkflowRun", "billing-secret", `{"run_id": "`+response.RunID+`"}`, http.StatusOK)
+
+	run, err := app.service.GetRun(response.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actors []string
+	for _, event := range run.History {
+		actors = append(actors, string(event.Type)+":"+event.Actor)
+	}
+	expected := []string{"created:billing-service", "started:billing-service", "advanced:billing-service"}
+	if !slices.Equal(actors, expected) {
+		t.Errorf("Expected history %v, got %v", expected, actors)
+	}
+
+	t.Run("the UI requires a login", func(t *testing.T) {
+		w := serve(http.MethodGet, "/runs/"+response.RunID, "", "", http.StatusSeeOther)
+		if location := w.Header().Get("Location"); location != "/login?next="+url.QueryEscape("/runs/"+response.RunID) {
+			t.Fatalf("Expected a redirect to the login page, got %q", location)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(url.Values{"api_key": {"wrong-secret"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if len(w.Result().Cookies()) != 0 || !strings.Contains(w.Body.String(), "Invalid API key") {
+			t.Fatalf("Expected the login to be refused, got %d: %s", w.Code, w.Body.String())
+		}
+
+		form := url.Values{"api_key": {"viewer-secret"}, "next": {"/runs/" + response.RunID}}
+		req = httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/runs/"+response.RunID {
+			t.Fatalf("Expected a redirect back to the run, got %d to %q", w.Code, w.Header().Get("Location"))
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteStrictMode {
+			t.Fatalf("Expected an HttpOnly session cookie, got %+v", cookies)
+		}
+
+		get := func(url string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			req.AddCookie(cookies[0])
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			return w
+		}
+		w = get("/runs/" + response.RunID)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "billing-service") {
+			t.Errorf("Expected the run page with its history, got %d", w.Code)
+		}
+		if w = get("/deadletters"); w.Code != http.StatusForbidden {
+			t.Errorf("Expected the dead letters to need the admin scope, got %d", w.Code)
+		}
+
+		req = httptest.NewRequest(http.MethodPost, "/logout", nil)
+		req.AddCookie(cookies[0])
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+		if w = get("/runs"); w.Code != http.StatusSeeOther {
+			t.Errorf("Expected the session to end on logout, got %d", w.Code)
+		}
+	})
+
+	if got := localPath("//evil.example.com"); got != "/runs" {
+		t.Errorf("Expected an external redirect to be refused, got %q", got)
+	}
+
+	if err := app.service.CancelWorkflow(t.Context(), response.RunID, ""); err != nil {
+		t.Fatal(err)
+	}
+	app.wg.Wait()
+}
+
+func TestNamespaces(t *testing.T) {
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.NewKeys([]auth.Key{
+		{ID: "billing-service", Hash: auth.HashSecret("billing-secret"), Scopes: []auth.Scope{auth.ScopeRunsRead, auth.ScopeRunsAdvance, auth.InitiateScope("*")}, Namespaces: []string{"billing"}},
+		{ID: "ops", Hash: auth.HashSecret("ops-secret"), Scopes: []auth.Scope{auth.ScopeAdmin}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"invoice": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+		"export":  {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+		// starts a child run in another namespace, which the configuration
+		// file would not allow
+		"signup": {{"step0": {Workflow: "export"}}},
+	}, map[string]workflow.Settings{"invoice": {Namespace: "billing"}, "signup": {Namespace: "billing"}})
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		logger:   logger,
+		keys:     keys,
+		sessions: auth.NewSessions(),
+	}
+	app.service = service.NewWorkflowService(config, store, &app.wg, logger)
+	mux := app.routes()
+
+	serve := func(method, url, secret, body string, expectedCode int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != expectedCode {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", method, url, expectedCode, w.Code, w.Body.String())
+		}
+		return w
+	}
+	initiate := func(secret, name string) string {
+		var response struct {
+			RunID string `json:"run_id"`
+		}
+		if err := json.NewDecoder(serve(http.MethodPost, "/initiateWorkflow", secret, `{"name": "`+name+`"}`, http.StatusCreated).Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return response.RunID
+	}
+
+	invoiceID := initiate("billing-secret", "invoice")
+	exportID := initiate("ops-secret", "export")
+	serve(http.MethodPost, "/initiateWorkflow", "billing-secret", `{"name": "export"}`, http.StatusForbidden)
+
+	// unknown workflows are refused like those of other namespaces, so that
+	// keys cannot probe which workflows exist
+	serve(http.MethodPost, "/initiateWorkflow", "billing-secret", `{"name": "missing"}`, http.StatusForbidden)
+	serve(http.MethodPost, "/initiateWorkflow", "ops-secret", `{"name": "missing"}`, http.StatusUnprocessableEntity)
+
+	// runs of other namespaces are not found
+	w := serve(http.MethodPost, "/cancelWorkflowRun", "billing-secret", `{"run_id": "`+exportID+`"}`, http.StatusNotFound)
+	if !strings.Contains(w.Body.String(), "no data found for run ID") {
+		t.Errorf("Expected the run not to be found, got %s", w.Body.String())
+	}
+	serve(http.MethodPost, "/runs/"+exportID+"/heartbeat", "billing-secret", "", http.StatusNotFound)
+	serve(http.MethodGet, "/runs/"+exportID, "billing-secret", "", http.StatusNotFound)
+	serve(http.MethodGet, "/runs/"+invoiceID, "billing-secret", "", http.StatusOK)
+	serve(http.MethodPost, "/runs/"+invoiceID+"/heartbeat", "billing-secret", "", http.StatusOK)
+
+	var runs []service.RunInfo
+	dec := json.NewDecoder(serve(http.MethodGet, "/api/runs/export?format=jsonl", "billing-secret", "", http.StatusOK).Body)
+	for dec.More() {
+		var run service.RunInfo
+		if err := dec.Decode(&run); err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, run)
+	}
+	if len(runs) != 1 || runs[0].ID != invoiceID || runs[0].Namespace != "billing" {
+		t.Errorf("Expected only the billing run, got %+v", runs)
+	}
+	if body := serve(http.MethodGet, "/runs", "ops-secret", "", http.StatusOK).Body.String(); !strings.Contains(body, exportID) || !strings.Contains(body, invoiceID) {
+		t.Error("Expected a key without namespaces to see every run")
+	}
+
+	// bulk jobs only reach the key's runs, and only their own key sees them
+	var response struct {
+		Job service.BulkJob `json:"job"`
+	}
+	w = serve(http.MethodPost, "/api/runs/bulk", "billing-secret", `{"action": "cancel", "filter": {}, "dry_run": true}`, http.StatusOK)
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Job.Total != 1 || response.Job.Results[0].RunID != invoiceID {
+		t.Errorf("Expected the job to select the billing run only, got %+v", response.Job)
+	}
+	serve(http.MethodGet, "/api/runs/bulk/"+response.Job.ID, "billing-secret", "", http.StatusOK)
+	w = serve(http.MethodPost, "/api/runs/bulk", "ops-secret", `{"action": "cancel", "run_ids": ["`+exportID+`"], "dry_run": true}`, http.StatusOK)
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	serve(http.MethodGet, "/api/runs/bulk/"+response.Job.ID, "billing-secret", "", http.StatusNotFound)
+
+	// related runs of other namespaces are left off a run's page
+	signupID := initiate("billing-secret", "signup")
+	var childID string
+	for range 100 {
+		if run, err := app.service.GetRun(signupID); err == nil && len(run.ChildRunIDs) == 1 {
+			childID = run.ChildRunIDs[0]
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if childID == "" {
+		t.Fatal("child run was not started")
+	}
+	if body := serve(http.MethodGet, "/runs/"+signupID, "billing-secret", "", http.StatusOK).Body.String(); strings.Contains(body, childID) {
+		t.Error("Expected the child run of another namespace to be hidden")
+	}
+	if body := serve(http.MethodGet, "/runs/"+signupID, "ops-secret", "", http.StatusOK).Body.String(); !strings.Contains(body, childID) {
+		t.Error("Expected a key without namespaces to see the child run")
+	}
+	serve(http.MethodGet, "/runs/"+childID, "billing-secret", "", http.StatusNotFound)
+
+	for _, runID := range []string{invoiceID, exportID, signupID} {
+		if err := app.service.CancelWorkflow(t.Context(), runID, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.wg.Wait()
+}
+
+func TestLimits(t *testing.T) {
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.NewKeys([]auth.Key{
+		{ID: "billing-service", Hash: auth.HashSecret("billing-secret"), Scopes: []auth.Scope{auth.ScopeAdmin}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientLimits, err := ratelimit.New(0.001, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyLimits, err := ratelimit.New(0.001, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workflows := workflow.NewConfigStore(workflow.Workflows{
+		"test": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+	}, nil)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	registry := metrics.NewRegistry()
+	app := &application{
+		config:       config{maxBodyBytes: 64},
+		logger:       logger,
+		keys:         keys,
+		sessions:     auth.NewSessions(),
+		clientLimits: clientLimits,
+		keyLimits:    keyLimits,
+		metrics:      registry,
+		rateLimited:  registry.Counter("flho_http_rate_limited_total", "Requests refused by a rate limit, by limit.", "limit"),
+	}
+	app.service = service.NewWorkflowService(workflows, store, &app.wg, logger)
+	mux := app.routes()
+
+	serve := func(path, remoteAddr, secret, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("request bodies", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			body         string
+			expectedCode int
+			expectedErr  string
+		}{
+			{name: "empty", body: "", expectedCode: http.StatusBadRequest, expectedErr: "body must not be empty"},
+			{name: "badly-formed", body: `{"name": }`, expectedCode: http.StatusBadRequest, expectedErr: "badly-formed JSON (at character 10)"},
+			{name: "truncated", body: `{"name": "x"`, expectedCode: http.StatusBadRequest, expectedErr: "badly-formed JSON"},
+			{name: "wrong type", body: `{"name": 42}`, expectedCode: http.StatusBadRequest, expectedErr: `body contains a number for field "name", which must be a string`},
+			{name: "unknown field", body: `{"workflow": "x"}`, expectedCode: http.StatusBadRequest, expectedErr: `body contains unknown field "workflow"`},
+			{name: "several values", body: `{"name": "x"} {}`, expectedCode: http.StatusBadRequest, expectedErr: "single JSON value"},
+			{name: "too large", body: `{"name": "x", "labels": {"a": "` + strings.Repeat("a", 64) + `"}}`, expectedCode: http.StatusRequestEntityTooLarge, expectedErr: "body must not be larger than 64 bytes"},
+		}
+
+		for i, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// bodies are decoded before the workflow's scope is checked; each
+				// case comes from its own client, so the client limit is not reached
+				w := serve("/initiateWorkflow", fmt.Sprintf("192.0.2.%d:1234", i+10), "", tt.body)
+				if w.Code != tt.expectedCode {
+					t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+				}
+				var response struct {
+					Error struct {
+						Message string `json:"message"`
+					} `json:"error"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(response.Error.Message, tt.expectedErr) {
+					t.Errorf("Expected error %q, got %q", tt.expectedErr, response.Error.Message)
+				}
+			})
+		}
+	})
+
+	t.Run("client rate limit", func(t *testing.T) {
+		for range 3 {
+			if w := serve("/cancelWorkflowRun", "192.0.2.1:1234", "", `{"run_id": "missing"}`); w.Code == http.StatusTooManyRequests {
+				t.Fatalf("Expected the burst to be allowed, got %d", w.Code)
+			}
+		}
+
+		// the limit applies before authentication, so guessing keys is limited too
+		w := serve("/cancelWorkflowRun", "192.0.2.1:5678", "guess", `{"run_id": "missing"}`)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+		}
+		if retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retryAfter < 1 {
+			t.Errorf("Expected a Retry-After in seconds, got %q", w.Header().Get("Retry-After"))
+		}
+
+		if w := serve("/cancelWorkflowRun", "192.0.2.2:1234", "", `{"run_id": "missing"}`); w.Code == http.StatusTooManyRequests {
+			t.Error("Expected other clients not to be limited")
+		}
+	})
+
+	t.Run("key rate limit", func(t *testing.T) {
+		// the key is limited however many clients share it
+		for i := range 2 {
+			if w := serve("/cancelWorkflowRun", fmt.Sprintf("198.51.100.%d:1234", i), "billing-secret", `{"run_id": "missing"}`); w.Code != http.StatusNotFound {
+				t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+			}
+		}
+		w := serve("/cancelWorkflowRun", "198.51.100.9:1234", "billing-secret", `{"run_id": "missing"}`)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Error("Expected a Retry-After header")
+		}
+	})
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`flho_http_rate_limited_total{limit="client"} 1`,
+		`flho_http_rate_limited_total{limit="key"} 1`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in metrics, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"billing": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		},
+	}, nil)
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{logger: logger}
+	app.service = service.NewWorkflowService(config, store, &app.wg, logger)
+	mux := app.routes()
+
+	type errorBody struct {
+		Error struct {
+			Code      string         `json:"code"`
+			Message   string         `json:"message"`
+			Details   map[string]any `json:"details"`
+			RequestID string         `json:"request_id"`
+		} `json:"error"`
+	}
+	serve := func(req *http.Request, expectedCode int) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != expectedCode {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", req.Method, req.URL, expectedCode, w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Request-ID") == "" {
+			t.Errorf("%s %s: expected an X-Request-ID header", req.Method, req.URL)
+		}
+		return w
+	}
+	decodeError := func(w *httptest.ResponseRecorder, code string) errorBody {
+		var body errorBody
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Error.Code != code {
+			t.Errorf("Expected code %q, got %q", code, body.Error.Code)
+		}
+		if body.Error.RequestID != w.Header().Get("X-Request-ID") {
+			t.Errorf("Expected request ID %q, got %q", w.Header().Get("X-Request-ID"), body.Error.RequestID)
+		}
+		return body
+	}
+	post := func(url, body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	}
+
+	t.Run("request IDs are echoed", func(t *testing.T) {
+		req := post("/cancelWorkflowRun", `{"run_id": "missing"}`)
+		req.Header.Set("X-Request-ID", "client-chosen.42")
+		w := serve(req, http.StatusNotFound)
+		body := decodeError(w, "not_found")
+		if body.Error.RequestID != "client-chosen.42" {
+			t.Errorf("Expected the client's request ID, got %q", body.Error.RequestID)
+		}
+		if body.Error.Details["run_id"] != "missing" {
+			t.Errorf("Expected the run ID in the details, got %v", body.Error.Details)
+		}
+
+		req = post("/cancelWorkflowRun", `{"run_id": "missing"}`)
+		req.Header.Set("X-Request-ID", "not valid\n")
+		if id := serve(req, http.StatusNotFound).Header().Get("X-Request-ID"); id == "not valid\n" {
+			t.Error("Expected an invalid request ID to be replaced")
+		}
+	})
+
+	t.Run("unknown workflows are rejected", func(t *testing.T) {
+		body := decodeError(serve(post("/initiateWorkflow", `{"name": "missing"}`), http.StatusUnprocessableEntity), "validation_failed")
+		if body.Error.Details["workflow"] != "missing" {
+			t.Errorf("Expected the workflow in the details, got %v", body.Error.Details)
+		}
+	})
+
+	t.Run("run operations answer success with 200", func(t *testing.T) {
+		var response struct {
+			RunID string `json:"run_id"`
+		}
+		if err := json.NewDecoder(serve(post("/initiateWorkflow", `{"name": "billing"}`), http.StatusCreated).Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		serve(post("/updateWorkflowRun", `{"run_id": "`+response.RunID+`"}`), http.StatusOK)
+		serve(post("/completeWorkflowRun", `{"run_id": "`+response.RunID+`"}`), http.StatusOK)
+		decodeError(serve(post("/completeWorkflowRun", `{"run_id": "`+response.RunID+`"}`), http.StatusConflict), "conflict")
+		decodeError(serve(post("/runs/"+response.RunID+"/heartbeat", `{"progress": 101}`), http.StatusUnprocessableEntity), "validation_failed")
+	})
+
+	t.Run("methods are enforced", func(t *testing.T) {
+		for _, url := range []string{"/initiateWorkflow", "/updateWorkflowRun", "/completeWorkflowRun"} {
+			w := serve(httptest.NewRequest(http.MethodGet, url, nil), http.StatusMethodNotAllowed)
+			if allow := w.Header().Get("Allow"); allow != "POST" {
+				t.Errorf("GET %s: expected Allow: POST, got %q", url, allow)
+			}
+			decodeError(w, "method_not_allowed")
+		}
+
+		w := serve(httptest.NewRequest(http.MethodDelete, "/api/runs/bulk", nil), http.StatusMethodNotAllowed)
+		body := decodeError(w, "method_not_allowed")
+		if allowed, _ := body.Error.Details["allowed_methods"].([]any); len(allowed) < 2 {
+			t.Errorf("Expected the allowed methods in the details, got %v", body.Error.Details)
+		}
+
+		serve(post("/health", ""), http.StatusMethodNotAllowed)
+		serve(post("/runs", ""), http.StatusMethodNotAllowed)
+	})
+
+	t.Run("malformed queries are rejected", func(t *testing.T) {
+		for _, query := range []string{"step=first", "priority=abc"} {
+			body := decodeError(serve(httptest.NewRequest(http.MethodGet, "/api/runs/export?"+query, nil), http.StatusBadRequest), "bad_request")
+			if !strings.Contains(body.Error.Message, strings.Split(query, "=")[0]) {
+				t.Errorf("Expected the parameter in the message, got %q", body.Error.Message)
+			}
+		}
+	})
+
+	t.Run("unknown routes", func(t *testing.T) {
+		decodeError(serve(httptest.NewRequest(http.MethodGet, "/missing", nil), http.StatusNotFound), "not_found")
+	})
+
+	app.wg.Wait()
+}
+
+func TestIdempotency(t *testing.T) {
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.NewKeys([]auth.Key{
+		{ID: "billing-a", Hash: auth.HashSecret("secret-a"), Scopes: []auth.Scope{auth.ScopeRunsAdvance, auth.InitiateScope("billing")}},
+		{ID: "billing-b", Hash: auth.HashSecret("secret-b"), Scopes: []auth.Scope{auth.ScopeRunsAdvance, auth.InitiateScope("billing")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"billing": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		},
+	}, nil)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		logger:      logger,
+		keys:        keys,
+		sessions:    auth.NewSessions(),
+		idempotency: newIdempotencyStore(),
+	}
+	app.service = service.NewWorkflowService(config, store, &app.wg, logger)
+	mux := app.routes()
+
+	serve := func(url, secret, key, body string, expectedCode int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != expectedCode {
+			t.Fatalf("POST %s: expected status %d, got %d: %s", url, expectedCode, w.Code, w.Body.String())
+		}
+		return w
+	}
+	runID := func(w *httptest.ResponseRecorder) string {
+		var response struct {
+			RunID string `json:"run_id"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response.RunID
+	}
+	var ongoing []string
+
+	t.Run("retries get the first response", func(t *testing.T) {
+		first := serve("/initiateWorkflow", "secret-a", "order-1", `{"name": "billing"}`, http.StatusCreated)
+		if first.Header().Get("Idempotent-Replayed") != "" {
+			t.Error("Expected the first response not to be marked as replayed")
+		}
+
+		retry := serve("/initiateWorkflow", "secret-a", "order-1", `{"name": "billing"}`, http.StatusCreated)
+		if runID(retry) != runID(first) {
+			t.Errorf("Expected the retry to get run %s, got %s", runID(first), runID(retry))
+		}
+		if retry.Header().Get("Idempotent-Replayed") != "true" {
+			t.Error("Expected the retry to be marked as replayed")
+		}
+		if retry.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected the replayed Content-Type, got %q", retry.Header().Get("Content-Type"))
+		}
+
+		serve("/completeWorkflowRun", "secret-a", "complete-1", `{"run_id": "`+runID(first)+`"}`, http.StatusOK)
+		serve("/completeWorkflowRun", "secret-a", "complete-1", `{"run_id": "`+runID(first)+`"}`, http.StatusOK)
+		// without a key, completing a completed run conflicts
+		serve("/completeWorkflowRun", "secret-a", "", `{"run_id": "`+runID(first)+`"}`, http.StatusConflict)
+	})
+
+	t.Run("keys are kept per API key", func(t *testing.T) {
+		a := serve("/initiateWorkflow", "secret-a", "order-2", `{"name": "billing"}`, http.StatusCreated)
+		b := serve("/initiateWorkflow", "secret-b", "order-2", `{"name": "billing"}`, http.StatusCreated)
+		if runID(a) == runID(b) {
+			t.Error("Expected another API key's request with the same key to start its own run")
+		}
+		ongoing = append(ongoing, runID(a), runID(b))
+	})
+
+	t.Run("a key cannot be reused for another request", func(t *testing.T) {
+		ongoing = append(ongoing, runID(serve("/initiateWorkflow", "secret-a", "order-3", `{"name": "billing"}`, http.StatusCreated)))
+		w := serve("/initiateWorkflow", "secret-a", "order-3", `{"name": "billing", "priority": 5}`, http.StatusUnprocessableEntity)
+		if !strings.Contains(w.Body.String(), `"validation_failed"`) {
+			t.Errorf("Expected a validation_failed error, got %s", w.Body.String())
+		}
+
+		serve("/initiateWorkflow", "secret-a", strings.Repeat("k", 256), `{"name": "billing"}`, http.StatusBadRequest)
+	})
+
+	t.Run("requests in progress conflict", func(t *testing.T) {
+		body := `{"name": "billing"}`
+		app.idempotency.begin("billing-a\x00order-4", sha256.Sum256([]byte("POST /initiateWorkflow\n"+body)))
+		w := serve("/initiateWorkflow", "secret-a", "order-4", body, http.StatusConflict)
+		if !strings.Contains(w.Body.String(), `"conflict"`) {
+			t.Errorf("Expected a conflict error, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("server errors are not kept", func(t *testing.T) {
+		calls := 0
+		handler := app.idempotent(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			app.writeResponse(w, http.StatusOK, envelope{"calls": calls})
+		})
+
+		for _, expectedCode := range []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusOK} {
+			req := httptest.NewRequest(http.MethodPost, "/anything", strings.NewReader(`{}`))
+			req.Header.Set("Idempotency-Key", "flaky")
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != expectedCode {
+				t.Fatalf("Expected status %d, got %d", expectedCode, w.Code)
+			}
+		}
+		if calls != 2 {
+			t.Errorf("Expected the handler to be called twice, got %d", calls)
+		}
+	})
+
+	t.Run("keys expire", func(t *testing.T) {
+		s := newIdempotencyStore()
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		s.now = func() time.Time { return now }
+		fingerprint := sha256.Sum256([]byte("request"))
+
+		if _, created := s.begin("k", fingerprint); !created {
+			t.Fatal("Expected a new key to be created")
+		}
+		if _, created := s.begin("k", fingerprint); created {
+			t.Fatal("Expected a used key to be found")
+		}
+		now = now.Add(idempotencyTTL)
+		if _, created := s.begin("k", fingerprint); !created {
+			t.Error("Expected an expired key to be created again")
+		}
+		if len(s.entries) != 1 || len(s.order) != 1 {
+			t.Errorf("Expected expired keys to be dropped, got %d entries", len(s.entries))
+		}
+	})
+
+	t.Run("abandoned keys are forgotten", func(t *testing.T) {
+		s := newIdempotencyStore()
+		fingerprint := sha256.Sum256([]byte("request"))
+
+		for range 3 {
+			entry, created := s.begin("k", fingerprint)
+			if !created {
+				t.Fatal("Expected an abandoned key to be created again")
+			}
+			s.abandon("k", entry)
+		}
+		if len(s.entries) != 0 || len(s.order) != 0 {
+			t.Errorf("Expected abandoned keys to be dropped, got %d entries and %d keys", len(s.entries), len(s.order))
+		}
+
+		kept, _ := s.begin("k", fingerprint)
+		s.abandon("k", &idempotencyEntry{})
+		if entry, created := s.begin("k", fingerprint); created || entry != kept || len(s.order) != 1 {
+			t.Error("Expected abandoning a stale entry to keep the key")
+		}
+	})
+
+	for _, id := range ongoing {
+		if err := app.service.CancelWorkflow(t.Context(), id, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.wg.Wait()
+}
+
+func TestClient(t *testing.T) {
+	store, err := genie.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.NewKeys([]auth.Key{
+		{ID: "billing-service", Hash: auth.HashSecret("billing-secret"), Scopes: []auth.Scope{auth.ScopeRunsRead, auth.ScopeRunsAdvance, auth.InitiateScope("*")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := workflow.NewConfigStore(workflow.Workflows{
+		"billing": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			{"step2": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		},
+	}, nil)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	app := &application{
+		logger:      logger,
+		keys:        keys,
+		sessions:    auth.NewSessions(),
+		idempotency: newIdempotencyStore(),
+	}
+	app.service = service.NewWorkflowService(config, store, &app.wg, logger)
+
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, client.WithAPIKey("billing-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if status, err := c.Health(ctx); err != nil || status != "available" {
+		t.Fatalf("Expected flho to be available, got %q, %v", status, err)
+	}
+
+	runID, err := c.InitiateWorkflow(ctx, client.InitiateRequest{Name: "billing", Priority: 3, Labels: map[string]string{"tier": "gold"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retried, err := c.InitiateWorkflow(client.WithIdempotencyKey(ctx, "order-1"), client.InitiateRequest{Name: "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := c.InitiateWorkflow(client.WithIdempotencyKey(ctx, "order-1"), client.InitiateRequest{Name: "billing"}); err != nil || again != retried {
+		t.Errorf("Expected the retry to get run %s, got %s, %v", retried, again, err)
+	}
+	scheduled, err := c.InitiateWorkflow(ctx, client.InitiateRequest{Name: "billing", StartAfter: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.UpdateWorkflowRun(ctx, runID, map[string]string{"region": "eu"}); err != nil {
+		t.Fatal(err)
+	}
+	progress := 40.0
+	if err := c.Heartbeat(ctx, runID, client.HeartbeatRequest{Progress: &progress, Message: "charging"}); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := c.GetRun(ctx, runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != client.RunStatusOngoing || run.CurrentStep != 1 || run.Priority != 3 {
+		t.Errorf("Expected an ongoing run of priority 3 on step 1, got %+v", run)
+	}
+	if !maps.Equal(run.Labels, map[string]string{"tier": "gold", "region": "eu"}) {
+		t.Errorf("Expected the run's labels, got %v", run.Labels)
+	}
+	if run.LastHeartbeat == nil || run.LastHeartbeat.Message != "charging" {
+		t.Errorf("Expected the run's heartbeat, got %+v", run.LastHeartbeat)
+	}
+	if len(run.History) < 2 || run.History[len(run.History)-1].Actor != "billing-service" {
+		t.Errorf("Expected the run's history, got %+v", run.History)
+	}
+
+	workflows, err := c.Workflows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workflows) != 1 || workflows[0].Name != "billing" || len(workflows[0].Steps) != 3 {
+		t.Errorf("Expected the billing workflow, got %+v", workflows)
+	}
+
+	if s, err := c.GetRun(ctx, scheduled); err != nil || s.Status != client.RunStatusScheduled {
+		t.Errorf("Expected run %s to be scheduled, got %+v, %v", scheduled, s, err)
+	}
+
+	var exported []string
+	for run, err := range c.ExportRuns(ctx, client.RunsFilter{Labels: "tier=gold"}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		exported = append(exported, run.ID)
+	}
+	if !slices.Equal(exported, []string{runID}) {
+		t.Errorf("Expected run %s to be exported, got %v", runID, exported)
+	}
+
+	job, err := c.StartBulk(ctx, client.BulkRequest{Action: client.BulkCancel, Filter: &client.RunsFilter{Status: client.RunStatusOngoing}, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !job.DryRun || job.Total != 2 {
+		t.Errorf("Expected a dry run over the 2 ongoing runs, got %+v", job)
+	}
+
+	job, err = c.StartBulk(ctx, client.BulkRequest{Action: client.BulkCancel, RunIDs: []string{retried}, Reason: "duplicate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for job.State == client.BulkJobRunning {
+		time.Sleep(10 * time.Millisecond)
+		if job, err = c.BulkJob(ctx, job.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Succeeded != 1 {
+		t.Errorf("Expected the bulk cancel to succeed, got %+v", job)
+	}
+	if jobs, err := c.BulkJobs(ctx); err != nil || len(jobs) != 2 {
+		t.Errorf("Expected 2 bulk jobs, got %d, %v", len(jobs), err)
+	}
+
+	if err := c.CompleteWorkflowRun(ctx, runID); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CompleteWorkflowRun(ctx, runID); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("Expected completing a completed run to conflict, got %v", err)
+	}
+	if err := c.ResumeWorkflowRun(ctx, runID); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("Expected resuming a completed run to conflict, got %v", err)
+	}
+	if err := c.CancelWorkflowRun(ctx, scheduled, "not needed"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetRun(ctx, "missing")
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "not_found" || apiErr.Details["run_id"] != "missing" || apiErr.RequestID == "" {
+		t.Errorf("Expected a not_found error for the run, got %#v", err)
+	}
+	if _, err := c.InitiateWorkflow(ctx, client.InitiateRequest{Name: "missing"}); !errors.Is(err, client.ErrValidationFailed) {
+		t.Errorf("Expected initiating an unknown workflow to fail validation, got %v", err)
+	}
+
+	viewer, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := viewer.GetRun(ctx, runID); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("Expected a request without an API key to be unauthorized, got %v", err)
+	}
+
+	app.wg.Wait()
+}
+
+func TestOpenAPI(t *testing.T) {
+	app := &application{logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected the JSON document, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, got %q", spec.OpenAPI)
+	}
+
+	// every route must be documented, and every documented route must exist
+	source, err := os.ReadFile("routes.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := regexp.MustCompile(`HandleFunc\("([A-Z]+) ([^"]+)"`).FindAllStringSubmatch(string(source), -1)
+	if len(routes) == 0 {
+		t.Fatal("Expected to find the routes")
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		method, path := strings.ToLower(route[1]), route[2]
+		registered[method+" "+path] = true
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("%s %s is not in the OpenAPI document", route[1], path)
+		}
+	}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if !registered[method+" "+path] {
+				t.Errorf("%s %s is in the OpenAPI document but not a route", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
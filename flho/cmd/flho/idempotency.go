@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// idempotencyHeader carries the key a client sends with a POST request so
+// that the request is applied once however many times it is retried.
+const idempotencyHeader = "Idempotency-Key"
+
+const (
+	// idempotencyTTL is how long the response to a request is replayed to
+	// retries sent with the same key
+	idempotencyTTL = 24 * time.Hour
+	// maxIdempotencyKeys bounds the responses kept for replay, the oldest
+	// being dropped first
+	maxIdempotencyKeys = 10000
+	// maxIdempotencyKeyLength bounds the keys a client may choose
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyEntry is the request made with an idempotency key and, once it
+// has been answered, its response.
+type idempotencyEntry struct {
+	fingerprint [sha256.Size]byte // of the method, path and body of the request
+	expires     time.Time
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+// idempotencyStore keeps the responses to requests made with an idempotency
+// key, by API key and idempotency key. It is safe for concurrent use.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	order   []string // keys of entries, oldest first, so also by expiry
+	now     func() time.Time
+}
+
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+// begin returns the entry of the key, creating it for fingerprint if it does
+// not exist, and reports whether it was created.
+func (s *idempotencyStore) begin(key string, fingerprint [sha256.Size]byte) (*idempotencyEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// entries expire in the order they were made, and the oldest are also
+	// the first dropped when there are too many
+	now := s.now()
+	for len(s.order) > 0 {
+		oldest, ok := s.entries[s.order[0]]
+		if ok && now.Before(oldest.expires) && len(s.order) < maxIdempotencyKeys {
+			break
+		}
+		if ok {
+			delete(s.entries, s.order[0])
+		}
+		s.order = s.order[1:]
+	}
+
+	if entry, ok := s.entries[key]; ok {
+		return entry, false
+	}
+
+	entry := &idempotencyEntry{fingerprint: fingerprint, expires: now.Add(idempotencyTTL)}
+	s.entries[key] = entry
+	s.order = append(s.order, key)
+	return entry, true
+}
+
+// finish records the response to the request of the entry.
+func (s *idempotencyStore) finish(entry *idempotencyEntry, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.done = true
+	entry.status = status
+	entry.header = header
+	entry.body = body
+}
+
+// abandon forgets the entry of a request that failed, so that it can be
+// retried.
+func (s *idempotencyStore) abandon(key string, entry *idempotencyEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries[key] != entry {
+		return
+	}
+	delete(s.entries, key)
+
+	// the entry is most likely among the newest, and its key must not count
+	// towards maxIdempotencyKeys, nor drop an entry made later with the key
+	for i := len(s.order) - 1; i >= 0; i-- {
+		if s.order[i] == key {
+			s.order = slices.Delete(s.order, i, i+1)
+			break
+		}
+	}
+}
+
+// snapshot returns whether the request of the entry has been answered and,
+// if so, its response.
+func (s *idempotencyStore) snapshot(entry *idempotencyEntry) (bool, int, http.Header, []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return entry.done, entry.status, entry.header, entry.body
+}
+
+// idempotent applies a POST request sent with an Idempotency-Key once: a
+// retry with the same key and request gets the response to the first
+// attempt, marked with an Idempotent-Replayed header, for 24 hours. Keys are
+// kept per API key. Responses with a 5xx status are not kept, so that the
+// request can be retried.
+func (app *application) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" || app.idempotency == nil {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			app.errorResponse(w, r, http.StatusBadRequest, codeBadRequest, "Idempotency-Key must be at most 255 characters", nil)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			app.badJSON(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := sha256.Sum256([]byte(r.Method + " " + r.URL.Path + "\n" + string(body)))
+		if k := requestKey(r); k != nil {
+			key = k.ID + "\x00" + key
+		}
+
+		entry, created := app.idempotency.begin(key, fingerprint)
+		if !created {
+			done, status, header, body := app.idempotency.snapshot(entry)
+			switch {
+			case entry.fingerprint != fingerprint:
+				app.validationFailed(w, r, "Idempotency-Key was already used with a different request", nil)
+			case !done:
+				app.errorResponse(w, r, http.StatusConflict, codeConflict, "a request with this Idempotency-Key is in progress", nil)
+			default:
+				for name, values := range header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(status)
+				_, _ = w.Write(body)
+			}
+			return
+		}
+
+		capture := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+		next(capture, r)
+
+		if capture.status >= http.StatusInternalServerError {
+			app.idempotency.abandon(key, entry)
+			return
+		}
+		header := make(http.Header)
+		for _, name := range []string{"Content-Type", "Location"} {
+			if v := w.Header().Get(name); v != "" {
+				header.Set(name, v)
+			}
+		}
+		app.idempotency.finish(entry, capture.status, header, capture.body.Bytes())
+	}
+}
+
+// responseCapture passes a response on while keeping a copy of its status and
+// body.
+type responseCapture struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.status = status
+		c.wroteHeader = true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	c.wroteHeader = true
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultMaxBodyBytes caps request bodies when no -MAX_BODY_BYTES is given.
+const defaultMaxBodyBytes = 1 << 20
+
+// maxBodyBytes returns the largest request body the API reads.
+func (app *application) maxBodyBytes() int64 {
+	if app.config.maxBodyBytes > 0 {
+		return app.config.maxBodyBytes
+	}
+	return defaultMaxBodyBytes
+}
+
+// limitBodies caps the size of every request body, so that reading one larger
+// fails instead of exhausting memory.
+func (app *application) limitBodies(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, app.maxBodyBytes())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitClients rate limits requests by the client's IP address. It runs
+// before requests are authenticated, so that it also slows down the guessing
+// of API keys.
+func (app *application) limitClients(next http.Handler) http.Handler {
+	if app.clientLimits == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := app.clientLimits.Allow(clientIP(r)); !ok {
+			app.tooManyRequests(w, r, "client", wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitKeys rate limits requests by the API key they were authenticated
+// with, however many clients share the key. Requests without a key are only
+// limited by client.
+func (app *application) limitKeys(next http.Handler) http.Handler {
+	if app.keyLimits == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := requestKey(r); key != nil {
+			if ok, wait := app.keyLimits.Allow(key.ID); !ok {
+				app.tooManyRequests(w, r, "key", wait)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tooManyRequests refuses a rate limited request, telling the client how many
+// seconds to wait before retrying.
+func (app *application) tooManyRequests(w http.ResponseWriter, r *http.Request, limit string, wait time.Duration) {
+	if app.rateLimited != nil {
+		app.rateLimited.With(limit).Inc()
+	}
+
+	seconds := max(1, int(math.Ceil(wait.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	app.errorResponse(w, r, http.StatusTooManyRequests, codeRateLimited,
+		"rate limit exceeded, retry in "+strconv.Itoa(seconds)+"s", details{"limit": limit, "retry_after_seconds": seconds})
+}
+
+// clientIP returns the IP address the request came from. Forwarding headers
+// are not trusted, as any client can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
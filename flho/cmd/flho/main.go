@@ -3,12 +3,14 @@ package main
 import (
 	"context"
 	"flag"
-	"fmt"
 	"log"
 	"log/slog"
 	"os"
 	"time"
 
+	"github.com/windevkay/forge/flho/internal/auth"
+	"github.com/windevkay/forge/flho/internal/metrics"
+	"github.com/windevkay/forge/flho/internal/ratelimit"
 	"github.com/windevkay/forge/flho/internal/service"
 	"github.com/windevkay/forge/flho/internal/workflow"
 	"github.com/windevkay/forge/genie/v2"
@@ -17,44 +19,140 @@ import (
 func main() {
 	var cfg config
 	const defaultHTTPPort = 4000
+	const defaultGRPCPort = 4001
 	const defaultDataBackupInterval = 1
+	const defaultDeliveryAttempts = 3
+	const defaultDeliveryBackoff = time.Second
+	const defaultBreakerThreshold = 5
+	const defaultBreakerCooldown = 30 * time.Second
+	const defaultHostConcurrency = 10
+	const defaultRateBurst = 20
 
 	flag.IntVar(&cfg.port, "PORT", defaultHTTPPort, "HTTP server port")
+	flag.IntVar(&cfg.grpcPort, "GRPC_PORT", defaultGRPCPort, "gRPC server port, the gRPC API is off if 0")
 	flag.StringVar(&cfg.workflowConfig, "WORKFLOWS", "", "Path to workflow config YAML")
+	flag.IntVar(&cfg.deliveryAttempts, "DELIVERY_ATTEMPTS", defaultDeliveryAttempts, "Attempts per notification before it is dead-lettered")
+	flag.DurationVar(&cfg.deliveryBackoff, "DELIVERY_BACKOFF", defaultDeliveryBackoff, "Initial backoff between notification attempts")
+	flag.IntVar(&cfg.breakerThreshold, "BREAKER_THRESHOLD", defaultBreakerThreshold, "Consecutive failed notifications that open a host's circuit breaker")
+	flag.DurationVar(&cfg.breakerCooldown, "BREAKER_COOLDOWN", defaultBreakerCooldown, "How long an open circuit breaker waits before probing the host")
+	flag.IntVar(&cfg.hostConcurrency, "HOST_CONCURRENCY", defaultHostConcurrency, "Maximum in-flight notifications per target host")
+	flag.DurationVar(&cfg.retention.Completed, "RETAIN_COMPLETED", 0, "How long completed runs are kept, forever if 0")
+	flag.DurationVar(&cfg.retention.Failed, "RETAIN_FAILED", 0, "How long failed runs are kept, forever if 0")
+	flag.DurationVar(&cfg.retention.TimedOut, "RETAIN_TIMED_OUT", 0, "How long timed out runs are kept, forever if 0")
+	flag.DurationVar(&cfg.retention.Cancelled, "RETAIN_CANCELLED", 0, "How long cancelled runs are kept, forever if 0")
+	flag.StringVar(&cfg.archiveDir, "ARCHIVE_DIR", "", "Directory runs are archived to before they are purged")
+	flag.StringVar(&cfg.traceExporter, "TRACE_EXPORTER", traceExporterNone, "Where spans are exported: none, otlp, stdout or file")
+	flag.StringVar(&cfg.traceFile, "TRACE_FILE", "", "File spans are exported to by the file trace exporter")
+	flag.StringVar(&cfg.apiKeys, "API_KEYS", "", "Path to API keys YAML, every endpoint is open if not set")
+	flag.StringVar(&cfg.signingSecretFile, "SIGNING_SECRET_FILE", "", "Path to the secret outbound notifications are signed with, unsigned if not set")
+	flag.Float64Var(&cfg.rateLimit, "RATE_LIMIT", 0, "Requests per second allowed per client IP, unlimited if 0")
+	flag.IntVar(&cfg.rateBurst, "RATE_BURST", defaultRateBurst, "Requests a client IP may make at once")
+	flag.Float64Var(&cfg.keyRateLimit, "KEY_RATE_LIMIT", 0, "Requests per second allowed per API key, unlimited if 0")
+	flag.IntVar(&cfg.keyRateBurst, "KEY_RATE_BURST", defaultRateBurst, "Requests an API key may make at once")
+	flag.Int64Var(&cfg.maxBodyBytes, "MAX_BODY_BYTES", defaultMaxBodyBytes, "Largest request body accepted, in bytes")
 	flag.DurationVar(&cfg.dataBackupInterval, "DBINTRVL", time.Duration(defaultDataBackupInterval), "Data backup interval")
 	flag.Parse()
 
+	if err := cfg.retention.Validate(); err != nil {
+		log.Fatal("invalid retention", err.Error())
+	}
+
 	workflowConfigStore, err := workflow.NewConfigStoreFromFile(cfg.workflowConfig)
 	if err != nil {
 		log.Fatal("error loading workflow configurations", err.Error())
 	}
 
+	var keys *auth.Keys
+	if cfg.apiKeys != "" {
+		keys, err = auth.LoadKeys(cfg.apiKeys)
+		if err != nil {
+			log.Fatal("error loading API keys", err.Error())
+		}
+	}
+
+	var signingSecret []byte
+	if cfg.signingSecretFile != "" {
+		signingSecret, err = loadSigningSecret(cfg.signingSecretFile)
+		if err != nil {
+			log.Fatal("error loading signing secret", err.Error())
+		}
+	}
+
+	if cfg.maxBodyBytes <= 0 {
+		log.Fatal("invalid -MAX_BODY_BYTES", "must be positive")
+	}
+
+	var clientLimits, keyLimits *ratelimit.Limiter
+	if cfg.rateLimit != 0 {
+		clientLimits, err = ratelimit.New(cfg.rateLimit, cfg.rateBurst)
+		if err != nil {
+			log.Fatal("invalid client rate limit", err.Error())
+		}
+	}
+	if cfg.keyRateLimit != 0 {
+		keyLimits, err = ratelimit.New(cfg.keyRateLimit, cfg.keyRateBurst)
+		if err != nil {
+			log.Fatal("invalid API key rate limit", err.Error())
+		}
+	}
+
 	dataStore, err := genie.NewStore()
 	if err != nil {
 		log.Fatal("error setting up datastore", err.Error())
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	registry := metrics.NewRegistry()
+
+	tracerProvider, err := newTracerProvider(ctx, cfg)
+	if err != nil {
+		log.Fatal("error setting up tracing", err.Error())
+	}
 
 	app := application{
-		ctx:        ctx,
-		cancelFunc: cancel,
-		config:     cfg,
-		datastore:  dataStore,
-		logger:     slog.New(slog.NewJSONHandler(os.Stdout, nil)),
-		workflows:  workflowConfigStore,
+		ctx:          ctx,
+		cancelFunc:   cancel,
+		config:       cfg,
+		datastore:    dataStore,
+		keys:         keys,
+		sessions:     auth.NewSessions(),
+		idempotency:  newIdempotencyStore(),
+		clientLimits: clientLimits,
+		keyLimits:    keyLimits,
+		rateLimited:  registry.Counter("flho_http_rate_limited_total", "Requests refused by a rate limit, by limit.", "limit"),
+		logger:       slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		metrics:      registry,
+		backups:      registry.Counter("flho_store_backups_total", "Backups of the datastore, by result.", "result"),
+		workflows:    workflowConfigStore,
 	}
 
-	app.service = service.NewWorkflowService(app.workflows, app.datastore, &app.wg, app.logger)
+	opts := []service.Option{
+		service.WithDeliveryRetries(cfg.deliveryAttempts, cfg.deliveryBackoff),
+		service.WithCircuitBreaker(cfg.breakerThreshold, cfg.breakerCooldown),
+		service.WithHostConcurrency(cfg.hostConcurrency),
+		service.WithRetention(cfg.retention),
+		service.WithRunArchive(cfg.archiveDir),
+		service.WithMetrics(registry),
+		service.WithSigningSecret(signingSecret),
+	}
+	if tracerProvider != nil {
+		app.traces = tracerProvider
+		app.tracer = tracerProvider.Tracer("github.com/windevkay/forge/flho/cmd/flho")
+		opts = append(opts, service.WithTracerProvider(tracerProvider))
+	}
+
+	if keys == nil {
+		app.logger.Warn("no -API_KEYS given, every endpoint is open")
+	}
+	if signingSecret == nil {
+		app.logger.Warn("no -SIGNING_SECRET_FILE given, outbound notifications are not signed")
+	}
 
-	app.datastore.StartAutoBackup(app.config.dataBackupInterval * time.Minute)
+	app.service = service.NewWorkflowService(app.workflows, app.datastore, &app.wg, app.logger, opts...)
+	app.service.Start(app.ctx)
 
-	// monitor for errors in data backup
-	go func() {
-		for err := range app.datastore.AutoBackupErrors() {
-			app.logger.Warn(fmt.Sprintf("error backing up data: %s", err.Error()))
-		}
-	}()
+	app.wg.Add(1)
+	go app.backupData(app.config.dataBackupInterval * time.Minute)
 
 	err = app.serve()
 	if err != nil {
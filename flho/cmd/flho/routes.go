@@ -1,15 +1,48 @@
 package main
 
-import "net/http"
+import (
+	"net/http"
 
-func (app *application) routes() *http.ServeMux {
+	"github.com/windevkay/forge/flho/internal/auth"
+)
+
+func (app *application) routes() http.Handler {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/health", app.healthcheck)
-	mux.HandleFunc("/initiateWorkflow", app.initiateWorkflow)
-	mux.HandleFunc("/updateWorkflowRun", app.updateWorkflow)
-	mux.HandleFunc("/completeWorkflowRun", app.completeWorkflow)
-	mux.HandleFunc("/runs", app.listRuns)
+	mux.HandleFunc("GET /health", app.healthcheck)
+	mux.HandleFunc("GET /openapi.json", app.openAPI)
+	mux.HandleFunc("GET /login", app.showLogin)
+	mux.HandleFunc("POST /login", app.login)
+	mux.HandleFunc("POST /logout", app.logout)
+
+	// initiateWorkflow checks the scope of the workflow named in its body
+	mux.HandleFunc("POST /initiateWorkflow", app.idempotent(app.initiateWorkflow))
+	mux.HandleFunc("POST /updateWorkflowRun", app.require(auth.ScopeRunsAdvance, app.idempotent(app.updateWorkflow)))
+	mux.HandleFunc("POST /completeWorkflowRun", app.require(auth.ScopeRunsAdvance, app.idempotent(app.completeWorkflow)))
+	mux.HandleFunc("POST /cancelWorkflowRun", app.require(auth.ScopeRunsAdvance, app.idempotent(app.cancelWorkflow)))
+	mux.HandleFunc("POST /resumeWorkflowRun", app.require(auth.ScopeRunsAdvance, app.idempotent(app.resumeWorkflow)))
+	mux.HandleFunc("GET /runs", app.requirePage(auth.ScopeRunsRead, app.listRuns))
+	mux.HandleFunc("GET /runs/{id}", app.requirePage(auth.ScopeRunsRead, app.showRun))
+	mux.HandleFunc("GET /api/runs/export", app.require(auth.ScopeRunsRead, app.exportRuns))
+	mux.HandleFunc("GET /api/runs/{id}", app.require(auth.ScopeRunsRead, app.getRun))
+	mux.HandleFunc("POST /api/runs/bulk", app.require(auth.ScopeRunsAdvance, app.idempotent(app.bulkRuns)))
+	mux.HandleFunc("GET /api/runs/bulk", app.require(auth.ScopeRunsRead, app.listBulkJobs))
+	mux.HandleFunc("GET /api/runs/bulk/{id}", app.require(auth.ScopeRunsRead, app.showBulkJob))
+	mux.HandleFunc("GET /api/workflows", app.require(auth.ScopeRunsRead, app.listWorkflows))
+	mux.HandleFunc("POST /runs/{id}/heartbeat", app.require(auth.ScopeRunsAdvance, app.idempotent(app.heartbeat)))
+	mux.HandleFunc("GET /schedules", app.requirePage(auth.ScopeRunsRead, app.listSchedules))
+	mux.HandleFunc("GET /deadletters", app.requirePage(auth.ScopeAdmin, app.listDeadLetters))
+	mux.HandleFunc("POST /deadletters/replay", app.requirePage(auth.ScopeAdmin, app.replayDeadLetters))
+	mux.HandleFunc("POST /deadletters/{id}/replay", app.requirePage(auth.ScopeAdmin, app.replayDeadLetters))
+	mux.HandleFunc("POST /deadletters/purge", app.requirePage(auth.ScopeAdmin, app.purgeDeadLetters))
+	mux.HandleFunc("POST /deadletters/{id}/purge", app.requirePage(auth.ScopeAdmin, app.purgeDeadLetters))
+	mux.HandleFunc("GET /admin/breakers", app.requirePage(auth.ScopeAdmin, app.listBreakers))
+
+	if app.metrics != nil {
+		mux.HandleFunc("GET /metrics", app.require(auth.ScopeMetricsRead, app.metrics.ServeHTTP))
+	}
 
-	return mux
+	// requests are rate limited by client before authentication and by API key
+	// after it; requests matching no route get the error envelope too
+	return app.assignRequestIDs(app.traceRequests(app.limitClients(app.limitBodies(app.authenticate(app.limitKeys(app.routeErrors(mux)))))))
 }
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 func (app *application) serve() error {
@@ -23,6 +27,23 @@ func (app *application) serve() error {
 		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
 	}
 
+	// the gRPC API listens on its own port, next to the HTTP server
+	var grpcSrv *grpc.Server
+	if app.config.grpcPort != 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", app.config.grpcPort))
+		if err != nil {
+			return err
+		}
+		grpcSrv = app.newGRPCServer()
+
+		go func() {
+			app.logger.Info("starting gRPC server", "addr", lis.Addr().String())
+			if err := grpcSrv.Serve(lis); err != nil {
+				app.logger.Error("gRPC server stopped", "error", err.Error())
+			}
+		}()
+	}
+
 	shutdownError := make(chan error)
 
 	go func() {
@@ -40,9 +61,21 @@ func (app *application) serve() error {
 
 		app.logger.Info("...finishing background tasks", "addr", srv.Addr)
 		app.cancelFunc()
-		app.datastore.StopAutoBackup()
+
+		// run watches end with the service, so the gRPC calls in progress
+		// finish too
+		if grpcSrv != nil {
+			grpcSrv.GracefulStop()
+		}
 		app.wg.Wait()
 
+		// flush the spans of the runs and requests that just finished
+		if app.traces != nil {
+			if err := app.traces.Shutdown(context.Background()); err != nil {
+				app.logger.Warn("error flushing traces", "error", err.Error())
+			}
+		}
+
 		shutdownError <- nil
 	}()
 
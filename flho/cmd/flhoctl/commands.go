@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/windevkay/forge/flho/client"
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+// labelsFlag collects repeated key=value flags.
+type labelsFlag map[string]string
+
+func (l labelsFlag) String() string {
+	return formatLabels(l)
+}
+
+func (l labelsFlag) Set(v string) error {
+	key, value, ok := strings.Cut(v, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("label %q must be key=value", v)
+	}
+	l[key] = value
+	return nil
+}
+
+// startInput is the input file of the start command: the body of
+// POST /initiateWorkflow, less the workflow name.
+type startInput struct {
+	StartAt    *time.Time        `json:"start_at"`
+	StartAfter string            `json:"start_after"`
+	Priority   int               `json:"priority"`
+	Labels     map[string]string `json:"labels"`
+}
+
+// readStartInput reads the input file at path, or standard input if path is
+// "-".
+func readStartInput(path string, stdin io.Reader) (startInput, error) {
+	var input startInput
+
+	r := stdin
+	if path != "-" {
+		f, err := os.Open(path) // #nosec G304 - a file the user names
+		if err != nil {
+			return input, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&input); err != nil {
+		return input, fmt.Errorf("reading input %s: %w", path, err)
+	}
+	return input, nil
+}
+
+func startCommand(fs *flag.FlagSet) action {
+	input := fs.String("input", "", "JSON `file` with the run's start_at, start_after, priority and labels, - for standard input")
+	startAfter := fs.Duration("start-after", 0, "start the run after this delay")
+	priority := fs.Int("priority", 0, "priority of the run")
+	labels := labelsFlag{}
+	fs.Var(labels, "label", "`key=value` label of the run, may be repeated")
+
+	return func(ctx context.Context, e *env, args []string) error {
+		req := client.InitiateRequest{Name: args[0]}
+
+		if *input != "" {
+			in, err := readStartInput(*input, e.stdin)
+			if err != nil {
+				return err
+			}
+			if in.StartAt != nil {
+				req.StartAt = *in.StartAt
+			}
+			if in.StartAfter != "" {
+				if req.StartAfter, err = time.ParseDuration(in.StartAfter); err != nil {
+					return fmt.Errorf("reading input %s: invalid start_after: %w", *input, err)
+				}
+			}
+			req.Priority = in.Priority
+			req.Labels = in.Labels
+		}
+
+		// flags override the input file
+		if *startAfter != 0 {
+			req.StartAfter = *startAfter
+		}
+		if *priority != 0 {
+			req.Priority = *priority
+		}
+		if len(labels) > 0 {
+			if req.Labels == nil {
+				req.Labels = map[string]string{}
+			}
+			maps.Copy(req.Labels, labels)
+		}
+
+		runID, err := e.client.InitiateWorkflow(ctx, req)
+		if err != nil {
+			return err
+		}
+		return e.out.print(map[string]string{"run_id": runID}, func(w io.Writer) {
+			fmt.Fprintln(w, runID)
+		})
+	}
+}
+
+// actionResult is what the commands acting on a run print.
+type actionResult struct {
+	RunID  string `json:"run_id"`
+	Action string `json:"action"`
+}
+
+// runAction returns the action of a command acting on a run with do, named
+// by past, such as "advanced".
+func runAction(name, past string, do func(ctx context.Context, e *env, runID string) error) action {
+	return func(ctx context.Context, e *env, args []string) error {
+		if err := do(ctx, e, args[0]); err != nil {
+			return err
+		}
+		return e.out.print(actionResult{RunID: args[0], Action: name}, func(w io.Writer) {
+			fmt.Fprintf(w, "run %s %s\n", args[0], past)
+		})
+	}
+}
+
+func advanceCommand(fs *flag.FlagSet) action {
+	labels := labelsFlag{}
+	fs.Var(labels, "label", "`key=value` label to set on the run, may be repeated, an empty value removes it")
+
+	return runAction("advance", "advanced", func(ctx context.Context, e *env, runID string) error {
+		return e.client.UpdateWorkflowRun(ctx, runID, labels)
+	})
+}
+
+func completeCommand(*flag.FlagSet) action {
+	return runAction("complete", "completed", func(ctx context.Context, e *env, runID string) error {
+		return e.client.CompleteWorkflowRun(ctx, runID)
+	})
+}
+
+func cancelCommand(fs *flag.FlagSet) action {
+	reason := fs.String("reason", "", "why the run is cancelled, kept in its history")
+
+	return runAction("cancel", "cancelled", func(ctx context.Context, e *env, runID string) error {
+		return e.client.CancelWorkflowRun(ctx, runID, *reason)
+	})
+}
+
+func resumeCommand(*flag.FlagSet) action {
+	return runAction("resume", "resumed", func(ctx context.Context, e *env, runID string) error {
+		return e.client.ResumeWorkflowRun(ctx, runID)
+	})
+}
+
+// defaultListLimit is how many runs runs list shows by default.
+const defaultListLimit = 50
+
+func runsListCommand(fs *flag.FlagSet) action {
+	var filter client.RunsFilter
+	fs.Func("status", "only runs of this `status`: scheduled, queued, ongoing, completed, failed, timed_out or cancelled", func(v string) error {
+		filter.Status = client.RunStatus(v)
+		return nil
+	})
+	fs.StringVar(&filter.Workflow, "workflow", "", "only runs of workflows whose name contains this")
+	fs.StringVar(&filter.Namespace, "namespace", "", "only runs of this namespace")
+	fs.StringVar(&filter.Labels, "labels", "", "only runs matching this label `selector`, such as customer_id=42,!trial")
+	fs.StringVar(&filter.Sort, "sort", "", "sort by start_time, end_time, duration or priority")
+	fs.StringVar(&filter.Order, "order", "", "asc or desc")
+	limit := fs.Int("limit", defaultListLimit, "show at most this many runs, all of them if 0")
+
+	return func(ctx context.Context, e *env, _ []string) error {
+		runs := []client.Run{}
+		for run, err := range e.client.ExportRuns(ctx, filter) {
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			if len(runs) == *limit {
+				break
+			}
+		}
+
+		return e.out.print(runs, func(w io.Writer) {
+			fmt.Fprintln(w, "ID\tWORKFLOW\tNAMESPACE\tSTATUS\tSTEP\tPRIORITY\tSTARTED\tDURATION\tLABELS")
+			for _, r := range runs {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n", r.ID, r.WorkflowName, r.Namespace, r.Status,
+					r.CurrentStep, r.Priority, formatTime(r.StartTime), formatDuration(r.Duration), formatLabels(r.Labels))
+			}
+		})
+	}
+}
+
+func runsGetCommand(*flag.FlagSet) action {
+	return func(ctx context.Context, e *env, args []string) error {
+		run, err := e.client.GetRun(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return e.out.print(run, func(w io.Writer) { runTable(w, run) })
+	}
+}
+
+// runTable writes the details of a run, then its history.
+func runTable(w io.Writer, r *client.Run) {
+	fmt.Fprintf(w, "ID:\t%s\n", r.ID)
+	fmt.Fprintf(w, "Workflow:\t%s\n", r.WorkflowName)
+	fmt.Fprintf(w, "Namespace:\t%s\n", r.Namespace)
+	fmt.Fprintf(w, "Status:\t%s\n", r.Status)
+	fmt.Fprintf(w, "Step:\t%d\n", r.CurrentStep)
+	fmt.Fprintf(w, "Priority:\t%d\n", r.Priority)
+	if r.ScheduledFor != nil {
+		fmt.Fprintf(w, "Scheduled for:\t%s\n", formatTime(r.ScheduledFor))
+	}
+	fmt.Fprintf(w, "Started:\t%s\n", formatTime(r.StartTime))
+	fmt.Fprintf(w, "Ended:\t%s\n", formatTime(r.EndTime))
+	fmt.Fprintf(w, "Duration:\t%s\n", formatDuration(r.Duration))
+	fmt.Fprintf(w, "Labels:\t%s\n", formatLabels(r.Labels))
+	if r.ParentRunID != "" {
+		fmt.Fprintf(w, "Parent run:\t%s\n", r.ParentRunID)
+	}
+	if len(r.ChildRunIDs) > 0 {
+		fmt.Fprintf(w, "Child runs:\t%s\n", strings.Join(r.ChildRunIDs, ", "))
+	}
+	if hb := r.LastHeartbeat; hb != nil {
+		progress := ""
+		if hb.Progress != nil {
+			progress = fmt.Sprintf("%.0f%% ", *hb.Progress)
+		}
+		fmt.Fprintf(w, "Heartbeat:\t%s%s (step %d, %s)\n", progress, hb.Message, hb.Step, formatTime(&hb.At))
+	}
+	if c := r.Compensation; c != nil {
+		fmt.Fprintf(w, "Compensation:\t%s, %d of %d steps", c.Phase, c.Completed, len(c.Steps))
+		if c.Error != "" {
+			fmt.Fprintf(w, ": %s", c.Error)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(r.History) > 0 {
+		fmt.Fprintln(w, "\nTIME\tEVENT\tSTEP\tACTOR\tDETAIL")
+		for _, ev := range r.History {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", formatTime(&ev.Time), ev.Type, ev.Step, orDash(ev.Actor), orDash(ev.Detail))
+		}
+	}
+}
+
+// defaultWatchInterval is how often runs watch polls the run by default.
+const defaultWatchInterval = 2 * time.Second
+
+func runsWatchCommand(fs *flag.FlagSet) action {
+	interval := fs.Duration("interval", defaultWatchInterval, "how often to poll the run")
+
+	return func(ctx context.Context, e *env, args []string) error {
+		if *interval <= 0 {
+			return errors.New("-interval must be positive")
+		}
+
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+
+		var last []byte
+		for {
+			run, err := e.client.GetRun(ctx, args[0])
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+
+			// only changes are shown
+			if state, _ := json.Marshal(run); string(state) != string(last) {
+				last = state
+				if err := e.out.printNext(run, func(w io.Writer) { watchLine(w, run) }); err != nil {
+					return err
+				}
+			}
+			if watchEnded(run) {
+				return nil
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// watchLine writes the state of a watched run as one line, with its latest
+// event.
+func watchLine(w io.Writer, r *client.Run) {
+	line := fmt.Sprintf("%s  %-10s step %d", time.Now().Format(time.DateTime), r.Status, r.CurrentStep)
+	if len(r.History) > 0 {
+		ev := r.History[len(r.History)-1]
+		line += "  " + ev.Type
+		if ev.Detail != "" {
+			line += ": " + ev.Detail
+		}
+	}
+	if hb := r.LastHeartbeat; hb != nil && hb.Step == r.CurrentStep {
+		if hb.Progress != nil {
+			line += fmt.Sprintf("  %.0f%%", *hb.Progress)
+		}
+		if hb.Message != "" {
+			line += "  " + hb.Message
+		}
+	}
+	fmt.Fprintln(w, line)
+}
+
+// watchEnded reports whether the run will not change again, short of being
+// resumed.
+func watchEnded(r *client.Run) bool {
+	switch r.Status {
+	case client.RunStatusCompleted, client.RunStatusFailed, client.RunStatusTimedOut, client.RunStatusCancelled:
+		return r.Compensation == nil || r.Compensation.Phase != "compensating"
+	}
+	return false
+}
+
+func workflowsListCommand(*flag.FlagSet) action {
+	return func(ctx context.Context, e *env, _ []string) error {
+		workflows, err := e.client.Workflows(ctx)
+		if err != nil {
+			return err
+		}
+		return e.out.print(workflows, func(w io.Writer) { workflowsTable(w, workflows) })
+	}
+}
+
+func workflowsValidateCommand(*flag.FlagSet) action {
+	return func(_ context.Context, e *env, args []string) error {
+		store, err := workflow.NewConfigStoreFromFile(args[0])
+		if err != nil {
+			return fmt.Errorf("%s is not valid: %w", args[0], err)
+		}
+
+		workflows := []client.Workflow{}
+		for _, name := range slices.Sorted(maps.Keys(store.GetWorkflows())) {
+			settings := store.GetSettings(name)
+			wf := client.Workflow{
+				Name:              name,
+				Namespace:         store.NamespaceOf(name),
+				Steps:             []string{},
+				Schedule:          settings.Schedule,
+				MaxConcurrentRuns: settings.MaxConcurrentRuns,
+			}
+			for _, step := range store.GetWorkflows()[name] {
+				for key := range step {
+					wf.Steps = append(wf.Steps, key)
+				}
+			}
+			workflows = append(workflows, wf)
+		}
+
+		return e.out.print(workflows, func(w io.Writer) {
+			fmt.Fprintf(w, "%s is valid\n\n", args[0])
+			workflowsTable(w, workflows)
+		})
+	}
+}
+
+func workflowsTable(w io.Writer, workflows []client.Workflow) {
+	fmt.Fprintln(w, "NAME\tNAMESPACE\tSTEPS\tSCHEDULE\tMAX RUNS")
+	for _, wf := range workflows {
+		maxRuns := "-"
+		if wf.MaxConcurrentRuns > 0 {
+			maxRuns = fmt.Sprint(wf.MaxConcurrentRuns)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", wf.Name, wf.Namespace, strings.Join(wf.Steps, ","), orDash(wf.Schedule), maxRuns)
+	}
+}
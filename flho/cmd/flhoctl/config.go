@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/windevkay/forge/flho/client"
+)
+
+// defaultAddress is where flho is found when nothing says otherwise.
+const defaultAddress = "http://localhost:4000"
+
+// settings say where flho is and how to authenticate with it. A config file
+// holds them as YAML:
+//
+//	address: https://flho.example.com
+//	api_key: <secret>
+type settings struct {
+	Address string `yaml:"address"`
+	APIKey  string `yaml:"api_key"`
+}
+
+// loadSettings reads the settings of the config file at path, or at
+// $FLHO_CONFIG or the default path if path is empty, and overrides them with
+// $FLHO_ADDR and $FLHO_API_KEY. A missing file at the default path is not
+// an error.
+func loadSettings(path string, getenv func(string) string) (settings, error) {
+	s := settings{Address: defaultAddress}
+
+	explicit := true
+	if path == "" {
+		path = getenv("FLHO_CONFIG")
+	}
+	if path == "" {
+		explicit = false
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			path = filepath.Join(dir, "flho", "config.yaml")
+		}
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path) // #nosec G304 - the user's own config file
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		case err != nil:
+			return s, fmt.Errorf("reading config: %w", err)
+		default:
+			if err := yaml.Unmarshal(data, &s); err != nil {
+				return s, fmt.Errorf("reading config %s: %w", path, err)
+			}
+		}
+	}
+
+	if addr := getenv("FLHO_ADDR"); addr != "" {
+		s.Address = addr
+	}
+	if key := getenv("FLHO_API_KEY"); key != "" {
+		s.APIKey = key
+	}
+	return s, nil
+}
+
+// env is what commands run with.
+type env struct {
+	client *client.Client
+	out    *printer
+	stdin  io.Reader
+}
+
+func newEnv(g globals, getenv func(string) string, stdin io.Reader, stdout io.Writer) (*env, error) {
+	out, err := newPrinter(g.output, stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := loadSettings(g.config, getenv)
+	if err != nil {
+		return nil, err
+	}
+	if g.addr != "" {
+		s.Address = g.addr
+	}
+
+	c, err := client.New(s.Address, client.WithAPIKey(s.APIKey))
+	if err != nil {
+		return nil, err
+	}
+	return &env{client: c, out: out, stdin: stdin}, nil
+}
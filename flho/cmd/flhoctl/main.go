@@ -0,0 +1,182 @@
+// Command flhoctl is a command-line client of flho's HTTP API.
+//
+// Usage:
+//
+//	flhoctl [flags] <command> [arguments] [flags]
+//
+// It finds flho at the address and API key of its config file, overridden by
+// the FLHO_ADDR and FLHO_API_KEY environment variables and the -addr flag.
+// Run flhoctl -h for the commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Getenv, os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// Exit codes of flhoctl.
+const (
+	exitOK    = 0
+	exitError = 1 // the command failed
+	exitUsage = 2 // the command line is invalid
+)
+
+// globals are the flags every command takes.
+type globals struct {
+	config string // config file, see loadSettings
+	addr   string // overrides the address of the config file and FLHO_ADDR
+	output string // table, json or yaml
+}
+
+// register adds the flags to fs, defaulting to their current values so that
+// flags given before the command carry over to it.
+func (g *globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.config, "config", g.config, "config `file`, $FLHO_CONFIG or ~/.config/flho/config.yaml by default")
+	fs.StringVar(&g.addr, "addr", g.addr, "flho `URL`, such as http://localhost:4000")
+	fs.StringVar(&g.output, "o", g.output, "output `format`: table, json or yaml")
+}
+
+// command is a command of flhoctl, such as "runs list".
+type command struct {
+	name    string
+	args    []string // names of the positional arguments
+	summary string
+	// setup registers the command's flags and returns what runs it.
+	setup func(fs *flag.FlagSet) action
+}
+
+// action runs a command with its positional arguments.
+type action func(ctx context.Context, e *env, args []string) error
+
+// commands are the commands of flhoctl, in the order of the usage.
+var commands = []command{
+	{"start", []string{"<workflow>"}, "Start a run of a workflow", startCommand},
+	{"advance", []string{"<run-id>"}, "Advance a run to its next step", advanceCommand},
+	{"complete", []string{"<run-id>"}, "Complete a run", completeCommand},
+	{"cancel", []string{"<run-id>"}, "Cancel a run", cancelCommand},
+	{"resume", []string{"<run-id>"}, "Resume a failed or timed out run", resumeCommand},
+	{"runs list", nil, "List runs, newest first", runsListCommand},
+	{"runs get", []string{"<run-id>"}, "Show a run with its history", runsGetCommand},
+	{"runs watch", []string{"<run-id>"}, "Show a run every time it changes, until it finishes", runsWatchCommand},
+	{"workflows list", nil, "List the workflows of flho", workflowsListCommand},
+	{"workflows validate", []string{"<file>"}, "Check a workflow configuration file", workflowsValidateCommand},
+}
+
+// run runs flhoctl with the command-line arguments args and returns its
+// exit code.
+func run(ctx context.Context, args []string, getenv func(string) string, stdin io.Reader, stdout, stderr io.Writer) int {
+	g := globals{output: formatTable}
+
+	fs := flag.NewFlagSet("flhoctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	g.register(fs)
+	fs.Usage = func() { usage(stderr, fs) }
+	if err := fs.Parse(args); err != nil {
+		return exitCode(err)
+	}
+
+	cmd, args, ok := findCommand(fs.Args())
+	if !ok {
+		if fs.NArg() > 0 {
+			fmt.Fprintf(stderr, "flhoctl: unknown command %q\n", strings.Join(fs.Args(), " "))
+		}
+		fs.Usage()
+		return exitUsage
+	}
+
+	cmdFlags := flag.NewFlagSet("flhoctl "+cmd.name, flag.ContinueOnError)
+	cmdFlags.SetOutput(stderr)
+	g.register(cmdFlags)
+	act := cmd.setup(cmdFlags)
+	cmdFlags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: flhoctl %s\n\n%s.\n\nFlags:\n", strings.Join(append([]string{cmd.name}, cmd.args...), " "), cmd.summary)
+		cmdFlags.PrintDefaults()
+	}
+
+	args, err := parseFlags(cmdFlags, args)
+	if err != nil {
+		return exitCode(err)
+	}
+	if len(args) != len(cmd.args) {
+		fmt.Fprintf(stderr, "flhoctl: %s takes %d argument(s), got %d\n", cmd.name, len(cmd.args), len(args))
+		cmdFlags.Usage()
+		return exitUsage
+	}
+
+	e, err := newEnv(g, getenv, stdin, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "flhoctl: %v\n", err)
+		return exitError
+	}
+	if err := act(ctx, e, args); err != nil {
+		fmt.Fprintf(stderr, "flhoctl: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// findCommand returns the command named by the start of args, and the
+// arguments following its name.
+func findCommand(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+// parseFlags parses the flags of args, which may come before, between or
+// after the positional arguments, and returns the latter.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// exitCode returns the exit code of a failed flag parse, which has printed
+// the usage.
+func exitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprint(w, "Usage: flhoctl [flags] <command> [arguments] [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-32s %s\n", strings.Join(append([]string{cmd.name}, cmd.args...), " "), cmd.summary)
+	}
+	fmt.Fprint(w, "\nRun flhoctl <command> -h for the flags of a command.\n\nFlags:\n")
+	fs.PrintDefaults()
+	fmt.Fprint(w, `
+Environment:
+  FLHO_CONFIG   config file
+  FLHO_ADDR     flho URL, overriding the config file
+  FLHO_API_KEY  API key secret, overriding the config file
+`)
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// request is a request the fake flho received.
+type request struct {
+	method, path, query, auth string
+	body                      map[string]any
+}
+
+// fakeFlho serves handler, records the requests it receives and writes a
+// config file pointing at it. It returns the environment to run flhoctl with.
+func fakeFlho(t *testing.T, handler http.HandlerFunc) (map[string]string, func() []request) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, auth: r.Header.Get("Authorization")}
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			if err := json.Unmarshal(b, &req.body); err != nil {
+				t.Errorf("Expected a JSON body, got %s", b)
+			}
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, []byte("address: "+srv.URL+"\napi_key: file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]string{"FLHO_CONFIG": config}, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]request(nil), requests...)
+	}
+}
+
+// runCtl runs flhoctl with the environment, standard input and arguments.
+func runCtl(t *testing.T, environ map[string]string, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	getenv := func(key string) string { return environ[key] }
+	code := run(context.Background(), args, getenv, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+const testRun = `{"id": "run-1", "workflow_name": "billing", "namespace": "default", "status": "ongoing",
+	"current_step": 1, "priority": 2, "labels": {"tier": "gold"},
+	"history": [{"time": "2024-01-01T00:00:00Z", "type": "created", "step": 0, "actor": "ci"}]}`
+
+func TestStart(t *testing.T) {
+	environ, requests := fakeFlho(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"run_id": "run-1"}`)
+	})
+
+	input := filepath.Join(t.TempDir(), "input.json")
+	if err := os.WriteFile(input, []byte(`{"start_after": "90m", "priority": 2, "labels": {"tier": "gold"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCtl(t, environ, "", "start", "billing", "--input", input, "-label", "region=eu", "-priority", "5")
+	if code != exitOK || stdout != "run-1\n" {
+		t.Fatalf("Expected the run ID, got %d %q %q", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCtl(t, environ, `{"labels": {"tier": "silver"}}`, "-o", "json", "start", "billing", "-input", "-")
+	if code != exitOK || strings.TrimSpace(stdout) != "{\n  \"run_id\": \"run-1\"\n}" {
+		t.Errorf("Expected the run ID as JSON, got %d %q", code, stdout)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(got))
+	}
+	expected := map[string]any{"name": "billing", "start_after": "1h30m0s", "priority": 5.0, "labels": map[string]any{"tier": "gold", "region": "eu"}}
+	if got[0].path != "/initiateWorkflow" || !equalJSON(got[0].body, expected) {
+		t.Errorf("Expected the flags to override the input, got %s %v", got[0].path, got[0].body)
+	}
+	if got[0].auth != "Bearer file-secret" {
+		t.Errorf("Expected the API key of the config file, got %q", got[0].auth)
+	}
+	if !equalJSON(got[1].body, map[string]any{"name": "billing", "labels": map[string]any{"tier": "silver"}}) {
+		t.Errorf("Expected the input of standard input, got %v", got[1].body)
+	}
+
+	code, _, stderr = runCtl(t, environ, `{"name": "billing"}`, "start", "billing", "-input", "-")
+	if code != exitError || !strings.Contains(stderr, `unknown field "name"`) {
+		t.Errorf("Expected unknown input fields to be rejected, got %d %q", code, stderr)
+	}
+}
+
+func TestRunActions(t *testing.T) {
+	environ, requests := fakeFlho(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/resumeWorkflowRun" {
+			w.WriteHeader(http.StatusConflict)
+			io.WriteString(w, `{"error": {"code": "conflict", "message": "run run-1 is not failed or timed out", "request_id": "req-1"}}`)
+			return
+		}
+		io.WriteString(w, `{"success": "ok"}`)
+	})
+
+	tests := []struct {
+		args   []string
+		path   string
+		body   map[string]any
+		stdout string
+	}{
+		{[]string{"advance", "run-1", "-label", "stage=billed"}, "/updateWorkflowRun", map[string]any{"run_id": "run-1", "labels": map[string]any{"stage": "billed"}}, "run run-1 advanced\n"},
+		{[]string{"complete", "run-1"}, "/completeWorkflowRun", map[string]any{"run_id": "run-1"}, "run run-1 completed\n"},
+		{[]string{"cancel", "run-1", "-reason", "duplicate"}, "/cancelWorkflowRun", map[string]any{"run_id": "run-1", "reason": "duplicate"}, "run run-1 cancelled\n"},
+		{[]string{"-o", "yaml", "complete", "run-1"}, "/completeWorkflowRun", map[string]any{"run_id": "run-1"}, "run_id: run-1\naction: complete\n"},
+	}
+
+	for i, tt := range tests {
+		code, stdout, stderr := runCtl(t, environ, "", tt.args...)
+		if code != exitOK || stdout != tt.stdout {
+			t.Errorf("%v: expected %q, got %d %q %q", tt.args, tt.stdout, code, stdout, stderr)
+		}
+		if got := requests()[i]; got.path != tt.path || !equalJSON(got.body, tt.body) {
+			t.Errorf("%v: expected %s %v, got %s %v", tt.args, tt.path, tt.body, got.path, got.body)
+		}
+	}
+
+	code, _, stderr := runCtl(t, environ, "", "resume", "run-1")
+	if code != exitError || !strings.Contains(stderr, "409 conflict: run run-1 is not failed or timed out (request req-1)") {
+		t.Errorf("Expected the error of flho, got %d %q", code, stderr)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	var watched atomic.Int32
+	environ, requests := fakeFlho(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/runs/export":
+			for _, id := range []string{"run-1", "run-2", "run-3"} {
+				io.WriteString(w, `{"id": "`+id+`", "workflow_name": "billing", "namespace": "default", "status": "failed", "current_step": 0, "priority": 0, "duration": 1500000000}`+"\n")
+			}
+		case "/api/runs/run-1":
+			io.WriteString(w, `{"run": `+testRun+`}`)
+		case "/api/runs/run-2":
+			// the run changes on the third poll and finishes on the fifth
+			switch n := watched.Add(1); {
+			case n < 3:
+				io.WriteString(w, `{"run": {"id": "run-2", "status": "ongoing", "current_step": 0}}`)
+			case n < 5:
+				io.WriteString(w, `{"run": {"id": "run-2", "status": "ongoing", "current_step": 1}}`)
+			default:
+				io.WriteString(w, `{"run": {"id": "run-2", "status": "completed", "current_step": 1}}`)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error": {"code": "not_found", "message": "not found"}}`)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		code, stdout, stderr := runCtl(t, environ, "", "runs", "list", "--status", "failed", "--workflow", "bill", "-limit", "2")
+		if code != exitOK {
+			t.Fatalf("Expected the runs, got %d %q", code, stderr)
+		}
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") || !strings.HasPrefix(lines[2], "run-2") || !strings.Contains(lines[1], "1.5s") {
+			t.Errorf("Expected a table of 2 runs, got\n%s", stdout)
+		}
+
+		got := requests()
+		if query := got[len(got)-1].query; !strings.Contains(query, "status=failed") || !strings.Contains(query, "workflow=bill") {
+			t.Errorf("Expected the filter to be sent, got %q", query)
+		}
+
+		code, stdout, _ = runCtl(t, environ, "", "runs", "list", "-o", "yaml", "-limit", "0")
+		var runs []map[string]any
+		if err := yaml.Unmarshal([]byte(stdout), &runs); code != exitOK || err != nil || len(runs) != 3 || runs[0]["workflow_name"] != "billing" {
+			t.Errorf("Expected every run as YAML, got %d %v\n%s", code, err, stdout)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		code, stdout, stderr := runCtl(t, environ, "", "runs", "get", "run-1")
+		if code != exitOK {
+			t.Fatalf("Expected the run, got %d %q", code, stderr)
+		}
+		for _, want := range []string{"Workflow:", "billing", "Labels:", "tier=gold", "EVENT", "created", "ci"} {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("Expected the run's details to contain %q, got\n%s", want, stdout)
+			}
+		}
+
+		code, stdout, _ = runCtl(t, environ, "", "runs", "get", "run-1", "-o", "json")
+		var run map[string]any
+		if err := json.Unmarshal([]byte(stdout), &run); code != exitOK || err != nil || run["id"] != "run-1" || run["history"] == nil {
+			t.Errorf("Expected the run as JSON, got %d %v\n%s", code, err, stdout)
+		}
+
+		code, _, stderr = runCtl(t, environ, "", "runs", "get", "missing")
+		if code != exitError || !strings.Contains(stderr, "404 not_found") {
+			t.Errorf("Expected the run not to be found, got %d %q", code, stderr)
+		}
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		code, stdout, stderr := runCtl(t, environ, "", "runs", "watch", "run-2", "-interval", "1ms", "-o", "json")
+		if code != exitOK {
+			t.Fatalf("Expected the watch to end with the run, got %d %q", code, stderr)
+		}
+
+		var steps []string
+		for line := range strings.Lines(stdout) {
+			var run struct {
+				Status      string `json:"status"`
+				CurrentStep int    `json:"current_step"`
+			}
+			if err := json.Unmarshal([]byte(line), &run); err != nil {
+				t.Fatalf("Expected JSON lines, got %v\n%s", err, stdout)
+			}
+			steps = append(steps, run.Status+"@"+string(rune('0'+run.CurrentStep)))
+		}
+		if strings.Join(steps, " ") != "ongoing@0 ongoing@1 completed@1" {
+			t.Errorf("Expected each change of the run once, got %v", steps)
+		}
+	})
+}
+
+func TestWorkflows(t *testing.T) {
+	environ, _ := fakeFlho(t, func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, `{"workflows": [{"name": "billing", "namespace": "default", "steps": ["charge", "ship"], "schedule": "@every 1h"}]}`)
+	})
+
+	code, stdout, stderr := runCtl(t, environ, "", "workflows", "list")
+	if code != exitOK || !strings.Contains(stdout, "NAMESPACE") || !strings.Contains(stdout, "charge,ship") || !strings.Contains(stdout, "@every 1h") {
+		t.Errorf("Expected a table of the workflows, got %d %q\n%s", code, stderr, stdout)
+	}
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(valid, []byte(`workflows:
+  invoice:
+    namespace: billing
+    steps:
+      - draft:
+          retryafter: 5m
+          retryurl: "https://example.com/retry"
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalid, []byte(`workflows:
+  invoice:
+    - draft:
+        workflow: missing
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ = runCtl(t, environ, "", "-o", "json", "workflows", "validate", valid)
+	var workflows []map[string]any
+	if err := json.Unmarshal([]byte(stdout), &workflows); code != exitOK || err != nil || len(workflows) != 1 || workflows[0]["namespace"] != "billing" {
+		t.Errorf("Expected the valid file's workflows, got %d %v\n%s", code, err, stdout)
+	}
+
+	code, _, stderr = runCtl(t, environ, "", "workflows", "validate", invalid)
+	if code != exitError || !strings.Contains(stderr, `draft starts unknown workflow "missing"`) {
+		t.Errorf("Expected the file to be invalid, got %d %q", code, stderr)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	environ, requests := fakeFlho(t, func(http.ResponseWriter, *http.Request) {})
+
+	tests := []struct {
+		args []string
+		code int
+		err  string
+	}{
+		{nil, exitUsage, "Commands:"},
+		{[]string{"-h"}, exitOK, "Commands:"},
+		{[]string{"runs", "delete"}, exitUsage, `unknown command "runs delete"`},
+		{[]string{"runs", "get"}, exitUsage, "runs get takes 1 argument(s), got 0"},
+		{[]string{"complete", "run-1", "run-2"}, exitUsage, "complete takes 1 argument(s), got 2"},
+		{[]string{"start", "billing", "-unknown"}, exitUsage, "flag provided but not defined: -unknown"},
+		{[]string{"advance", "run-1", "-label", "stage"}, exitUsage, `label "stage" must be key=value`},
+		{[]string{"-o", "xml", "runs", "list"}, exitError, `unknown output format "xml"`},
+	}
+
+	for _, tt := range tests {
+		code, _, stderr := runCtl(t, environ, "", tt.args...)
+		if code != tt.code || !strings.Contains(stderr, tt.err) {
+			t.Errorf("%v: expected %d %q, got %d %q", tt.args, tt.code, tt.err, code, stderr)
+		}
+	}
+	if got := requests(); len(got) != 0 {
+		t.Errorf("Expected no requests, got %v", got)
+	}
+}
+
+func TestLoadSettings(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, []byte("address: https://flho.example.com\napi_key: file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		environ  map[string]string
+		expected settings
+	}{
+		{"config file", config, nil, settings{Address: "https://flho.example.com", APIKey: "file-secret"}},
+		{"config file of the environment", "", map[string]string{"FLHO_CONFIG": config}, settings{Address: "https://flho.example.com", APIKey: "file-secret"}},
+		{"environment overrides the file", config, map[string]string{"FLHO_ADDR": "http://localhost:5000", "FLHO_API_KEY": "env-secret"}, settings{Address: "http://localhost:5000", APIKey: "env-secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := loadSettings(tt.path, func(key string) string { return tt.environ[key] })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, s)
+			}
+		})
+	}
+
+	t.Run("missing default file", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		t.Setenv("HOME", t.TempDir())
+
+		s, err := loadSettings("", func(string) string { return "" })
+		if err != nil || s != (settings{Address: defaultAddress}) {
+			t.Errorf("Expected the default address, got %+v, %v", s, err)
+		}
+	})
+
+	t.Run("missing explicit file", func(t *testing.T) {
+		if _, err := loadSettings(filepath.Join(t.TempDir(), "missing.yaml"), func(string) string { return "" }); err == nil {
+			t.Error("Expected a missing config file to be an error")
+		}
+	})
+}
+
+// equalJSON reports whether a decoded JSON body holds the expected values.
+func equalJSON(got, expected map[string]any) bool {
+	if len(got) != len(expected) {
+		return false
+	}
+	for key, value := range expected {
+		if m, ok := value.(map[string]any); ok {
+			g, ok := got[key].(map[string]any)
+			if !ok || !maps.EqualFunc(g, m, func(a, b any) bool { return a == b }) {
+				return false
+			}
+			continue
+		}
+		if got[key] != value {
+			return false
+		}
+	}
+	return true
+}
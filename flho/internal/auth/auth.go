@@ -0,0 +1,194 @@
+// Package auth authenticates API keys and checks the scopes they grant.
+//
+// Keys are read from a YAML file holding the SHA-256 hash of each key's
+// secret, never the secret itself:
+//
+//	keys:
+//	  - id: billing-service
+//	    hash: "sha256:12d043d4bd516bc34ea9e95648e9a12329d2d851840fb60b83822997f1382e17"
+//	    scopes: ["runs:read", "runs:advance", "workflows:initiate:billing"]
+//	    namespaces: ["billing"]
+//	  - id: ops
+//	    hash: "sha256:32323cfa9ec9d62750daad0836a4cf3d7b60d23723b7852a529667deed01669f"
+//	    scopes: ["admin"]
+//
+// A client presents the secret as a bearer token:
+//
+//	Authorization: Bearer <secret>
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+// hashPrefix names the hash function of a key's hash.
+const hashPrefix = "sha256:"
+
+// Scope grants access to part of the API.
+type Scope string
+
+const (
+	// ScopeRunsRead allows listing, exporting and viewing runs and schedules
+	ScopeRunsRead Scope = "runs:read"
+	// ScopeRunsAdvance allows advancing, completing, cancelling, resuming and
+	// heartbeating runs, one at a time or in bulk
+	ScopeRunsAdvance Scope = "runs:advance"
+	// ScopeMetricsRead allows scraping metrics
+	ScopeMetricsRead Scope = "metrics:read"
+	// ScopeAdmin allows everything, including managing dead letters and
+	// viewing circuit breakers
+	ScopeAdmin Scope = "admin"
+
+	// initiatePrefix prefixes the scopes allowing a workflow to be initiated
+	initiatePrefix = "workflows:initiate:"
+	// anyWorkflow stands for every workflow in an initiate scope
+	anyWorkflow = "*"
+)
+
+// InitiateScope returns the scope allowing runs of the named workflow to be
+// initiated. "workflows:initiate:*" allows every workflow.
+func InitiateScope(workflow string) Scope {
+	return Scope(initiatePrefix + workflow)
+}
+
+func (s Scope) validate() error {
+	switch s {
+	case ScopeRunsRead, ScopeRunsAdvance, ScopeMetricsRead, ScopeAdmin:
+		return nil
+	}
+	if name, ok := strings.CutPrefix(string(s), initiatePrefix); ok && name != "" {
+		return nil
+	}
+	return fmt.Errorf("unknown scope %q", s)
+}
+
+// Key is an API key, identified by its ID in logs and run histories.
+type Key struct {
+	ID     string  `yaml:"id"`
+	Hash   string  `yaml:"hash"` // "sha256:" followed by the hex-encoded SHA-256 hash of the secret
+	Scopes []Scope `yaml:"scopes"`
+	// Namespaces binds the key to the workflows and runs of these
+	// namespaces. A key without namespaces reaches every namespace.
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// Allows reports whether the key grants the scope.
+func (k *Key) Allows(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin || (s == InitiateScope(anyWorkflow) && strings.HasPrefix(string(scope), initiatePrefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NamespaceFilter returns the namespaces the key is bound to, or nil if it
+// reaches every namespace.
+func (k *Key) NamespaceFilter() []string {
+	if len(k.Namespaces) == 0 {
+		return nil
+	}
+	return k.Namespaces
+}
+
+// Keys holds the API keys, indexed by the hash of their secret.
+type Keys struct {
+	byHash map[string]*Key
+}
+
+// NewKeys indexes the keys, checking that their IDs and hashes are unique and
+// their scopes known.
+func NewKeys(keys []Key) (*Keys, error) {
+	k := &Keys{byHash: make(map[string]*Key, len(keys))}
+	ids := make(map[string]bool, len(keys))
+
+	for i := range keys {
+		key := &keys[i]
+		if key.ID == "" {
+			return nil, fmt.Errorf("key %d has no id", i)
+		}
+		if ids[key.ID] {
+			return nil, fmt.Errorf("key %s is defined twice", key.ID)
+		}
+		ids[key.ID] = true
+
+		sum, ok := strings.CutPrefix(strings.ToLower(key.Hash), hashPrefix)
+		if b, err := hex.DecodeString(sum); !ok || err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("key %s: hash must be %q followed by 64 hex digits", key.ID, hashPrefix)
+		}
+		if _, ok := k.byHash[sum]; ok {
+			return nil, fmt.Errorf("key %s has the same secret as another key", key.ID)
+		}
+
+		if len(key.Scopes) == 0 {
+			return nil, fmt.Errorf("key %s has no scopes", key.ID)
+		}
+		for _, scope := range key.Scopes {
+			if err := scope.validate(); err != nil {
+				return nil, fmt.Errorf("key %s: %w", key.ID, err)
+			}
+		}
+		for _, ns := range key.Namespaces {
+			if err := workflow.ValidateNamespace(ns); err != nil {
+				return nil, fmt.Errorf("key %s: %w", key.ID, err)
+			}
+		}
+
+		k.byHash[sum] = key
+	}
+
+	return k, nil
+}
+
+// LoadKeys reads the API keys from the YAML file at path.
+func LoadKeys(path string) (*Keys, error) {
+	if path == "" {
+		return nil, errors.New("path cannot be empty")
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Keys []Key `yaml:"keys"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Keys) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", path)
+	}
+
+	return NewKeys(file.Keys)
+}
+
+// Authenticate returns the key whose secret is given. Secrets are looked up
+// by their hash, so the time taken does not depend on how much of a secret
+// matches.
+func (k *Keys) Authenticate(secret string) (*Key, bool) {
+	if secret == "" {
+		return nil, false
+	}
+
+	sum := sha256.Sum256([]byte(secret))
+	key, ok := k.byHash[hex.EncodeToString(sum[:])]
+	return key, ok
+}
+
+// HashSecret returns the hash of a secret, as written in the keys file.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeysFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	return path
+}
+
+func TestLoadKeys(t *testing.T) {
+	billing := HashSecret("billing-secret")
+
+	t.Run("valid keys authenticate by their secret", func(t *testing.T) {
+		keys, err := LoadKeys(writeKeysFile(t, `
+keys:
+  - id: billing-service
+    hash: "`+billing+`"
+    scopes: ["runs:read", "workflows:initiate:billing"]
+  - id: ops
+    hash: "`+HashSecret("ops-secret")+`"
+    scopes: ["admin"]
+`))
+		require.NoError(t, err)
+
+		key, ok := keys.Authenticate("billing-secret")
+		require.True(t, ok)
+		require.Equal(t, "billing-service", key.ID)
+
+		key, ok = keys.Authenticate("ops-secret")
+		require.True(t, ok)
+		require.Equal(t, "ops", key.ID)
+
+		_, ok = keys.Authenticate("billing")
+		require.False(t, ok)
+		_, ok = keys.Authenticate("")
+		require.False(t, ok)
+	})
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "no keys", content: "keys: []"},
+		{name: "invalid yaml", content: "keys: [}"},
+		{name: "missing id", content: `keys: [{hash: "` + billing + `", scopes: [admin]}]`},
+		{name: "duplicate id", content: `keys: [{id: a, hash: "` + billing + `", scopes: [admin]}, {id: a, hash: "` + HashSecret("other") + `", scopes: [admin]}]`},
+		{name: "duplicate secret", content: `keys: [{id: a, hash: "` + billing + `", scopes: [admin]}, {id: b, hash: "` + billing + `", scopes: [admin]}]`},
+		{name: "plain secret", content: `keys: [{id: a, hash: billing-secret, scopes: [admin]}]`},
+		{name: "short hash", content: `keys: [{id: a, hash: "sha256:abcd", scopes: [admin]}]`},
+		{name: "no scopes", content: `keys: [{id: a, hash: "` + billing + `"}]`},
+		{name: "unknown scope", content: `keys: [{id: a, hash: "` + billing + `", scopes: [runs:write]}]`},
+		{name: "initiate scope without workflow", content: `keys: [{id: a, hash: "` + billing + `", scopes: ["workflows:initiate:"]}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadKeys(writeKeysFile(t, tt.content))
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadKeys(filepath.Join(t.TempDir(), "missing.yaml"))
+		require.Error(t, err)
+		_, err = LoadKeys("")
+		require.Error(t, err)
+	})
+}
+
+func TestKeyAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []Scope
+		allowed []Scope
+		denied  []Scope
+	}{
+		{
+			name:    "listed scopes",
+			scopes:  []Scope{ScopeRunsRead, InitiateScope("billing")},
+			allowed: []Scope{ScopeRunsRead, InitiateScope("billing")},
+			denied:  []Scope{ScopeRunsAdvance, ScopeAdmin, ScopeMetricsRead, InitiateScope("export")},
+		},
+		{
+			name:    "any workflow",
+			scopes:  []Scope{InitiateScope("*")},
+			allowed: []Scope{InitiateScope("billing"), InitiateScope("export")},
+			denied:  []Scope{ScopeRunsRead},
+		},
+		{
+			name:    "admin",
+			scopes:  []Scope{ScopeAdmin},
+			allowed: []Scope{ScopeRunsRead, ScopeRunsAdvance, ScopeMetricsRead, ScopeAdmin, InitiateScope("billing")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := Key{ID: "key", Scopes: tt.scopes}
+			for _, scope := range tt.allowed {
+				require.True(t, key.Allows(scope), scope)
+			}
+			for _, scope := range tt.denied {
+				require.False(t, key.Allows(scope), scope)
+			}
+		})
+	}
+}
+
+func TestSessions(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	sessions := NewSessions()
+	sessions.now = func() time.Time { return now }
+	key := &Key{ID: "ops", Scopes: []Scope{ScopeAdmin}}
+
+	token, err := sessions.Create(key)
+	require.NoError(t, err)
+	other, err := sessions.Create(key)
+	require.NoError(t, err)
+	require.NotEqual(t, token, other)
+
+	found, ok := sessions.Lookup(token)
+	require.True(t, ok)
+	require.Same(t, key, found)
+	_, ok = sessions.Lookup("unknown")
+	require.False(t, ok)
+
+	sessions.Delete(other)
+	_, ok = sessions.Lookup(other)
+	require.False(t, ok)
+
+	now = now.Add(SessionTTL)
+	_, ok = sessions.Lookup(token)
+	require.False(t, ok, "sessions expire")
+}
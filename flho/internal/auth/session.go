@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// SessionTTL is how long a login session lasts.
+const SessionTTL = 12 * time.Hour
+
+// Sessions holds the login sessions of the runs UI, each naming the key that
+// logged in. Sessions live in memory only, so a restart logs everyone out.
+type Sessions struct {
+	mu       sync.Mutex
+	sessions map[string]session
+	now      func() time.Time
+}
+
+type session struct {
+	key     *Key
+	expires time.Time
+}
+
+// NewSessions creates an empty session store.
+func NewSessions() *Sessions {
+	return &Sessions{sessions: make(map[string]session), now: time.Now}
+}
+
+// Create starts a session for the key and returns its token.
+func (s *Sessions) Create(key *Key) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	// drop expired sessions so that abandoned logins do not accumulate
+	for t, sess := range s.sessions {
+		if !now.Before(sess.expires) {
+			delete(s.sessions, t)
+		}
+	}
+	s.sessions[token] = session{key: key, expires: now.Add(SessionTTL)}
+
+	return token, nil
+}
+
+// Lookup returns the key of the session with the token, if it has not
+// expired.
+func (s *Sessions) Lookup(token string) (*Key, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[token]
+	if !ok {
+		return nil, false
+	}
+	if !s.now().Before(sess.expires) {
+		delete(s.sessions, token)
+		return nil, false
+	}
+	return sess.key, true
+}
+
+// Delete ends the session with the token.
+func (s *Sessions) Delete(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, token)
+}
@@ -0,0 +1,198 @@
+// Package label validates the free-form labels attached to runs and parses
+// the selectors used to search runs by label.
+//
+// A selector is a comma-separated list of requirements, all of which a run's
+// labels must satisfy:
+//
+//	"customer_id=42"             customer_id is 42
+//	"region!=eu"                 region is not eu, or is not set
+//	"priority_customer"          priority_customer is set, to any value
+//	"!trial"                     trial is not set
+//	"customer_id=42,region!=eu"  both of the above
+//
+// "==" is accepted as a synonym of "=". Whitespace around keys and values is
+// ignored.
+package label
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxLabels is the number of labels a run may carry.
+	MaxLabels = 32
+	// MaxKeyLength and MaxValueLength bound the size of a single label.
+	MaxKeyLength   = 63
+	MaxValueLength = 255
+)
+
+// Validate reports whether labels can be stored with a run and matched by a
+// selector. Keys are made of letters, digits, '_', '-', '.' and '/'; values
+// may not contain ',', '=' or '!', nor surrounding whitespace, so that any
+// label can be written as a selector.
+func Validate(labels map[string]string) error {
+	if len(labels) > MaxLabels {
+		return fmt.Errorf("at most %d labels are allowed, got %d", MaxLabels, len(labels))
+	}
+
+	for k, v := range labels {
+		if err := validateKey(k); err != nil {
+			return err
+		}
+		if len(v) > MaxValueLength {
+			return fmt.Errorf("label %q: value is longer than %d characters", k, MaxValueLength)
+		}
+		if strings.ContainsAny(v, ",=!") || strings.TrimSpace(v) != v {
+			return fmt.Errorf("label %q: value may not contain ',', '=', '!' or surrounding whitespace", k)
+		}
+	}
+
+	return nil
+}
+
+func validateKey(k string) error {
+	if k == "" {
+		return errors.New("label key is empty")
+	}
+	if len(k) > MaxKeyLength {
+		return fmt.Errorf("label key %q is longer than %d characters", k, MaxKeyLength)
+	}
+	for _, r := range k {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_', r == '-', r == '.', r == '/':
+		default:
+			return fmt.Errorf("label key %q: invalid character %q", k, r)
+		}
+	}
+	return nil
+}
+
+// operator is how a requirement compares a label.
+type operator int
+
+const (
+	equals operator = iota
+	notEquals
+	exists
+	notExists
+)
+
+// requirement is a single condition of a selector.
+type requirement struct {
+	key   string
+	op    operator
+	value string
+}
+
+func (r requirement) matches(labels map[string]string) bool {
+	v, ok := labels[r.key]
+	switch r.op {
+	case equals:
+		return ok && v == r.value
+	case notEquals:
+		return !ok || v != r.value
+	case exists:
+		return ok
+	default:
+		return !ok
+	}
+}
+
+// Selector matches runs by their labels. The zero Selector matches every run.
+type Selector struct {
+	requirements []requirement
+	spec         string
+}
+
+// ParseSelector parses a selector such as "customer_id=42,region!=eu".
+func ParseSelector(spec string) (Selector, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return Selector{}, nil
+	}
+
+	var s Selector
+	for _, part := range strings.Split(spec, ",") {
+		r, err := parseRequirement(strings.TrimSpace(part))
+		if err != nil {
+			return Selector{}, fmt.Errorf("invalid label selector %q: %w", spec, err)
+		}
+		s.requirements = append(s.requirements, r)
+	}
+	s.spec = spec
+
+	return s, nil
+}
+
+func parseRequirement(part string) (requirement, error) {
+	var r requirement
+
+	switch {
+	case part == "":
+		return r, errors.New("empty requirement")
+	case strings.Contains(part, "!="):
+		key, value, _ := strings.Cut(part, "!=")
+		r = requirement{key: strings.TrimSpace(key), op: notEquals, value: strings.TrimSpace(value)}
+	case strings.Contains(part, "=="):
+		key, value, _ := strings.Cut(part, "==")
+		r = requirement{key: strings.TrimSpace(key), op: equals, value: strings.TrimSpace(value)}
+	case strings.Contains(part, "="):
+		key, value, _ := strings.Cut(part, "=")
+		r = requirement{key: strings.TrimSpace(key), op: equals, value: strings.TrimSpace(value)}
+	case strings.HasPrefix(part, "!"):
+		r = requirement{key: strings.TrimSpace(part[1:]), op: notExists}
+	default:
+		r = requirement{key: part, op: exists}
+	}
+
+	if err := validateKey(r.key); err != nil {
+		return requirement{}, err
+	}
+	if strings.ContainsAny(r.value, "=!") {
+		return requirement{}, fmt.Errorf("%q: value may not contain '=' or '!'", part)
+	}
+
+	return r, nil
+}
+
+// Matches reports whether labels satisfy every requirement of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s.requirements {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equalities returns the labels the selector requires to have a given value,
+// keyed by label key. ok is false if two requirements ask for different
+// values of the same key, so that no run can match.
+func (s Selector) Equalities() (labels map[string]string, ok bool) {
+	for _, r := range s.requirements {
+		if r.op != equals {
+			continue
+		}
+		if v, seen := labels[r.key]; seen && v != r.value {
+			return nil, false
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[r.key] = r.value
+	}
+	return labels, true
+}
+
+// Empty reports whether the selector matches every run.
+func (s Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// String returns the selector as it was parsed.
+func (s Selector) String() string {
+	return s.spec
+}
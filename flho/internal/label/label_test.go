@@ -0,0 +1,104 @@
+package label
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSelector_Matches(t *testing.T) {
+	labels := map[string]string{"customer_id": "42", "region": "us", "tier": "gold"}
+
+	tests := []struct {
+		name     string
+		selector string
+		expected bool
+	}{
+		{name: "empty selector", selector: "", expected: true},
+		{name: "equals", selector: "customer_id=42", expected: true},
+		{name: "double equals", selector: "customer_id==42", expected: true},
+		{name: "equals other value", selector: "customer_id=43", expected: false},
+		{name: "not equals", selector: "region!=eu", expected: true},
+		{name: "not equals same value", selector: "region!=us", expected: false},
+		{name: "not equals missing label", selector: "plan!=free", expected: true},
+		{name: "exists", selector: "tier", expected: true},
+		{name: "exists missing label", selector: "plan", expected: false},
+		{name: "not exists", selector: "!plan", expected: true},
+		{name: "not exists present label", selector: "!tier", expected: false},
+		{name: "all requirements match", selector: "customer_id=42,region!=eu", expected: true},
+		{name: "one requirement fails", selector: "customer_id=42,region=eu", expected: false},
+		{name: "whitespace", selector: " customer_id = 42 , region != eu ", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSelector(tt.selector)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, s.Matches(labels))
+		})
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	for _, selector := range []string{
+		"customer_id=42,",
+		"=42",
+		"!",
+		"customer id=42",
+		"region=eu=us",
+		"region!=!eu",
+	} {
+		t.Run(selector, func(t *testing.T) {
+			_, err := ParseSelector(selector)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(nil))
+	require.NoError(t, Validate(map[string]string{"customer_id": "42", "team/owner": "billing", "empty": ""}))
+
+	tooMany := make(map[string]string)
+	for i := range MaxLabels + 1 {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+	}{
+		{name: "empty key", labels: map[string]string{"": "42"}},
+		{name: "invalid key character", labels: map[string]string{"customer id": "42"}},
+		{name: "key too long", labels: map[string]string{strings.Repeat("k", MaxKeyLength+1): "v"}},
+		{name: "value too long", labels: map[string]string{"k": strings.Repeat("v", MaxValueLength+1)}},
+		{name: "comma in value", labels: map[string]string{"regions": "eu,us"}},
+		{name: "operator in value", labels: map[string]string{"query": "a=b"}},
+		{name: "surrounding whitespace", labels: map[string]string{"region": " eu"}},
+		{name: "too many labels", labels: tooMany},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, Validate(tt.labels))
+		})
+	}
+}
+
+func TestSelector_Equalities(t *testing.T) {
+	s, err := ParseSelector("customer_id=42,region!=eu,tier,plan==gold")
+	require.NoError(t, err)
+	labels, ok := s.Equalities()
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"customer_id": "42", "plan": "gold"}, labels)
+
+	s, err = ParseSelector("region=us,region=eu")
+	require.NoError(t, err)
+	_, ok = s.Equalities()
+	require.False(t, ok)
+
+	labels, ok = Selector{}.Equalities()
+	require.True(t, ok)
+	require.Empty(t, labels)
+}
@@ -0,0 +1,370 @@
+// Package metrics provides counters, gauges and histograms exposed in the
+// Prometheus text format, without depending on a Prometheus client library.
+//
+// Metrics are created on a Registry, optionally with label names, and the
+// series of each label combination are created on first use:
+//
+//	reg := metrics.NewRegistry()
+//	started := reg.Counter("flho_runs_started_total", "Runs started.", "workflow")
+//	started.With("billing").Inc()
+//
+//	http.Handle("/metrics", reg)
+//
+// Series are written sorted by their label values, so the output of a
+// registry is stable and can be compared in tests.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default histogram buckets, in seconds, suited to
+// network latencies.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and writes them out, in the order they were
+// created.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// metric is a named family of series.
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes every metric in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = r.WriteTo(w)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// family holds the series of a metric, keyed by their label values.
+type family[S any] struct {
+	name, help, kind string
+	labels           []string
+	newSeries        func() *S
+
+	mu     sync.Mutex
+	series map[string]*S
+	values map[string][]string
+}
+
+func newFamily[S any](name, help, kind string, labels []string, newSeries func() *S) *family[S] {
+	return &family[S]{
+		name:      name,
+		help:      help,
+		kind:      kind,
+		labels:    labels,
+		newSeries: newSeries,
+		series:    make(map[string]*S),
+		values:    make(map[string][]string),
+	}
+}
+
+// with returns the series of the label values, creating it on first use.
+func (f *family[S]) with(values []string) *S {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[key]
+	if !ok {
+		s = f.newSeries()
+		f.series[key] = s
+		f.values[key] = slices.Clone(values)
+	}
+	return s
+}
+
+// each calls fn with every series and its label pairs, sorted by label
+// values.
+func (f *family[S]) each(fn func(labels string, s *S)) {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	series := make([]*S, len(keys))
+	labels := make([]string, len(keys))
+	for i, key := range keys {
+		series[i] = f.series[key]
+		labels[i] = labelPairs(f.labels, f.values[key])
+	}
+	f.mu.Unlock()
+
+	for i := range keys {
+		fn(labels[i], series[i])
+	}
+}
+
+func (f *family[S]) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+}
+
+// labelPairs formats label names and values as name="value" pairs,
+// separated by commas.
+func labelPairs(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func braces(pairs string) string {
+	if pairs == "" {
+		return ""
+	}
+	return "{" + pairs + "}"
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// value is a float64 updated atomically.
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (v *value) set(f float64) { v.bits.Store(math.Float64bits(f)) }
+func (v *value) get() float64  { return math.Float64frombits(v.bits.Load()) }
+
+// Counter is a value that only goes up.
+type Counter struct {
+	v value
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() { c.v.add(1) }
+
+// Add adds delta, which must not be negative, to the counter.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.v.add(delta)
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	f *family[Counter]
+}
+
+// Counter creates a counter with the given label names.
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{f: newFamily(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	r.register(name, c)
+	return c
+}
+
+// With returns the counter of the label values, given in the order of the
+// label names.
+func (c *CounterVec) With(values ...string) *Counter { return c.f.with(values) }
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.f.writeHeader(w)
+	c.f.each(func(labels string, s *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", c.f.name, braces(labels), formatFloat(s.v.get()))
+	})
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	v value
+}
+
+// Inc adds one to the gauge.
+func (g *Gauge) Inc() { g.v.add(1) }
+
+// Dec subtracts one from the gauge.
+func (g *Gauge) Dec() { g.v.add(-1) }
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) { g.v.set(v) }
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct {
+	f *family[Gauge]
+}
+
+// Gauge creates a gauge with the given label names.
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{f: newFamily(name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	r.register(name, g)
+	return g
+}
+
+// With returns the gauge of the label values, given in the order of the
+// label names.
+func (g *GaugeVec) With(values ...string) *Gauge { return g.f.with(values) }
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.f.writeHeader(w)
+	g.f.each(func(labels string, s *Gauge) {
+		fmt.Fprintf(w, "%s%s %s\n", g.f.name, braces(labels), formatFloat(s.v.get()))
+	})
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	bounds []float64 // upper bounds, ascending, without +Inf
+
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative, with +Inf last
+	sum    float64
+}
+
+// Observe records a single observation.
+func (h *Histogram) Observe(v float64) {
+	i, _ := slices.BinarySearch(h.bounds, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[i]++
+	h.sum += v
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	f      *family[Histogram]
+	bounds []float64
+}
+
+// Histogram creates a histogram with the given bucket upper bounds and label
+// names. The +Inf bucket is always added.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	bounds := slices.Clone(buckets)
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+	if n := len(bounds); n > 0 && math.IsInf(bounds[n-1], 1) {
+		bounds = bounds[:n-1]
+	}
+
+	h := &HistogramVec{bounds: bounds}
+	h.f = newFamily(name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+	})
+	r.register(name, h)
+	return h
+}
+
+// With returns the histogram of the label values, given in the order of the
+// label names.
+func (h *HistogramVec) With(values ...string) *Histogram { return h.f.with(values) }
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.f.writeHeader(w)
+	h.f.each(func(labels string, s *Histogram) {
+		s.mu.Lock()
+		counts := slices.Clone(s.counts)
+		sum := s.sum
+		s.mu.Unlock()
+
+		prefix := labels
+		if prefix != "" {
+			prefix += ","
+		}
+
+		var cumulative uint64
+		for i, count := range counts {
+			cumulative += count
+			le := math.Inf(1)
+			if i < len(h.bounds) {
+				le = h.bounds[i]
+			}
+			fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", h.f.name, prefix, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.f.name, braces(labels), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.f.name, braces(labels), cumulative)
+	})
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+
+	runs := reg.Counter("runs_total", "Runs finished.", "workflow", "status")
+	runs.With("export", "failed").Inc()
+	runs.With("billing", "completed").Add(2)
+	runs.With("billing", "completed").Inc()
+
+	active := reg.Gauge("active", "Active runs.")
+	active.With().Inc()
+	active.With().Inc()
+	active.With().Dec()
+
+	duration := reg.Histogram("duration_seconds", "Run \\ duration\nin seconds.", []float64{1, 0.5, 1}, "workflow")
+	duration.With("billing").Observe(0.5)
+	duration.With("billing").Observe(0.7)
+	duration.With("billing").Observe(3)
+
+	unused := reg.Counter("unused_total", "Never incremented.", "quoted")
+	unused.With("say \"hi\"\n")
+
+	var out strings.Builder
+	n, err := reg.WriteTo(&out)
+	require.NoError(t, err)
+	require.Equal(t, int64(out.Len()), n)
+	require.Equal(t, `# HELP runs_total Runs finished.
+# TYPE runs_total counter
+runs_total{workflow="billing",status="completed"} 3
+runs_total{workflow="export",status="failed"} 1
+# HELP active Active runs.
+# TYPE active gauge
+active 1
+# HELP duration_seconds Run \\ duration\nin seconds.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{workflow="billing",le="0.5"} 1
+duration_seconds_bucket{workflow="billing",le="1"} 2
+duration_seconds_bucket{workflow="billing",le="+Inf"} 3
+duration_seconds_sum{workflow="billing"} 4.2
+duration_seconds_count{workflow="billing"} 3
+# HELP unused_total Never incremented.
+# TYPE unused_total counter
+unused_total{quoted="say \"hi\"\n"} 0
+`, out.String())
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	reg := NewRegistry()
+	counter := reg.Counter("requests_total", "Requests.", "code")
+	histogram := reg.Histogram("latency_seconds", "Latency.", DefBuckets)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				counter.With("200").Inc()
+				histogram.With().Observe(0.01)
+			}
+		}()
+	}
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	reg.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	require.Contains(t, w.Body.String(), `requests_total{code="200"} 1000`)
+	require.Contains(t, w.Body.String(), `latency_seconds_bucket{le="0.01"} 1000`)
+	require.Contains(t, w.Body.String(), `latency_seconds_bucket{le="0.005"} 0`)
+}
+
+func TestMisuse(t *testing.T) {
+	reg := NewRegistry()
+	counter := reg.Counter("runs_total", "Runs.", "workflow")
+
+	require.Panics(t, func() { reg.Gauge("runs_total", "Runs.") })
+	require.Panics(t, func() { counter.With("billing", "failed") })
+	require.Panics(t, func() { counter.With("billing").Add(-1) })
+}
@@ -0,0 +1,105 @@
+// Package ratelimit limits how often each client may make requests, with a
+// token bucket per client.
+//
+// A bucket holds up to burst tokens and refills at rate tokens per second.
+// Every request takes a token, and a request finding the bucket empty is
+// refused until the next token arrives.
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have refilled completely, and so
+// are no different from new ones, are dropped.
+const sweepInterval = time.Minute
+
+// Limiter holds a token bucket for every client, identified by a key such as
+// its IP address or API key ID. It is safe for concurrent use.
+type Limiter struct {
+	rate  float64 // tokens added per second
+	burst float64 // tokens a bucket holds when full
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time // when tokens was last brought up to date
+}
+
+// New returns a limiter allowing each client rate requests per second on
+// average and bursts of up to burst requests.
+func New(rate float64, burst int) (*Limiter, error) {
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return nil, errors.New("rate must be a positive number of requests per second")
+	}
+	if burst < 1 {
+		return nil, errors.New("burst must be at least 1")
+	}
+
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}, nil
+}
+
+// Allow takes a token from the client's bucket, reporting whether there was
+// one. When there was not, it also returns how long the client should wait
+// before its next request is allowed.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.rate, l.burst)
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// Burst returns the number of requests a client may make at once.
+func (l *Limiter) Burst() int {
+	return int(l.burst)
+}
+
+// sweep drops the buckets that have refilled completely. The caller must
+// hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.refill(now, l.rate, l.burst); b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// refill adds the tokens earned since the bucket was last brought up to date.
+func (b *bucket) refill(now time.Time, rate, burst float64) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(burst, b.tokens+elapsed.Seconds()*rate)
+		b.last = now
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(0, 1)
+	require.Error(t, err)
+	_, err = New(-1, 1)
+	require.Error(t, err)
+	_, err = New(1, 0)
+	require.Error(t, err)
+
+	l, err := New(0.5, 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, l.Burst())
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	l, err := New(2, 3)
+	require.NoError(t, err)
+	l.now = func() time.Time { return now }
+
+	t.Run("a full bucket allows a burst", func(t *testing.T) {
+		for range 3 {
+			ok, _ := l.Allow("a")
+			require.True(t, ok)
+		}
+
+		ok, wait := l.Allow("a")
+		require.False(t, ok)
+		require.Equal(t, 500*time.Millisecond, wait)
+	})
+
+	t.Run("clients have their own buckets", func(t *testing.T) {
+		ok, _ := l.Allow("b")
+		require.True(t, ok)
+	})
+
+	t.Run("buckets refill over time", func(t *testing.T) {
+		now = now.Add(250 * time.Millisecond)
+		ok, wait := l.Allow("a")
+		require.False(t, ok)
+		require.Equal(t, 250*time.Millisecond, wait)
+
+		now = now.Add(250 * time.Millisecond)
+		ok, _ = l.Allow("a")
+		require.True(t, ok)
+		ok, _ = l.Allow("a")
+		require.False(t, ok)
+	})
+
+	t.Run("full buckets are swept", func(t *testing.T) {
+		now = now.Add(sweepInterval)
+		ok, _ := l.Allow("c")
+		require.True(t, ok)
+		require.Len(t, l.buckets, 1)
+
+		// a swept client starts again with a full bucket
+		for range 3 {
+			ok, _ := l.Allow("a")
+			require.True(t, ok)
+		}
+	})
+}
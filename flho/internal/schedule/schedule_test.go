@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Next(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2024, 1, 10, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		spec     string
+		expected time.Time
+	}{
+		{name: "daily at 2am", spec: "0 2 * * *", expected: time.Date(2024, 1, 11, 2, 0, 0, 0, time.UTC)},
+		{name: "every 15 minutes", spec: "*/15 * * * *", expected: time.Date(2024, 1, 10, 14, 45, 0, 0, time.UTC)},
+		{name: "list of minutes", spec: "10,40 * * * *", expected: time.Date(2024, 1, 10, 14, 40, 0, 0, time.UTC)},
+		{name: "weekdays only", spec: "0 9 * * MON-FRI", expected: time.Date(2024, 1, 11, 9, 0, 0, 0, time.UTC)},
+		{name: "sunday as 7", spec: "0 0 * * 7", expected: time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{name: "next month", spec: "0 0 1 * *", expected: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "named month", spec: "0 0 1 jun *", expected: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", spec: "0 0 29 2 *", expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month or day of week", spec: "0 0 15 * 5", expected: time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{name: "step from a value", spec: "5/20 * * * *", expected: time.Date(2024, 1, 10, 14, 45, 0, 0, time.UTC)},
+		{name: "descriptor", spec: "@hourly", expected: time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC)},
+		{name: "interval", spec: "@every 90m", expected: time.Date(2024, 1, 10, 16, 0, 0, 0, time.UTC)},
+		{name: "never matches", spec: "0 0 30 2 *", expected: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+			require.True(t, tt.expected.Equal(s.Next(from)), "expected %v, got %v", tt.expected, s.Next(from))
+		})
+	}
+}
+
+func TestParse_Timezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	s, err := Parse("0 2 * * *")
+	require.NoError(t, err)
+
+	// 02:00 in New York is 07:00 UTC in winter
+	next := s.Next(time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC).In(loc))
+	require.Equal(t, time.Date(2024, 1, 11, 7, 0, 0, 0, time.UTC), next.UTC())
+
+	// 02:00 does not exist on the day clocks spring forward, so that day
+	// is skipped
+	next = s.Next(time.Date(2024, 3, 10, 0, 0, 0, 0, loc))
+	require.Equal(t, time.Date(2024, 3, 11, 2, 0, 0, 0, loc), next)
+
+	// 01:30 happens twice on the day clocks fall back, but fires once
+	s, err = Parse("30 1 * * *")
+	require.NoError(t, err)
+	first := s.Next(time.Date(2024, 11, 3, 0, 0, 0, 0, loc))
+	require.Equal(t, time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), first.UTC())
+	require.Equal(t, time.Date(2024, 11, 4, 1, 30, 0, 0, loc), s.Next(first))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		spec        string
+		expectedErr string
+	}{
+		{spec: "", expectedErr: "cannot be empty"},
+		{spec: "* * * *", expectedErr: "expected 5 fields"},
+		{spec: "60 * * * *", expectedErr: "minute field"},
+		{spec: "0 24 * * *", expectedErr: "hour field"},
+		{spec: "0 0 0 * *", expectedErr: "day of month field"},
+		{spec: "0 0 * 13 *", expectedErr: "month field"},
+		{spec: "0 0 * * mon-sun-x", expectedErr: "day of week field"},
+		{spec: "*/0 * * * *", expectedErr: "invalid step"},
+		{spec: "30-10 * * * *", expectedErr: "invalid range"},
+		{spec: "@every soon", expectedErr: "invalid interval"},
+		{spec: "@every -5m", expectedErr: "must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
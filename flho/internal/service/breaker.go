@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+	defaultHostConcurrency  = 10
+)
+
+// BreakerState represents the state of a per-host circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets deliveries through, counting consecutive failures.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen holds deliveries back until the cooldown has elapsed.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe delivery through to test the host.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerStatus is a snapshot of the breaker guarding one target host.
+type BreakerStatus struct {
+	Host          string
+	State         BreakerState
+	Failures      int // consecutive failures
+	InFlight      int
+	Deferred      int // deliveries waiting on the breaker or a concurrency slot
+	MaxConcurrent int
+	OpenedAt      *time.Time
+	RetryAt       *time.Time // when an open breaker next allows a probe
+	LastError     string
+}
+
+// hostBreaker tracks the health of, and the outstanding deliveries to, a
+// single target host.
+type hostBreaker struct {
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	inFlight  int
+	waiting   []*ticket
+	lastError string
+	changed   chan struct{} // closed and replaced whenever the host changes
+}
+
+// ticket is a delivery waiting its turn for a host. Waiters are served in
+// queue order, and a waiter only proceeds once it reaches the head. The queue
+// is ordered by priority, highest first, then by arrival.
+type ticket struct {
+	seq      uint64 // arrival order
+	priority int
+}
+
+// breakers guards outbound deliveries with a circuit breaker and a concurrency
+// limit per target host. Deliveries held back by an open breaker or a full
+// host wait in line rather than being dropped.
+type breakers struct {
+	mu            sync.Mutex
+	hosts         map[string]*hostBreaker
+	seq           uint64
+	threshold     int
+	cooldown      time.Duration
+	maxConcurrent int
+	timeProvider  TimeProvider // the service's, or the wall clock if nil
+}
+
+// WithCircuitBreaker sets how many consecutive failed deliveries open a host's
+// circuit breaker, and how long it stays open before a probe is let through.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(w *WorkflowService) {
+		w.breakers.threshold = threshold
+		w.breakers.cooldown = cooldown
+	}
+}
+
+// WithHostConcurrency limits how many deliveries may be in flight to a single
+// target host at once.
+func WithHostConcurrency(limit int) Option {
+	return func(w *WorkflowService) {
+		w.breakers.maxConcurrent = limit
+	}
+}
+
+func (b *breakers) limits() (threshold int, cooldown time.Duration, maxConcurrent int) {
+	threshold, cooldown, maxConcurrent = b.threshold, b.cooldown, b.maxConcurrent
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultHostConcurrency
+	}
+	return threshold, cooldown, maxConcurrent
+}
+
+// now returns the current time of the breakers' time provider.
+func (b *breakers) now() time.Time {
+	if b.timeProvider == nil {
+		return time.Now()
+	}
+	return b.timeProvider.Now()
+}
+
+// host returns the breaker for a host, creating it on first use. The caller
+// must hold b.mu.
+func (b *breakers) host(name string) *hostBreaker {
+	if b.hosts == nil {
+		b.hosts = make(map[string]*hostBreaker)
+	}
+	hb, ok := b.hosts[name]
+	if !ok {
+		hb = &hostBreaker{state: BreakerClosed}
+		b.hosts[name] = hb
+	}
+	return hb
+}
+
+// notify wakes the waiters of the host so they can re-check it, leaving those
+// of other hosts asleep. The caller must hold b.mu.
+func (hb *hostBreaker) notify() {
+	if hb.changed != nil {
+		close(hb.changed)
+	}
+	hb.changed = make(chan struct{})
+}
+
+// wait returns the channel closed on the host's next change. The caller must
+// hold b.mu.
+func (hb *hostBreaker) wait() <-chan struct{} {
+	if hb.changed == nil {
+		hb.changed = make(chan struct{})
+	}
+	return hb.changed
+}
+
+// acquire blocks until a delivery to host may proceed: the breaker is closed
+// or this delivery is the half-open probe, a concurrency slot is free, and no
+// earlier delivery of the same or a higher priority is waiting. The returned
+// release func must be called with the error of the delivery, or nil.
+func (b *breakers) acquire(ctx context.Context, host string, priority int) (func(err error), error) {
+	_, cooldown, maxConcurrent := b.limits()
+
+	b.mu.Lock()
+	b.seq++
+	t := &ticket{seq: b.seq, priority: priority}
+	hb := b.host(host)
+	i := slices.IndexFunc(hb.waiting, func(w *ticket) bool { return w.priority < priority })
+	if i < 0 {
+		i = len(hb.waiting)
+	}
+	hb.waiting = slices.Insert(hb.waiting, i, t)
+
+	for {
+		var retry *time.Timer
+		if hb.waiting[0] == t {
+			if hb.state == BreakerOpen {
+				if remaining := cooldown - b.now().Sub(hb.openedAt); remaining > 0 {
+					retry = time.NewTimer(remaining)
+				} else {
+					hb.state = BreakerHalfOpen
+				}
+			}
+
+			admitted := hb.inFlight < maxConcurrent &&
+				(hb.state == BreakerClosed || (hb.state == BreakerHalfOpen && !hb.probing))
+			if admitted {
+				probe := hb.state == BreakerHalfOpen
+				hb.probing = hb.probing || probe
+				hb.inFlight++
+				hb.waiting = hb.waiting[1:]
+				hb.notify()
+				b.mu.Unlock()
+
+				return func(err error) {
+					b.release(ctx, hb, probe, err)
+				}, nil
+			}
+		}
+
+		changed := hb.wait()
+		b.mu.Unlock()
+
+		var retryC <-chan time.Time
+		if retry != nil {
+			retryC = retry.C
+		}
+
+		select {
+		case <-changed:
+		case <-retryC:
+		case <-ctx.Done():
+			b.mu.Lock()
+			for i, w := range hb.waiting {
+				if w == t {
+					hb.waiting = append(hb.waiting[:i], hb.waiting[i+1:]...)
+					break
+				}
+			}
+			hb.notify()
+			b.mu.Unlock()
+			return nil, ctx.Err()
+		}
+
+		if retry != nil {
+			retry.Stop()
+		}
+		b.mu.Lock()
+	}
+}
+
+// release records the outcome of a delivery and frees its concurrency slot.
+// A failed probe re-opens the breaker, a successful one closes it, and enough
+// consecutive failures open a closed breaker. Only a 2xx answer is a success:
+// a client error shows the host is up without showing that it handles
+// requests again, so a probe answered with one leaves the breaker half-open
+// and the next delivery probes the host in turn. A delivery abandoned because
+// ctx ended says nothing about the host either, and leaves the breaker as it
+// was.
+func (b *breakers) release(ctx context.Context, hb *hostBreaker, probe bool, err error) {
+	threshold, _, _ := b.limits()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hb.inFlight--
+	if probe {
+		hb.probing = false
+	}
+
+	switch {
+	case err == nil:
+		hb.failures = 0
+		if probe {
+			hb.state = BreakerClosed
+		}
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		// abandoned by the caller; neither a failure nor a success
+	case !breakerFailure(err):
+		if !probe {
+			hb.failures = 0
+		}
+	default:
+		hb.failures++
+		hb.lastError = err.Error()
+		if probe || (hb.state == BreakerClosed && hb.failures >= threshold) {
+			hb.state = BreakerOpen
+			hb.openedAt = b.now()
+		}
+	}
+
+	hb.notify()
+}
+
+// statuses returns a snapshot of every known host breaker, sorted by host.
+func (b *breakers) statuses() []BreakerStatus {
+	_, cooldown, maxConcurrent := b.limits()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(b.hosts))
+	for host, hb := range b.hosts {
+		status := BreakerStatus{
+			Host:          host,
+			State:         hb.state,
+			Failures:      hb.failures,
+			InFlight:      hb.inFlight,
+			Deferred:      len(hb.waiting),
+			MaxConcurrent: maxConcurrent,
+			LastError:     hb.lastError,
+		}
+		if hb.state != BreakerClosed {
+			openedAt := hb.openedAt
+			retryAt := openedAt.Add(cooldown)
+			status.OpenedAt, status.RetryAt = &openedAt, &retryAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Host < statuses[j].Host })
+
+	return statuses
+}
+
+// Breakers returns the circuit breaker state of every host that has been sent
+// a notification.
+func (w *WorkflowService) Breakers() []BreakerStatus {
+	return w.breakers.statuses()
+}
+
+// targetHost returns the host a notification is sent to, which is the unit
+// the circuit breaker and concurrency limit apply to.
+func targetHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// breakerFailure reports whether a delivery error reflects on the health of
+// the target host. Client errors are the caller's fault, not the host's.
+func breakerFailure(err error) bool {
+	status := responseStatus(err)
+	return status == 0 || status >= 500
+}
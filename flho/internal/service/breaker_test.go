@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func breakerFor(t *testing.T, b *breakers, host string) BreakerStatus {
+	t.Helper()
+	for _, status := range b.statuses() {
+		if status.Host == host {
+			return status
+		}
+	}
+	t.Fatalf("no breaker for host %s", host)
+	return BreakerStatus{}
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := &breakers{threshold: 2, cooldown: time.Hour}
+
+	for range 2 {
+		release, err := b.acquire(context.Background(), "example.com", 0)
+		require.NoError(t, err)
+		release(errors.New("connection refused"))
+	}
+
+	status := breakerFor(t, b, "example.com")
+	require.Equal(t, BreakerOpen, status.State)
+	require.Equal(t, 2, status.Failures)
+	require.Equal(t, "connection refused", status.LastError)
+	require.NotNil(t, status.RetryAt)
+
+	// deliveries are deferred rather than dropped while the breaker is open
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := b.acquire(ctx, "example.com", 0)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// other hosts are unaffected
+	release, err := b.acquire(context.Background(), "other.com", 0)
+	require.NoError(t, err)
+	release(nil)
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	opened := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		probeErr      error
+		expectedState BreakerState
+	}{
+		{name: "successful probe closes the breaker", expectedState: BreakerClosed},
+		{name: "failed probe re-opens the breaker", probeErr: errors.New("boom"), expectedState: BreakerOpen},
+		{name: "client error leaves the breaker half-open", probeErr: &statusError{code: http.StatusNotFound}, expectedState: BreakerHalfOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeProvider := new(MockTimeProvider)
+			b := &breakers{threshold: 1, cooldown: time.Hour, timeProvider: timeProvider}
+
+			timeProvider.On("Now").Return(opened).Once()
+			release, err := b.acquire(context.Background(), "example.com", 0)
+			require.NoError(t, err)
+			release(errors.New("boom"))
+			require.Equal(t, BreakerOpen, breakerFor(t, b, "example.com").State)
+
+			// the deferred delivery is let through as the probe once the
+			// time provider is past the cooldown
+			timeProvider.On("Now").Return(opened.Add(time.Hour))
+			probe, err := b.acquire(context.Background(), "example.com", 0)
+			require.NoError(t, err)
+			require.Equal(t, BreakerHalfOpen, breakerFor(t, b, "example.com").State)
+
+			// only a single probe is in flight at a time
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			_, err = b.acquire(ctx, "example.com", 0)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			probe(tt.probeErr)
+			require.Equal(t, tt.expectedState, breakerFor(t, b, "example.com").State)
+		})
+	}
+}
+
+func TestBreakerConcurrencyLimit(t *testing.T) {
+	b := &breakers{maxConcurrent: 1}
+
+	release, err := b.acquire(context.Background(), "example.com", 0)
+	require.NoError(t, err)
+
+	acquired := make(chan func(error))
+	go func() {
+		next, err := b.acquire(context.Background(), "example.com", 0)
+		if err == nil {
+			acquired <- next
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		return breakerFor(t, b, "example.com").Deferred == 1
+	}, time.Second, time.Millisecond)
+
+	select {
+	case <-acquired:
+		t.Fatal("second delivery should wait for a free slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release(nil)
+	next := <-acquired
+	require.Equal(t, 1, breakerFor(t, b, "example.com").InFlight)
+	next(nil)
+	require.Equal(t, 0, breakerFor(t, b, "example.com").InFlight)
+}
+
+func TestBreakerWakesOnlyWaitersOfTheHost(t *testing.T) {
+	b := &breakers{maxConcurrent: 1}
+
+	release, err := b.acquire(context.Background(), "example.com", 0)
+	require.NoError(t, err)
+
+	go func() {
+		next, err := b.acquire(context.Background(), "example.com", 0)
+		if err == nil {
+			next(nil)
+		}
+	}()
+	require.Eventually(t, func() bool {
+		return breakerFor(t, b, "example.com").Deferred == 1
+	}, time.Second, time.Millisecond)
+
+	b.mu.Lock()
+	changed := b.host("example.com").wait()
+	b.mu.Unlock()
+
+	// deliveries to other hosts leave the waiter asleep
+	other, err := b.acquire(context.Background(), "other.com", 0)
+	require.NoError(t, err)
+	other(errors.New("connection refused"))
+	select {
+	case <-changed:
+		t.Fatal("a change of other.com should not wake waiters of example.com")
+	default:
+	}
+
+	release(nil)
+	<-changed
+}
+
+func TestBreakerPriorityOrder(t *testing.T) {
+	b := &breakers{maxConcurrent: 1}
+
+	release, err := b.acquire(context.Background(), "example.com", 0)
+	require.NoError(t, err)
+
+	waitFor := func(priority, deferred int) <-chan func(error) {
+		acquired := make(chan func(error), 1)
+		go func() {
+			next, err := b.acquire(context.Background(), "example.com", priority)
+			if err == nil {
+				acquired <- next
+			}
+		}()
+		require.Eventually(t, func() bool {
+			return breakerFor(t, b, "example.com").Deferred == deferred
+		}, time.Second, time.Millisecond)
+		return acquired
+	}
+
+	low := waitFor(0, 1)
+	high := waitFor(10, 2)
+
+	// the later, higher-priority delivery goes ahead of the waiting one
+	release(nil)
+	next := <-high
+	select {
+	case <-low:
+		t.Fatal("lower-priority delivery should wait behind the higher-priority one")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	next(nil)
+	last := <-low
+	last(nil)
+}
+
+func TestBreakerIgnoresClientErrors(t *testing.T) {
+	require.True(t, breakerFailure(errors.New("connection refused")))
+	require.True(t, breakerFailure(&statusError{code: http.StatusServiceUnavailable}))
+	require.False(t, breakerFailure(&statusError{code: http.StatusNotFound}))
+}
+
+func TestBreakerIgnoresAbandonedDeliveries(t *testing.T) {
+	opened := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeProvider := new(MockTimeProvider)
+	b := &breakers{threshold: 1, cooldown: time.Hour, timeProvider: timeProvider}
+
+	// a cancelled delivery does not count towards opening a closed breaker
+	ctx, cancel := context.WithCancel(context.Background())
+	release, err := b.acquire(ctx, "example.com", 0)
+	require.NoError(t, err)
+	cancel()
+	release(fmt.Errorf("post: %w", context.Canceled))
+	require.Equal(t, BreakerClosed, breakerFor(t, b, "example.com").State)
+	require.Zero(t, breakerFor(t, b, "example.com").Failures)
+
+	timeProvider.On("Now").Return(opened).Once()
+	release, err = b.acquire(context.Background(), "example.com", 0)
+	require.NoError(t, err)
+	release(errors.New("boom"))
+
+	// nor does a probe whose caller gave up close or re-open the breaker;
+	// its slot goes to the next probe
+	timeProvider.On("Now").Return(opened.Add(time.Hour))
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	probe, err := b.acquire(ctx, "example.com", 0)
+	require.NoError(t, err)
+	<-ctx.Done()
+	probe(ctx.Err())
+	require.Equal(t, BreakerHalfOpen, breakerFor(t, b, "example.com").State)
+	require.Equal(t, 1, breakerFor(t, b, "example.com").Failures)
+
+	probe, err = b.acquire(context.Background(), "example.com", 0)
+	require.NoError(t, err)
+	probe(nil)
+	require.Equal(t, BreakerClosed, breakerFor(t, b, "example.com").State)
+}
+
+func TestDeliveryDeferredByOpenBreaker(t *testing.T) {
+	svc, httpClient, _, _ := setupDeliveryService(t)
+	svc.deliveryAttempts = 1
+	svc.breakers = breakers{threshold: 1, cooldown: 30 * time.Millisecond}
+
+	httpClient.On("Do", mock.Anything).Return(nil, errors.New("connection refused")).Once()
+	httpClient.On("Do", mock.Anything).Return(okResponse(), nil)
+
+	// the first delivery fails and opens the breaker
+	_, err := svc.attemptDelivery(context.Background(), delivery{url: "http://example.com/retry"})
+	require.Error(t, err)
+	require.Equal(t, BreakerOpen, svc.Breakers()[0].State)
+
+	// the next one waits out the cooldown instead of being dropped
+	start := time.Now()
+	attempts, err := svc.attemptDelivery(context.Background(), delivery{url: "http://example.com/retry"})
+	require.NoError(t, err)
+	require.Equal(t, 1, attempts)
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	require.Equal(t, BreakerClosed, svc.Breakers()[0].State)
+}
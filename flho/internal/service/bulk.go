@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// maxBulkRuns bounds how many runs a bulk job applies its action to.
+	maxBulkRuns = 10000
+	// maxBulkJobs is how many bulk jobs are kept, the oldest being dropped
+	// first.
+	maxBulkJobs = 50
+)
+
+// ErrBulkJobNotFound is returned when a bulk job ID is unknown.
+var ErrBulkJobNotFound = errorf(ErrNotFound, "bulk job not found")
+
+// BulkAction is an action applied to many runs at once.
+type BulkAction string
+
+const (
+	// BulkCancel cancels runs, as CancelWorkflow does
+	BulkCancel BulkAction = "cancel"
+	// BulkResume resumes failed and timed out runs, as ResumeWorkflow does
+	BulkResume BulkAction = "resume"
+	// BulkAdvance moves runs on to their next step, as UpdateWorkflow does
+	BulkAdvance BulkAction = "advance"
+	// BulkComplete completes runs, as CompleteWorkflow does
+	BulkComplete BulkAction = "complete"
+)
+
+// BulkJobState represents the progress of a bulk job.
+type BulkJobState string
+
+const (
+	// BulkJobRunning represents a job still applying its action
+	BulkJobRunning BulkJobState = "running"
+	// BulkJobDone represents a job that went through all of its runs
+	BulkJobDone BulkJobState = "done"
+	// BulkJobStopped represents a job cut short by the service shutting down
+	BulkJobStopped BulkJobState = "stopped"
+)
+
+// BulkRequest selects runs, either by ID or by filter, and the action to
+// apply to them.
+type BulkRequest struct {
+	Action BulkAction
+	RunIDs []string
+	Filter *RunsFilter // matches the runs when RunIDs is empty; page and page size are ignored
+	Reason string      // passed on in the on_cancel callback of cancelled runs
+	DryRun bool        // reports what the action would do without applying it
+	// Namespaces restricts the action to runs of these namespaces, such as
+	// those of an API key, or nil for all. Runs of other namespaces are
+	// reported as not found.
+	Namespaces []string
+}
+
+// BulkResult is the outcome of a bulk action on one run.
+type BulkResult struct {
+	RunID  string    `json:"run_id"`
+	Status RunStatus `json:"status,omitempty"` // after the action, or before it in a dry run
+	Error  string    `json:"error,omitempty"`  // why the action was not, or would not be, applied
+}
+
+// BulkJob tracks a bulk action applied to its runs in the background.
+type BulkJob struct {
+	ID         string       `json:"id"`
+	Action     BulkAction   `json:"action"`
+	DryRun     bool         `json:"dry_run"`
+	State      BulkJobState `json:"state"`
+	Total      int          `json:"total"`
+	Succeeded  int          `json:"succeeded"`
+	Failed     int          `json:"failed"`
+	Actor      string       `json:"actor,omitempty"`   // who started the job, such as an API key ID
+	Results    []BulkResult `json:"results,omitempty"` // in the order the runs were selected
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+
+	namespaces []string // the runs the job may act on
+}
+
+// bulkJobs holds the most recent bulk jobs, oldest first.
+type bulkJobs struct {
+	mu   sync.Mutex
+	jobs []*BulkJob
+}
+
+// record adds the outcome of the action on one run to the job.
+func (b *bulkJobs) record(job *BulkJob, result BulkResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	job.Results = append(job.Results, result)
+	if result.Error == "" {
+		job.Succeeded++
+	} else {
+		job.Failed++
+	}
+}
+
+// finish marks the job as done, or stopped.
+func (b *bulkJobs) finish(job *BulkJob, state BulkJobState, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	job.State = state
+	job.FinishedAt = &now
+}
+
+// snapshot copies the job, so that it can be read while the job goes on.
+func (b *bulkJobs) snapshot(job *BulkJob) BulkJob {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snapshot := *job
+	snapshot.Results = slices.Clone(job.Results)
+	return snapshot
+}
+
+// StartBulk selects the runs of the request and applies its action to them in
+// the background, returning the job tracking it. A dry run checks each run
+// right away instead, and returns the job already done. The actor in ctx is
+// recorded as the cause of the job's changes to runs.
+func (w *WorkflowService) StartBulk(ctx context.Context, req BulkRequest) (BulkJob, error) {
+	switch req.Action {
+	case BulkCancel, BulkResume, BulkAdvance, BulkComplete:
+	default:
+		return BulkJob{}, errorf(ErrInvalid, "invalid bulk action %q", req.Action)
+	}
+
+	runIDs, err := w.bulkRunIDs(req)
+	if err != nil {
+		return BulkJob{}, err
+	}
+
+	job := &BulkJob{
+		ID:        w.uuidProvider.NewString(),
+		Action:    req.Action,
+		DryRun:    req.DryRun,
+		State:     BulkJobRunning,
+		Total:     len(runIDs),
+		Actor:     Actor(ctx),
+		CreatedAt: w.timeProvider.Now(),
+
+		namespaces: req.Namespaces,
+	}
+
+	w.bulk.mu.Lock()
+	if len(w.bulk.jobs) >= maxBulkJobs {
+		w.bulk.jobs = slices.Delete(w.bulk.jobs, 0, len(w.bulk.jobs)-maxBulkJobs+1)
+	}
+	w.bulk.jobs = append(w.bulk.jobs, job)
+	w.bulk.mu.Unlock()
+
+	if req.DryRun {
+		w.previewBulk(job, runIDs)
+		return w.bulk.snapshot(job), nil
+	}
+
+	w.logger.Info("started bulk job", "job_id", job.ID, "action", job.Action, "runs", job.Total, "actor", job.Actor)
+
+	w.wg.Add(1)
+	go w.runBulk(WithActor(w.lifetime(), job.Actor), job, runIDs, req.Reason)
+
+	return w.bulk.snapshot(job), nil
+}
+
+// bulkRunIDs returns the IDs of the runs the request selects, without
+// duplicates.
+func (w *WorkflowService) bulkRunIDs(req BulkRequest) ([]string, error) {
+	if (len(req.RunIDs) == 0) == (req.Filter == nil) {
+		return nil, errorf(ErrInvalid, "exactly one of run IDs and a filter must be given")
+	}
+
+	var runIDs []string
+	if req.Filter != nil {
+		if err := req.Filter.Validate(); err != nil {
+			return nil, err
+		}
+		filter := *req.Filter
+		if req.Namespaces != nil {
+			filter.Namespaces = req.Namespaces
+		}
+		for run := range w.AllRuns(filter) {
+			runIDs = append(runIDs, run.ID)
+			if len(runIDs) > maxBulkRuns {
+				break
+			}
+		}
+	} else {
+		seen := make(map[string]bool, len(req.RunIDs))
+		for _, runID := range req.RunIDs {
+			if !seen[runID] {
+				seen[runID] = true
+				runIDs = append(runIDs, runID)
+			}
+		}
+	}
+
+	if len(runIDs) > maxBulkRuns {
+		return nil, errorf(ErrInvalid, "a bulk action applies to at most %d runs", maxBulkRuns)
+	}
+
+	return runIDs, nil
+}
+
+// previewBulk reports, for every run, whether the job's action would apply to
+// it and why not, without applying it.
+func (w *WorkflowService) previewBulk(job *BulkJob, runIDs []string) {
+	w.mu.Lock()
+	for _, runID := range runIDs {
+		result := BulkResult{RunID: runID}
+		if run, ok := w.getRun(runID); !ok || !InNamespaces(run.namespace, job.namespaces) {
+			result.Error = fmt.Sprintf("no data found for run ID: %s", runID)
+		} else {
+			result.Status = run.status()
+			if err := checkBulk(job.Action, run); err != nil {
+				result.Error = err.Error()
+			}
+		}
+		w.bulk.record(job, result)
+	}
+	w.mu.Unlock()
+
+	w.bulk.finish(job, BulkJobDone, w.timeProvider.Now())
+}
+
+// checkBulk checks that the action can be applied to the run.
+func checkBulk(action BulkAction, run *Run) error {
+	switch action {
+	case BulkCancel:
+		return checkCancellable(run)
+	case BulkResume:
+		return checkResumable(run)
+	default:
+		return checkOngoing(run)
+	}
+}
+
+// runBulk applies the job's action to each run in turn, taking w.mu per run so
+// that runs keep progressing while the job goes on, until ctx is done.
+func (w *WorkflowService) runBulk(ctx context.Context, job *BulkJob, runIDs []string, reason string) {
+	defer w.wg.Done()
+
+	state := BulkJobDone
+	for _, runID := range runIDs {
+		if ctx.Err() != nil {
+			state = BulkJobStopped
+			break
+		}
+
+		var err error
+		switch {
+		case !w.runInNamespaces(runID, job.namespaces):
+			err = runNotFound(runID)
+		case job.Action == BulkCancel:
+			err = w.CancelWorkflow(ctx, runID, reason)
+		case job.Action == BulkResume:
+			err = w.ResumeWorkflow(ctx, runID)
+		case job.Action == BulkAdvance:
+			err = w.UpdateWorkflow(ctx, runID)
+		case job.Action == BulkComplete:
+			err = w.CompleteWorkflow(ctx, runID)
+		}
+
+		result := BulkResult{RunID: runID}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		if run, err := w.GetRun(runID); err == nil && InNamespaces(run.Namespace, job.namespaces) {
+			result.Status = run.Status
+		}
+		w.bulk.record(job, result)
+	}
+
+	w.bulk.finish(job, state, w.timeProvider.Now())
+
+	snapshot := w.bulk.snapshot(job)
+	w.logger.Info("finished bulk job", "job_id", job.ID, "state", state, "succeeded", snapshot.Succeeded, "failed", snapshot.Failed)
+}
+
+// runInNamespaces reports whether the run exists and belongs to one of the
+// namespaces.
+func (w *WorkflowService) runInNamespaces(runID string, namespaces []string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, ok := w.getRun(runID)
+	return ok && InNamespaces(run.namespace, namespaces)
+}
+
+// BulkJob returns the bulk job with the given ID, with the result of every
+// run processed so far.
+func (w *WorkflowService) BulkJob(id string) (BulkJob, error) {
+	w.bulk.mu.Lock()
+	i := slices.IndexFunc(w.bulk.jobs, func(job *BulkJob) bool { return job.ID == id })
+	var job *BulkJob
+	if i >= 0 {
+		job = w.bulk.jobs[i]
+	}
+	w.bulk.mu.Unlock()
+
+	if job == nil {
+		return BulkJob{}, ErrBulkJobNotFound
+	}
+	return w.bulk.snapshot(job), nil
+}
+
+// BulkJobs returns the most recent bulk jobs, newest first, without their
+// per-run results.
+func (w *WorkflowService) BulkJobs() []BulkJob {
+	w.bulk.mu.Lock()
+	defer w.bulk.mu.Unlock()
+
+	jobs := make([]BulkJob, 0, len(w.bulk.jobs))
+	for _, job := range slices.Backward(w.bulk.jobs) {
+		snapshot := *job
+		snapshot.Results = nil
+		jobs = append(jobs, snapshot)
+	}
+	return jobs
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setupBulkService starts five export runs and fails the first three. Bulk
+// jobs are numbered from job-1.
+func setupBulkService(t *testing.T) *WorkflowService {
+	svc := setupQueueService(t, 0)
+	for range 5 {
+		svc.InitiateWorkflow(context.Background(), "export")
+	}
+	for _, runID := range []string{"run-1", "run-2", "run-3"} {
+		svc.markRunAsFailed(runID)
+	}
+
+	for i := 1; i <= maxBulkJobs+1; i++ {
+		svc.uuidProvider.(*MockUUIDProvider).On("NewString").Return(fmt.Sprintf("job-%d", i)).Once()
+	}
+
+	return svc
+}
+
+func waitForBulkJob(t *testing.T, svc *WorkflowService, id string) BulkJob {
+	t.Helper()
+
+	var job BulkJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = svc.BulkJob(id)
+		require.NoError(t, err)
+		return job.State != BulkJobRunning
+	}, time.Second, time.Millisecond)
+
+	return job
+}
+
+func TestStartBulk(t *testing.T) {
+	failed := &RunsFilter{Status: string(RunStatusFailed), Order: RunsOrderAsc}
+
+	t.Run("dry run previews the runs without changing them", func(t *testing.T) {
+		svc := setupBulkService(t)
+
+		job, err := svc.StartBulk(context.Background(), BulkRequest{Action: BulkResume, RunIDs: []string{"run-1", "run-4", "run-1", "missing"}, DryRun: true})
+		require.NoError(t, err)
+		require.Equal(t, BulkJobDone, job.State)
+		require.Equal(t, 3, job.Total)
+		require.Equal(t, 1, job.Succeeded)
+		require.Equal(t, 2, job.Failed)
+		require.Equal(t, []BulkResult{
+			{RunID: "run-1", Status: RunStatusFailed},
+			{RunID: "run-4", Status: RunStatusOngoing, Error: "run is ongoing, only failed or timed out runs can be resumed"},
+			{RunID: "missing", Error: "no data found for run ID: missing"},
+		}, job.Results)
+
+		requireStatus(t, svc, "run-1", RunStatusFailed)
+	})
+
+	t.Run("resume runs matching a filter", func(t *testing.T) {
+		svc := setupBulkService(t)
+
+		job, err := svc.StartBulk(context.Background(), BulkRequest{Action: BulkResume, Filter: failed})
+		require.NoError(t, err)
+		require.Equal(t, 3, job.Total)
+
+		job = waitForBulkJob(t, svc, job.ID)
+		require.Equal(t, BulkJobDone, job.State)
+		require.Equal(t, 3, job.Succeeded)
+		require.NotNil(t, job.FinishedAt)
+		for i, runID := range []string{"run-1", "run-2", "run-3"} {
+			require.Equal(t, BulkResult{RunID: runID, Status: RunStatusOngoing}, job.Results[i])
+		}
+		require.Empty(t, runIDs(svc.GetRuns(*failed)))
+	})
+
+	t.Run("each run reports its own outcome", func(t *testing.T) {
+		svc := setupBulkService(t)
+
+		job, err := svc.StartBulk(context.Background(), BulkRequest{Action: BulkCancel, RunIDs: []string{"run-1", "run-4"}, Reason: "duplicate order"})
+		require.NoError(t, err)
+
+		job = waitForBulkJob(t, svc, job.ID)
+		require.Equal(t, []BulkResult{
+			{RunID: "run-1", Status: RunStatusFailed, Error: "run is already failed"},
+			{RunID: "run-4", Status: RunStatusCancelled},
+		}, job.Results)
+		requireStatus(t, svc, "run-5", RunStatusOngoing)
+	})
+
+	t.Run("advance and complete", func(t *testing.T) {
+		svc := setupBulkService(t)
+
+		job, err := svc.StartBulk(context.Background(), BulkRequest{Action: BulkComplete, Filter: &RunsFilter{Status: string(RunStatusOngoing)}})
+		require.NoError(t, err)
+		require.Equal(t, 2, waitForBulkJob(t, svc, job.ID).Succeeded)
+
+		job, err = svc.StartBulk(context.Background(), BulkRequest{Action: BulkAdvance, Filter: &RunsFilter{}})
+		require.NoError(t, err)
+		require.Equal(t, 5, waitForBulkJob(t, svc, job.ID).Failed)
+	})
+
+	t.Run("runs of other namespaces are not found", func(t *testing.T) {
+		svc := setupBulkService(t)
+		others := []string{"billing"}
+
+		job, err := svc.StartBulk(context.Background(), BulkRequest{Action: BulkCancel, RunIDs: []string{"run-4"}, DryRun: true, Namespaces: others})
+		require.NoError(t, err)
+		require.Equal(t, []BulkResult{{RunID: "run-4", Error: "no data found for run ID: run-4"}}, job.Results)
+
+		job, err = svc.StartBulk(context.Background(), BulkRequest{Action: BulkCancel, RunIDs: []string{"run-4"}, Namespaces: others})
+		require.NoError(t, err)
+		require.Equal(t, []BulkResult{{RunID: "run-4", Error: "no data found for run ID: run-4"}}, waitForBulkJob(t, svc, job.ID).Results)
+		requireStatus(t, svc, "run-4", RunStatusOngoing)
+
+		job, err = svc.StartBulk(context.Background(), BulkRequest{Action: BulkCancel, Filter: &RunsFilter{}, Namespaces: others})
+		require.NoError(t, err)
+		require.Zero(t, job.Total)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		svc := setupBulkService(t)
+
+		for _, req := range []BulkRequest{
+			{Action: "restart", RunIDs: []string{"run-1"}},
+			{Action: BulkCancel},
+			{Action: BulkCancel, RunIDs: []string{"run-1"}, Filter: failed},
+			{Action: BulkCancel, Filter: &RunsFilter{Sort: "name"}},
+		} {
+			_, err := svc.StartBulk(context.Background(), req)
+			require.Error(t, err)
+		}
+		require.Empty(t, svc.BulkJobs())
+	})
+}
+
+func TestBulkJobs(t *testing.T) {
+	svc := setupBulkService(t)
+
+	for range maxBulkJobs + 1 {
+		_, err := svc.StartBulk(context.Background(), BulkRequest{Action: BulkCancel, RunIDs: []string{"run-1"}, DryRun: true})
+		require.NoError(t, err)
+	}
+
+	jobs := svc.BulkJobs()
+	require.Len(t, jobs, maxBulkJobs)
+	require.Equal(t, "job-51", jobs[0].ID)
+	require.Nil(t, jobs[0].Results)
+
+	// the oldest job made way for the newest
+	_, err := svc.BulkJob("job-1")
+	require.ErrorIs(t, err, ErrBulkJobNotFound)
+
+	job, err := svc.BulkJob("job-51")
+	require.NoError(t, err)
+	require.Len(t, job.Results, 1)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+// Reasons a run finished because of a run it is linked to, reported in
+// lifecycle callbacks.
+const (
+	reasonChildFailed = "child_workflow_failed"
+	reasonParentEnded = "parent_run_ended"
+)
+
+// startChild starts a run of the named child workflow for the run's step at
+// index, linking the two runs. Nothing is started if the run has moved on or
+// finished in the meantime.
+func (w *WorkflowService) startChild(ctx context.Context, runID string, index int, name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, ok := w.getRun(runID)
+	if !ok {
+		return
+	}
+	if run.status() != RunStatusOngoing || run.currStep != index {
+		return
+	}
+
+	// the child inherits the parent's priority, since the parent waits on
+	// it, and its labels, so that a search for the parent finds it too
+	opts := RunOptions{Priority: run.priority, Labels: run.labels}
+	childID := w.startRun(ctx, name, opts, func(child *Run) {
+		child.parentRunID = runID
+		child.parentStep = index
+	})
+
+	run.children = append(run.children, childID)
+	w.saveRun(runID, run)
+
+	w.logger.Info("started child workflow", "run_id", runID, "child_run_id", childID, "workflow_name", name)
+}
+
+// resumeParent carries a finished child run's outcome over to its parent: the
+// parent advances when the child completed, and otherwise fails or advances
+// according to the step's failure policy. A parent that completes its last
+// step this way is completed. The caller must hold w.mu.
+func (w *WorkflowService) resumeParent(run *Run, status RunStatus) {
+	if run.parentRunID == "" {
+		return
+	}
+
+	parent, ok := w.getRun(run.parentRunID)
+	if !ok {
+		return
+	}
+
+	// the parent may have been moved on by hand, or finished, while the
+	// child was running
+	if parent.status() != RunStatusOngoing || parent.currStep != run.parentStep {
+		return
+	}
+
+	wf := w.config.GetWorkflows()[parent.workflowName]
+	step := wf[parent.currStep][fmt.Sprintf("step%v", parent.currStep)]
+
+	if status != RunStatusCompleted && step.OnChildFailure != workflow.ChildFailureContinue {
+		w.finishRun(w.lifetime(), run.parentRunID, parent, RunStatusFailed, reasonChildFailed)
+		return
+	}
+
+	if parent.currStep+1 >= len(wf) {
+		w.finishRun(w.lifetime(), run.parentRunID, parent, RunStatusCompleted, "")
+		return
+	}
+
+	w.advance(w.lifetime(), run.parentRunID, parent)
+}
+
+// cancelChildren cancels the run's child runs that have not finished, whether
+// ongoing, queued or scheduled, as nothing is left to wait for them. The
+// caller must hold w.mu.
+func (w *WorkflowService) cancelChildren(run *Run) {
+	for _, childID := range run.children {
+		child, ok := w.getRun(childID)
+		if !ok {
+			continue
+		}
+		if child.end == nil {
+			w.finishRun(w.lifetime(), childID, child, RunStatusCancelled, reasonParentEnded)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func setupChildService(t *testing.T, steps workflow.Workflow) *WorkflowService {
+	svc, _, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(workflow.Workflows{
+		"onboarding": steps,
+		"verify": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		},
+	}, nil)
+
+	uuidProvider.On("NewString").Return("parent-run-id").Once()
+	uuidProvider.On("NewString").Return("child-run-id")
+	timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	return svc
+}
+
+// startParent initiates the parent run and waits for its first step to start
+// the child run.
+func startParent(t *testing.T, svc *WorkflowService) string {
+	t.Helper()
+
+	runID := svc.InitiateWorkflow(context.Background(), "onboarding")
+	require.Eventually(t, func() bool {
+		run, err := svc.GetRun(runID)
+		return err == nil && len(run.ChildRunIDs) == 1
+	}, time.Second, time.Millisecond)
+
+	return runID
+}
+
+func TestChildWorkflow(t *testing.T) {
+	awaitChild := workflow.Workflow{
+		{"step0": {Workflow: "verify"}},
+		{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+	}
+
+	t.Run("parent and child runs are linked", func(t *testing.T) {
+		svc := setupChildService(t, awaitChild)
+		runID := startParent(t, svc)
+
+		parent, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"child-run-id"}, parent.ChildRunIDs)
+
+		child, err := svc.GetRun("child-run-id")
+		require.NoError(t, err)
+		require.Equal(t, "verify", child.WorkflowName)
+		require.Equal(t, runID, child.ParentRunID)
+		require.Equal(t, RunStatusOngoing, child.Status)
+	})
+
+	t.Run("completed child advances the parent", func(t *testing.T) {
+		svc := setupChildService(t, awaitChild)
+		runID := startParent(t, svc)
+
+		require.NoError(t, svc.CompleteWorkflow(context.Background(), "child-run-id"))
+
+		parent, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		require.Equal(t, RunStatusOngoing, parent.Status)
+		require.Equal(t, 1, parent.CurrentStep)
+	})
+
+	t.Run("completed child at the last step completes the parent", func(t *testing.T) {
+		svc := setupChildService(t, workflow.Workflow{
+			{"step0": {Workflow: "verify"}},
+		})
+		runID := startParent(t, svc)
+
+		require.NoError(t, svc.CompleteWorkflow(context.Background(), "child-run-id"))
+
+		parent, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		require.Equal(t, RunStatusCompleted, parent.Status)
+	})
+
+	t.Run("failed child fails the parent by default", func(t *testing.T) {
+		svc := setupChildService(t, awaitChild)
+		runID := startParent(t, svc)
+
+		require.NoError(t, svc.CancelWorkflow(context.Background(), "child-run-id", ""))
+
+		parent, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		require.Equal(t, RunStatusFailed, parent.Status)
+		require.Equal(t, 0, parent.CurrentStep)
+	})
+
+	t.Run("failed child advances the parent when the policy is continue", func(t *testing.T) {
+		svc := setupChildService(t, workflow.Workflow{
+			{"step0": {Workflow: "verify", OnChildFailure: workflow.ChildFailureContinue}},
+			{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		})
+		runID := startParent(t, svc)
+
+		require.NoError(t, svc.CancelWorkflow(context.Background(), "child-run-id", ""))
+
+		parent, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		require.Equal(t, RunStatusOngoing, parent.Status)
+		require.Equal(t, 1, parent.CurrentStep)
+	})
+
+	t.Run("ending the parent cancels the child", func(t *testing.T) {
+		svc := setupChildService(t, awaitChild)
+		runID := startParent(t, svc)
+
+		require.NoError(t, svc.CancelWorkflow(context.Background(), runID, ""))
+		waitForRuns(t, svc)
+
+		child, err := svc.GetRun("child-run-id")
+		require.NoError(t, err)
+		require.Equal(t, RunStatusCancelled, child.Status)
+	})
+
+	t.Run("ending the parent cancels a queued child", func(t *testing.T) {
+		svc, _, uuidProvider, timeProvider := setupDeliveryService(t)
+		svc.config = workflow.NewConfigStore(workflow.Workflows{
+			"onboarding": awaitChild,
+			"verify": {
+				{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			},
+		}, map[string]workflow.Settings{"verify": {MaxConcurrentRuns: 1}})
+		uuidProvider.On("NewString").Return("blocker-run-id").Once()
+		uuidProvider.On("NewString").Return("parent-run-id").Once()
+		uuidProvider.On("NewString").Return("child-run-id")
+		timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+
+		// a verify run started by hand takes the only slot
+		svc.InitiateWorkflow(context.Background(), "verify")
+		runID := startParent(t, svc)
+		requireStatus(t, svc, "child-run-id", RunStatusQueued)
+
+		require.NoError(t, svc.CancelWorkflow(context.Background(), runID, ""))
+		requireStatus(t, svc, "child-run-id", RunStatusCancelled)
+
+		// and the child does not start once the slot frees up
+		require.NoError(t, svc.CompleteWorkflow(context.Background(), "blocker-run-id"))
+		requireStatus(t, svc, "child-run-id", RunStatusCancelled)
+		require.Empty(t, svc.Queues())
+	})
+
+	t.Run("parent moved on by hand ignores the child", func(t *testing.T) {
+		svc := setupChildService(t, awaitChild)
+		runID := startParent(t, svc)
+
+		require.NoError(t, svc.UpdateWorkflow(context.Background(), runID))
+		require.NoError(t, svc.CancelWorkflow(context.Background(), "child-run-id", ""))
+
+		parent, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		require.Equal(t, RunStatusOngoing, parent.Status)
+		require.Equal(t, 1, parent.CurrentStep)
+	})
+}
+
+func TestGetRunNotFound(t *testing.T) {
+	svc, _, _, _ := setupService(t)
+
+	_, err := svc.GetRun("missing-run-id")
+	require.EqualError(t, err, "no data found for run ID: missing-run-id")
+}
+
+func TestChildWorkflowUnderNamespaceQuota(t *testing.T) {
+	svc, _, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(
+		workflow.Workflows{
+			"onboarding": {
+				{"step0": {Workflow: "verify"}},
+				{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			},
+			"verify": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+		},
+		map[string]workflow.Settings{"onboarding": {Namespace: "accounts"}, "verify": {Namespace: "accounts"}},
+	).WithNamespaces(map[string]workflow.Namespace{"accounts": {MaxActiveRuns: 1}})
+
+	uuidProvider.On("NewString").Return("parent-run-id").Once()
+	uuidProvider.On("NewString").Return("child-run-id").Once()
+	uuidProvider.On("NewString").Return("other-run-id").Once()
+	timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	// the parent holds the namespace's only slot while it waits on the child,
+	// so the child does not wait for a slot of its own
+	runID := startParent(t, svc)
+	requireStatus(t, svc, "child-run-id", RunStatusOngoing)
+
+	runs := svc.GetRuns(RunsFilter{})
+	require.Equal(t, []QuotaStatus{{Namespace: "accounts", Active: 1, Limit: 1}}, runs.Quotas)
+
+	// other runs of the namespace still wait for the parent's slot
+	svc.InitiateWorkflow(context.Background(), "verify")
+	requireStatus(t, svc, "other-run-id", RunStatusQueued)
+
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "child-run-id"))
+	parent, err := svc.GetRun(runID)
+	require.NoError(t, err)
+	require.Equal(t, RunStatusOngoing, parent.Status)
+	require.Equal(t, 1, parent.CurrentStep)
+	requireStatus(t, svc, "other-run-id", RunStatusQueued)
+
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), runID))
+	requireStatus(t, svc, "other-run-id", RunStatusOngoing)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CompensationPhase represents the progress of undoing a run's completed steps.
+type CompensationPhase string
+
+const (
+	// CompensationRunning represents a run whose compensations are being called
+	CompensationRunning CompensationPhase = "compensating"
+	// CompensationDone represents a run whose completed steps were all compensated
+	CompensationDone CompensationPhase = "compensated"
+	// CompensationFailed represents a run with a compensation that could not be delivered
+	CompensationFailed CompensationPhase = "compensation_failed"
+)
+
+// Compensation tracks the undoing of a failed, timed out or cancelled run's
+// completed steps.
+type Compensation struct {
+	Phase     CompensationPhase `json:"phase"`
+	Steps     []string          `json:"steps"`     // steps being compensated, in the order they are called
+	Completed int               `json:"completed"` // how many of Steps have been compensated
+	Error     string            `json:"error,omitempty"`
+}
+
+// compensationStep is a completed step with a compensation URL.
+type compensationStep struct {
+	name string
+	url  string
+}
+
+// compensationSteps returns the run's completed steps that declare a
+// compensation URL, latest first. The caller must hold w.mu.
+func (w *WorkflowService) compensationSteps(run *Run) []compensationStep {
+	wf := w.config.GetWorkflows()[run.workflowName]
+
+	var steps []compensationStep
+	for index := min(run.currStep, len(wf)) - 1; index >= 0; index-- {
+		name := fmt.Sprintf("step%v", index)
+		if url := wf[index][name].CompensateURL; url != "" {
+			steps = append(steps, compensationStep{name: name, url: url})
+		}
+	}
+
+	return steps
+}
+
+// startCompensation begins undoing the run's completed steps, if any declare
+// a compensation URL. The caller must hold w.mu.
+func (w *WorkflowService) startCompensation(runID string, run *Run, status RunStatus) {
+	steps := w.compensationSteps(run)
+	if len(steps) == 0 {
+		return
+	}
+
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.name
+	}
+	run.compensation = &Compensation{Phase: CompensationRunning, Steps: names}
+	w.saveRun(runID, run)
+
+	w.wg.Add(1)
+	go w.compensate(w.runSpanContext(w.lifetime(), run), runID, run.workflowName, run.priority, steps, status)
+}
+
+// compensate calls each step's compensation URL in turn, through the usual
+// delivery retries. Later steps may depend on earlier ones, so compensation
+// stops at the first one that cannot be delivered; the undelivered request is
+// left in the dead-letter queue to be replayed.
+func (w *WorkflowService) compensate(ctx context.Context, runID, name string, priority int, steps []compensationStep, status RunStatus) {
+	defer w.wg.Done()
+
+	for i, step := range steps {
+		compensationData := struct {
+			WorkflowName  string    `json:"workflow_name"`
+			WorkflowStep  string    `json:"workflow_step"`
+			WorkflowRunID string    `json:"workflow_run_id"`
+			RunStatus     RunStatus `json:"run_status"`
+		}{
+			WorkflowName:  name,
+			WorkflowStep:  step.name,
+			WorkflowRunID: runID,
+			RunStatus:     status,
+		}
+
+		jsonData, _ := json.Marshal(compensationData)
+
+		err := w.deliver(ctx, delivery{
+			runID:        runID,
+			workflowName: name,
+			step:         step.name,
+			url:          step.url,
+			body:         jsonData,
+			priority:     priority,
+		})
+
+		w.mu.Lock()
+		run, ok := w.getRun(runID)
+		if !ok {
+			w.mu.Unlock()
+			return
+		}
+
+		if err != nil {
+			run.compensation.Phase = CompensationFailed
+			run.compensation.Error = err.Error()
+			w.saveRun(runID, run)
+			w.mu.Unlock()
+
+			w.logger.Error("compensation unsuccessful", "run_id", runID, "step", step.name, "error", err.Error())
+			return
+		}
+
+		run.compensation.Completed = i + 1
+		if run.compensation.Completed == len(steps) {
+			run.compensation.Phase = CompensationDone
+		}
+		w.saveRun(runID, run)
+		w.mu.Unlock()
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func setupCompensationService(t *testing.T) (*WorkflowService, *MockHTTPClient) {
+	svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(workflow.Workflows{"payments": {
+		{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry", CompensateURL: "http://example.com/undo/step0"}},
+		{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		{"step2": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry", CompensateURL: "http://example.com/undo/step2"}},
+		{"step3": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry", CompensateURL: "http://example.com/undo/step3"}},
+	}}, nil)
+
+	uuidProvider.On("NewString").Return("payments-run-id")
+	timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	return svc, httpClient
+}
+
+// recordCalls records the paths of the requests made through the mock client.
+func recordCalls(httpClient *MockHTTPClient, failPath string) func() []string {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+
+	record := func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, args.Get(0).(*http.Request).URL.Path)
+	}
+
+	httpClient.On("Do", mock.MatchedBy(func(r *http.Request) bool {
+		return r.URL.Path == failPath
+	})).Run(record).Return(nil, errors.New("connection refused"))
+	httpClient.On("Do", mock.Anything).Run(record).Return(okResponse(), nil)
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), calls...)
+	}
+}
+
+func TestCompensation(t *testing.T) {
+	t.Run("completed steps are compensated in reverse order", func(t *testing.T) {
+		svc, httpClient := setupCompensationService(t)
+		calls := recordCalls(httpClient, "")
+
+		runID := svc.InitiateWorkflow(context.Background(), "payments")
+		for range 3 {
+			require.NoError(t, svc.UpdateWorkflow(context.Background(), runID))
+		}
+		// step3 is current and has not completed, so it is not compensated
+		require.NoError(t, svc.CancelWorkflow(context.Background(), runID, ""))
+		waitForRuns(t, svc)
+
+		require.Equal(t, []string{"/undo/step2", "/undo/step0"}, calls())
+
+		runs := svc.GetRuns(RunsFilter{Page: 1, PageSize: 10})
+		require.Equal(t, &Compensation{
+			Phase:     CompensationDone,
+			Steps:     []string{"step2", "step0"},
+			Completed: 2,
+		}, runs.Runs[0].Compensation)
+	})
+
+	t.Run("compensation stops at an undeliverable step", func(t *testing.T) {
+		svc, httpClient := setupCompensationService(t)
+		calls := recordCalls(httpClient, "/undo/step2")
+
+		runID := svc.InitiateWorkflow(context.Background(), "payments")
+		for range 3 {
+			require.NoError(t, svc.UpdateWorkflow(context.Background(), runID))
+		}
+		svc.markRunAsFailed(runID)
+		waitForRuns(t, svc)
+
+		// step2 is attempted until its retries run out, step0 is never called
+		require.Equal(t, []string{"/undo/step2", "/undo/step2"}, calls())
+
+		runs := svc.GetRuns(RunsFilter{Page: 1, PageSize: 10})
+		compensation := runs.Runs[0].Compensation
+		require.Equal(t, CompensationFailed, compensation.Phase)
+		require.Equal(t, 0, compensation.Completed)
+		require.Contains(t, compensation.Error, "connection refused")
+
+		// the undelivered compensation can be replayed from the dead-letter queue
+		deadLetters := svc.DeadLetters()
+		require.Len(t, deadLetters, 1)
+		require.Equal(t, "http://example.com/undo/step2", deadLetters[0].URL)
+	})
+
+	t.Run("completed runs are not compensated", func(t *testing.T) {
+		svc, httpClient := setupCompensationService(t)
+		calls := recordCalls(httpClient, "")
+
+		runID := svc.InitiateWorkflow(context.Background(), "payments")
+		require.NoError(t, svc.UpdateWorkflow(context.Background(), runID))
+		require.NoError(t, svc.CompleteWorkflow(context.Background(), runID))
+		waitForRuns(t, svc)
+
+		require.Empty(t, calls())
+		runs := svc.GetRuns(RunsFilter{Page: 1, PageSize: 10})
+		require.Nil(t, runs.Runs[0].Compensation)
+	})
+
+	t.Run("runs failing at the first step have nothing to compensate", func(t *testing.T) {
+		svc, httpClient := setupCompensationService(t)
+		calls := recordCalls(httpClient, "")
+
+		runID := svc.InitiateWorkflow(context.Background(), "payments")
+		require.NoError(t, svc.CancelWorkflow(context.Background(), runID, ""))
+		waitForRuns(t, svc)
+
+		require.Empty(t, calls())
+	})
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// deadLettersKey is the genie store key the dead-letter queue is persisted under.
+const deadLettersKey = "flho:deadletters"
+
+// ErrDeadLetterNotFound is returned when a dead letter ID is unknown.
+var ErrDeadLetterNotFound = errorf(ErrNotFound, "dead letter not found")
+
+// DeadLetter records a notification that could not be delivered, together
+// with the full request so it can be replayed later.
+type DeadLetter struct {
+	ID            string            `json:"id"`
+	RunID         string            `json:"run_id"`
+	WorkflowName  string            `json:"workflow_name"`
+	Step          string            `json:"step"`
+	Method        string            `json:"method"`
+	URL           string            `json:"url"`
+	Headers       map[string]string `json:"headers"`
+	Body          string            `json:"body"`
+	Error         string            `json:"error"`
+	StatusCode    int               `json:"status_code,omitempty"`
+	Attempts      int               `json:"attempts"`
+	CreatedAt     time.Time         `json:"created_at"`
+	LastAttemptAt time.Time         `json:"last_attempt_at"`
+	Priority      int               `json:"priority,omitempty"` // of the run, kept for replays
+}
+
+// delivery rebuilds the notification held by the dead letter.
+func (dl DeadLetter) delivery() delivery {
+	return delivery{
+		runID:        dl.RunID,
+		workflowName: dl.WorkflowName,
+		step:         dl.Step,
+		url:          dl.URL,
+		headers:      dl.Headers,
+		body:         []byte(dl.Body),
+		priority:     dl.Priority,
+	}
+}
+
+// deadLetterQueue holds undeliverable notifications, oldest first. It is
+// loaded lazily from the store and written back after every change so the
+// queue survives restarts through genie backups.
+type deadLetterQueue struct {
+	mu        sync.Mutex
+	loaded    bool
+	entries   []DeadLetter
+	replaying bool // whether ReplayDeadLetters is going through the queue
+}
+
+// decodeStored converts a value read from the genie store into target.
+// Values set during this process keep their Go type, but those restored from
+// a backup come back as generic JSON maps and slices, so the value is
+// round-tripped through JSON either way.
+func decodeStored(value, target any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// lockDeadLetters locks the queue, loading it from the store on first use.
+func (w *WorkflowService) lockDeadLetters() *deadLetterQueue {
+	q := &w.deadLetters
+	q.mu.Lock()
+
+	if !q.loaded {
+		if v, ok := w.store.Get(deadLettersKey); ok {
+			if err := decodeStored(v, &q.entries); err != nil {
+				w.logger.Error("failed to restore dead letters", "error", err.Error())
+			}
+		}
+		q.loaded = true
+	}
+
+	return q
+}
+
+// persistDeadLetters writes the queue back to the store. The caller must hold
+// q.mu.
+func (w *WorkflowService) persistDeadLetters(q *deadLetterQueue) {
+	entries := make([]DeadLetter, len(q.entries))
+	copy(entries, q.entries)
+	w.store.Set(deadLettersKey, entries)
+}
+
+// deadLetter moves an undeliverable notification to the dead-letter queue.
+func (w *WorkflowService) deadLetter(d delivery, attempts int, err error) {
+	now := w.timeProvider.Now()
+	dl := DeadLetter{
+		ID:            w.uuidProvider.NewString(),
+		RunID:         d.runID,
+		WorkflowName:  d.workflowName,
+		Step:          d.step,
+		Method:        "POST",
+		URL:           d.url,
+		Headers:       d.header(),
+		Body:          string(d.body),
+		Error:         err.Error(),
+		StatusCode:    responseStatus(err),
+		Attempts:      attempts,
+		CreatedAt:     now,
+		LastAttemptAt: now,
+		Priority:      d.priority,
+	}
+
+	q := w.lockDeadLetters()
+	defer q.mu.Unlock()
+
+	q.entries = append(q.entries, dl)
+	w.persistDeadLetters(q)
+
+	w.logger.Warn("notification moved to dead-letter queue", "dead_letter_id", dl.ID, "run_id", dl.RunID, "url", dl.URL)
+}
+
+// DeadLetters returns every dead-lettered notification, newest first.
+func (w *WorkflowService) DeadLetters() []DeadLetter {
+	q := w.lockDeadLetters()
+	defer q.mu.Unlock()
+
+	entries := make([]DeadLetter, 0, len(q.entries))
+	for i := len(q.entries) - 1; i >= 0; i-- {
+		entries = append(entries, q.entries[i])
+	}
+
+	return entries
+}
+
+// ReplayDeadLetter redelivers a single dead-lettered notification. It is
+// removed from the queue on success; otherwise its attempt count and error
+// are updated and it stays queued.
+func (w *WorkflowService) ReplayDeadLetter(ctx context.Context, id string) error {
+	q := w.lockDeadLetters()
+	var (
+		dl    DeadLetter
+		found bool
+	)
+	for _, e := range q.entries {
+		if e.ID == id {
+			dl, found = e, true
+			break
+		}
+	}
+	q.mu.Unlock()
+
+	if !found {
+		return ErrDeadLetterNotFound
+	}
+
+	attempts, err := w.attemptDelivery(ctx, dl.delivery())
+
+	q = w.lockDeadLetters()
+	defer q.mu.Unlock()
+
+	for i, e := range q.entries {
+		if e.ID != id {
+			continue
+		}
+		if err == nil {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+		} else {
+			q.entries[i].Attempts += attempts
+			q.entries[i].Error = err.Error()
+			q.entries[i].StatusCode = responseStatus(err)
+			q.entries[i].LastAttemptAt = w.timeProvider.Now()
+		}
+		w.persistDeadLetters(q)
+		break
+	}
+
+	return err
+}
+
+// StartDeadLetterReplay redelivers a single dead-lettered notification in the
+// background, since it may wait out its host's breaker and take several
+// attempts, and returns ErrDeadLetterNotFound if there is no such dead letter.
+// The replay runs until it is over or the service shuts down.
+func (w *WorkflowService) StartDeadLetterReplay(id string) error {
+	q := w.lockDeadLetters()
+	defer q.mu.Unlock()
+
+	if !slices.ContainsFunc(q.entries, func(e DeadLetter) bool { return e.ID == id }) {
+		return ErrDeadLetterNotFound
+	}
+
+	ctx := w.lifetime()
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		err := w.ReplayDeadLetter(ctx, id)
+		if err != nil && !errors.Is(err, ErrDeadLetterNotFound) {
+			w.logger.Warn("dead letter replay failed", "dead_letter_id", id, "error", err.Error())
+		}
+	}()
+
+	return nil
+}
+
+// ReplayDeadLetters redelivers every dead-lettered notification in the
+// background, since each one may take several attempts, and returns how many
+// are being replayed. Dead letters are replayed one at a time, oldest first,
+// until the service shuts down. While a replay is going on, another one is
+// not started and zero is returned.
+func (w *WorkflowService) ReplayDeadLetters() int {
+	q := w.lockDeadLetters()
+	defer q.mu.Unlock()
+
+	if q.replaying || len(q.entries) == 0 {
+		return 0
+	}
+	q.replaying = true
+
+	ids := make([]string, len(q.entries))
+	for i, e := range q.entries {
+		ids[i] = e.ID
+	}
+
+	w.wg.Add(1)
+	go w.replayDeadLetters(w.lifetime(), ids)
+
+	return len(ids)
+}
+
+// replayDeadLetters redelivers the dead letters with the given IDs, skipping
+// those purged or replayed in the meantime.
+func (w *WorkflowService) replayDeadLetters(ctx context.Context, ids []string) {
+	defer w.wg.Done()
+
+	var replayed, failed int
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		switch err := w.ReplayDeadLetter(ctx, id); {
+		case errors.Is(err, ErrDeadLetterNotFound):
+		case err != nil:
+			failed++
+		default:
+			replayed++
+		}
+	}
+
+	q := w.lockDeadLetters()
+	q.replaying = false
+	q.mu.Unlock()
+
+	w.logger.Info("replayed dead letters", "replayed", replayed, "failed", failed)
+}
+
+// ReplayingDeadLetters reports whether ReplayDeadLetters is still going
+// through the queue.
+func (w *WorkflowService) ReplayingDeadLetters() bool {
+	q := w.lockDeadLetters()
+	defer q.mu.Unlock()
+
+	return q.replaying
+}
+
+// PurgeDeadLetter discards a single dead-lettered notification.
+func (w *WorkflowService) PurgeDeadLetter(id string) error {
+	q := w.lockDeadLetters()
+	defer q.mu.Unlock()
+
+	for i, e := range q.entries {
+		if e.ID == id {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			w.persistDeadLetters(q)
+			return nil
+		}
+	}
+
+	return ErrDeadLetterNotFound
+}
+
+// PurgeDeadLetters discards every dead-lettered notification, returning how
+// many were removed.
+func (w *WorkflowService) PurgeDeadLetters() int {
+	q := w.lockDeadLetters()
+	defer q.mu.Unlock()
+
+	purged := len(q.entries)
+	q.entries = nil
+	w.persistDeadLetters(q)
+
+	return purged
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func okResponse() *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}
+}
+
+func setupDeliveryService(t *testing.T) (*WorkflowService, *MockHTTPClient, *MockUUIDProvider, *MockTimeProvider) {
+	svc, uuidProvider, timeProvider, _ := setupService(t)
+	svc.config = workflow.NewConfigStore(workflow.Workflows{
+		"test-workflow": {
+			{"step0": {Name: "first", RetryAfter: 10 * time.Millisecond, RetryURL: "http://example.com/retry"}},
+		},
+	}, nil)
+	svc.deliveryAttempts = 2
+	svc.deliveryBackoff = time.Millisecond
+
+	return svc, svc.httpClient.(*MockHTTPClient), uuidProvider, timeProvider
+}
+
+func TestProcessStepDeadLettersUndeliverableNotification(t *testing.T) {
+	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		response       *http.Response
+		responseErr    error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:          "transport error",
+			responseErr:   errors.New("connection refused"),
+			expectedError: "connection refused",
+		},
+		{
+			name:           "non-2xx response",
+			response:       &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader(""))},
+			expectedStatus: http.StatusBadGateway,
+			expectedError:  "unexpected response status 502",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+
+			httpClient.On("Do", mock.Anything).Return(tt.response, tt.responseErr)
+			uuidProvider.On("NewString").Return("dead-letter-id")
+			timeProvider.On("Now").Return(fixedTime)
+
+			runID := "test-run-id"
+			_, cancel := context.WithCancel(context.Background())
+			svc.store.Set(runID, &Run{workflowName: "test-workflow", retryCancel: cancel})
+
+			svc.wg.Add(1)
+			svc.processStep(context.Background(), 0, runID, "test-workflow")
+
+			httpClient.AssertNumberOfCalls(t, "Do", 2)
+
+			deadLetters := svc.DeadLetters()
+			require.Len(t, deadLetters, 1)
+			dl := deadLetters[0]
+			require.Equal(t, "dead-letter-id", dl.ID)
+			require.Equal(t, runID, dl.RunID)
+			require.Equal(t, "step0", dl.Step)
+			require.Equal(t, "http://example.com/retry", dl.URL)
+			require.Equal(t, "application/json", dl.Headers["Content-Type"])
+			require.Contains(t, dl.Body, `"workflow_run_id":"test-run-id"`)
+			require.Equal(t, 2, dl.Attempts)
+			require.Equal(t, tt.expectedStatus, dl.StatusCode)
+			require.Contains(t, dl.Error, tt.expectedError)
+
+			// the run must not be left looking ongoing
+			runValue, _ := svc.store.Get(runID)
+			require.True(t, runValue.(*Run).failed)
+		})
+	}
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("successful replay removes the dead letter", func(t *testing.T) {
+		svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+		uuidProvider.On("NewString").Return("dead-letter-id")
+		timeProvider.On("Now").Return(fixedTime)
+		svc.deadLetter(delivery{runID: "run", url: "http://example.com/retry"}, 1, errors.New("boom"))
+
+		httpClient.On("Do", mock.Anything).Return(okResponse(), nil)
+
+		require.NoError(t, svc.ReplayDeadLetter(context.Background(), "dead-letter-id"))
+		require.Empty(t, svc.DeadLetters())
+	})
+
+	t.Run("failed replay keeps the dead letter", func(t *testing.T) {
+		svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+		uuidProvider.On("NewString").Return("dead-letter-id")
+		timeProvider.On("Now").Return(fixedTime)
+		svc.deadLetter(delivery{runID: "run", url: "http://example.com/retry"}, 1, errors.New("boom"))
+
+		httpClient.On("Do", mock.Anything).Return(nil, errors.New("still down"))
+
+		require.Error(t, svc.ReplayDeadLetter(context.Background(), "dead-letter-id"))
+		deadLetters := svc.DeadLetters()
+		require.Len(t, deadLetters, 1)
+		require.Equal(t, 3, deadLetters[0].Attempts)
+		require.Equal(t, "still down", deadLetters[0].Error)
+	})
+
+	t.Run("unknown dead letter", func(t *testing.T) {
+		svc, _, _, _ := setupDeliveryService(t)
+		require.ErrorIs(t, svc.ReplayDeadLetter(context.Background(), "missing"), ErrDeadLetterNotFound)
+	})
+
+	t.Run("replay in the background", func(t *testing.T) {
+		svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+		uuidProvider.On("NewString").Return("dead-letter-id")
+		timeProvider.On("Now").Return(fixedTime)
+		svc.deadLetter(delivery{runID: "run", url: "http://example.com/retry"}, 1, errors.New("boom"))
+
+		release := make(chan time.Time)
+		httpClient.On("Do", mock.Anything).WaitUntil(release).Return(okResponse(), nil)
+
+		require.ErrorIs(t, svc.StartDeadLetterReplay("missing"), ErrDeadLetterNotFound)
+
+		// the replay is started without waiting for the delivery
+		require.NoError(t, svc.StartDeadLetterReplay("dead-letter-id"))
+		require.Len(t, svc.DeadLetters(), 1)
+
+		close(release)
+		svc.wg.Wait()
+		require.Empty(t, svc.DeadLetters())
+	})
+
+	t.Run("replay all", func(t *testing.T) {
+		svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+		uuidProvider.On("NewString").Return("first").Once()
+		uuidProvider.On("NewString").Return("second").Once()
+		timeProvider.On("Now").Return(fixedTime)
+		svc.deadLetter(delivery{url: "http://example.com/up"}, 1, errors.New("boom"))
+		svc.deadLetter(delivery{url: "http://example.com/down"}, 1, errors.New("boom"))
+
+		httpClient.On("Do", mock.MatchedBy(func(r *http.Request) bool { return r.URL.Path == "/up" })).Return(okResponse(), nil)
+		httpClient.On("Do", mock.Anything).Return(nil, errors.New("still down"))
+
+		require.Equal(t, 2, svc.ReplayDeadLetters())
+		svc.wg.Wait()
+		require.False(t, svc.ReplayingDeadLetters())
+
+		deadLetters := svc.DeadLetters()
+		require.Len(t, deadLetters, 1)
+		require.Equal(t, "second", deadLetters[0].ID)
+	})
+
+	t.Run("one replay at a time", func(t *testing.T) {
+		svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+		uuidProvider.On("NewString").Return("first")
+		timeProvider.On("Now").Return(fixedTime)
+		svc.deadLetter(delivery{url: "http://example.com/retry"}, 1, errors.New("boom"))
+
+		release := make(chan time.Time)
+		httpClient.On("Do", mock.Anything).WaitUntil(release).Return(okResponse(), nil)
+
+		require.Equal(t, 1, svc.ReplayDeadLetters())
+		require.True(t, svc.ReplayingDeadLetters())
+		require.Zero(t, svc.ReplayDeadLetters())
+
+		close(release)
+		svc.wg.Wait()
+		require.Empty(t, svc.DeadLetters())
+		require.Zero(t, svc.ReplayDeadLetters())
+	})
+}
+
+func TestPurgeDeadLetters(t *testing.T) {
+	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	svc, _, uuidProvider, timeProvider := setupDeliveryService(t)
+	uuidProvider.On("NewString").Return("first").Once()
+	uuidProvider.On("NewString").Return("second").Once()
+	uuidProvider.On("NewString").Return("third").Once()
+	timeProvider.On("Now").Return(fixedTime)
+	for range 3 {
+		svc.deadLetter(delivery{url: "http://example.com/retry"}, 1, errors.New("boom"))
+	}
+
+	require.NoError(t, svc.PurgeDeadLetter("second"))
+	require.ErrorIs(t, svc.PurgeDeadLetter("second"), ErrDeadLetterNotFound)
+	require.Len(t, svc.DeadLetters(), 2)
+
+	require.Equal(t, 2, svc.PurgeDeadLetters())
+	require.Empty(t, svc.DeadLetters())
+
+	// the purge is persisted to the store
+	stored, ok := svc.store.Get(deadLettersKey)
+	require.True(t, ok)
+	require.Empty(t, stored)
+}
+
+func TestDeadLettersRestoredFromBackup(t *testing.T) {
+	svc, _, _, _ := setupDeliveryService(t)
+
+	// values restored from a genie backup are generic JSON rather than Go types
+	svc.store.Set(deadLettersKey, []any{
+		map[string]any{"id": "restored", "run_id": "run", "url": "http://example.com/retry", "attempts": float64(3)},
+	})
+
+	deadLetters := svc.DeadLetters()
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, "restored", deadLetters[0].ID)
+	require.Equal(t, 3, deadLetters[0].Attempts)
+}
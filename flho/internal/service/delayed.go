@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// pendingStartsKey is the genie store key the delayed runs waiting to start
+// are persisted under.
+const pendingStartsKey = "flho:pending"
+
+// errNotStarted is returned when a delayed run is advanced or completed
+// before it has started.
+var errNotStarted = errorf(ErrConflict, "run has not started yet")
+
+// pendingStart records a delayed run waiting to start, so that it can be
+// rescheduled after a restart.
+type pendingStart struct {
+	RunID        string            `json:"run_id"`
+	WorkflowName string            `json:"workflow_name"`
+	StartAt      time.Time         `json:"start_at"`
+	Priority     int               `json:"priority,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// pendingStarts holds the delayed runs waiting to start. It is loaded lazily
+// from the store and written back after every change.
+type pendingStarts struct {
+	mu      sync.Mutex
+	loaded  bool
+	entries []pendingStart
+}
+
+// lockPendingStarts locks the pending starts, loading them from the store on
+// first use.
+func (w *WorkflowService) lockPendingStarts() *pendingStarts {
+	p := &w.pending
+	p.mu.Lock()
+
+	if !p.loaded {
+		if v, ok := w.store.Get(pendingStartsKey); ok {
+			if err := decodeStored(v, &p.entries); err != nil {
+				w.logger.Error("failed to restore delayed runs", "error", err.Error())
+			}
+		}
+		p.loaded = true
+	}
+
+	return p
+}
+
+// persistPendingStarts writes the pending starts back to the store. The
+// caller must hold p.mu.
+func (w *WorkflowService) persistPendingStarts(p *pendingStarts) {
+	w.store.Set(pendingStartsKey, slices.Clone(p.entries))
+}
+
+// scheduleStart stores the run in the scheduled state and waits for startAt
+// to begin it. The caller must hold w.mu.
+func (w *WorkflowService) scheduleStart(ctx context.Context, runID string, run *Run, startAt time.Time) {
+	waitCtx, cancel := w.runContext(ctx)
+	run.retryCancel = cancel
+	run.startAt = &startAt
+
+	p := w.lockPendingStarts()
+	if !slices.ContainsFunc(p.entries, func(e pendingStart) bool { return e.RunID == runID }) {
+		p.entries = append(p.entries, pendingStart{RunID: runID, WorkflowName: run.workflowName, StartAt: startAt, Priority: run.priority, Labels: run.labels})
+		w.persistPendingStarts(p)
+	}
+	p.mu.Unlock()
+
+	w.saveRun(runID, run)
+
+	w.wg.Add(1)
+	go w.awaitStart(waitCtx, runID, startAt)
+}
+
+// awaitStart begins the delayed run once startAt is reached, unless it is
+// cancelled first.
+func (w *WorkflowService) awaitStart(ctx context.Context, runID string, startAt time.Time) {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(max(startAt.Sub(w.timeProvider.Now()), 0))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, ok := w.getRun(runID)
+	if !ok {
+		return
+	}
+	if run.status() != RunStatusScheduled {
+		return
+	}
+
+	w.unschedule(runID)
+	w.admit(ctx, runID, run)
+}
+
+// unschedule removes the run from the pending starts, once it has started or
+// been cancelled.
+func (w *WorkflowService) unschedule(runID string) {
+	p := w.lockPendingStarts()
+	defer p.mu.Unlock()
+
+	p.entries = slices.DeleteFunc(p.entries, func(e pendingStart) bool { return e.RunID == runID })
+	w.persistPendingStarts(p)
+}
+
+// restorePendingStarts reschedules the delayed runs persisted before a
+// restart. Runs whose start time passed while flho was down start right away.
+func (w *WorkflowService) restorePendingStarts(ctx context.Context) {
+	p := w.lockPendingStarts()
+	entries := slices.Clone(p.entries)
+	p.mu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, e := range entries {
+		if _, live := w.getRun(e.RunID); live {
+			// already scheduled by this process
+			continue
+		}
+
+		run := &Run{
+			workflowName: e.WorkflowName,
+			namespace:    w.config.NamespaceOf(e.WorkflowName),
+			heartbeats:   make(chan struct{}, 1),
+			priority:     e.Priority,
+			labels:       e.Labels,
+		}
+		w.scheduleStart(ctx, e.RunID, run, e.StartAt)
+	}
+
+	if len(entries) > 0 {
+		w.logger.Info("restored delayed runs", "count", len(entries))
+	}
+}
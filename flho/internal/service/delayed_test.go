@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+var delayedNow = time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func setupDelayedService(t *testing.T) *WorkflowService {
+	svc, _, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(workflow.Workflows{"trial": {
+		{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+	}}, nil)
+
+	uuidProvider.On("NewString").Return("delayed-run-id")
+	timeProvider.On("Now").Return(delayedNow)
+
+	return svc
+}
+
+func pendingRunIDs(svc *WorkflowService) []string {
+	p := svc.lockPendingStarts()
+	defer p.mu.Unlock()
+
+	var ids []string
+	for _, e := range p.entries {
+		ids = append(ids, e.RunID)
+	}
+	return ids
+}
+
+func TestDelayedStart(t *testing.T) {
+	t.Run("run starts once its start time is reached", func(t *testing.T) {
+		svc := setupDelayedService(t)
+
+		runID := svc.InitiateWorkflowWithOptions(context.Background(), "trial", RunOptions{StartAfter: 20 * time.Millisecond})
+
+		run, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		require.Equal(t, RunStatusScheduled, run.Status)
+		require.Equal(t, delayedNow.Add(20*time.Millisecond), *run.ScheduledFor)
+		require.Nil(t, run.StartTime)
+		require.Equal(t, []string{runID}, pendingRunIDs(svc))
+
+		runs := svc.GetRuns(RunsFilter{Status: string(RunStatusScheduled), Page: 1, PageSize: 10})
+		require.Equal(t, 1, runs.TotalCount)
+
+		require.Eventually(t, func() bool {
+			run, _ := svc.GetRun(runID)
+			return run.Status == RunStatusOngoing
+		}, time.Second, time.Millisecond)
+
+		run, _ = svc.GetRun(runID)
+		require.NotNil(t, run.StartTime)
+		require.Empty(t, pendingRunIDs(svc))
+	})
+
+	t.Run("start time in the past starts the run immediately", func(t *testing.T) {
+		svc := setupDelayedService(t)
+
+		runID := svc.InitiateWorkflowWithOptions(context.Background(), "trial", RunOptions{StartAt: delayedNow.Add(-time.Hour)})
+
+		run, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		require.Equal(t, RunStatusOngoing, run.Status)
+		require.Empty(t, pendingRunIDs(svc))
+	})
+
+	t.Run("run can be cancelled before it starts", func(t *testing.T) {
+		svc := setupDelayedService(t)
+
+		runID := svc.InitiateWorkflowWithOptions(context.Background(), "trial", RunOptions{StartAt: delayedNow.Add(time.Hour)})
+		require.NoError(t, svc.CancelWorkflow(context.Background(), runID, "trial converted early"))
+
+		// the countdown to the start stops with the cancellation
+		waitForRuns(t, svc)
+
+		run, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		require.Equal(t, RunStatusCancelled, run.Status)
+		require.Nil(t, run.StartTime)
+		require.Empty(t, pendingRunIDs(svc))
+	})
+
+	t.Run("run cannot be advanced or completed before it starts", func(t *testing.T) {
+		svc := setupDelayedService(t)
+
+		runID := svc.InitiateWorkflowWithOptions(context.Background(), "trial", RunOptions{StartAt: delayedNow.Add(time.Hour)})
+
+		require.EqualError(t, svc.UpdateWorkflow(context.Background(), runID), "run has not started yet")
+		require.EqualError(t, svc.CompleteWorkflow(context.Background(), runID), "run has not started yet")
+
+		run, _ := svc.GetRun(runID)
+		require.Equal(t, RunStatusScheduled, run.Status)
+		require.Equal(t, 0, run.CurrentStep)
+	})
+
+	t.Run("refused completion leaves the start in place", func(t *testing.T) {
+		svc := setupDelayedService(t)
+
+		runID := svc.InitiateWorkflowWithOptions(context.Background(), "trial", RunOptions{StartAfter: 20 * time.Millisecond})
+		require.EqualError(t, svc.CompleteWorkflow(context.Background(), runID), "run has not started yet")
+
+		require.Eventually(t, func() bool {
+			run, _ := svc.GetRun(runID)
+			return run.Status == RunStatusOngoing
+		}, time.Second, time.Millisecond)
+	})
+}
+
+func TestDelayedStartSurvivesRestart(t *testing.T) {
+	svc := setupDelayedService(t)
+
+	// the pending start as restored from a backup, one due in the future and
+	// one that fell due while flho was down
+	svc.store.Set(pendingStartsKey, []any{
+		map[string]any{"run_id": "later-run-id", "workflow_name": "trial", "start_at": "2023-01-01T13:00:00Z"},
+		map[string]any{"run_id": "overdue-run-id", "workflow_name": "trial", "start_at": "2023-01-01T11:00:00Z"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.Start(ctx)
+
+	later, err := svc.GetRun("later-run-id")
+	require.NoError(t, err)
+	require.Equal(t, RunStatusScheduled, later.Status)
+	require.Equal(t, time.Date(2023, 1, 1, 13, 0, 0, 0, time.UTC), later.ScheduledFor.UTC())
+
+	require.Eventually(t, func() bool {
+		run, err := svc.GetRun("overdue-run-id")
+		return err == nil && run.Status == RunStatusOngoing
+	}, time.Second, time.Millisecond)
+
+	require.Equal(t, []string{"later-run-id"}, pendingRunIDs(svc))
+}
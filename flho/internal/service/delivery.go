@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultDeliveryAttempts = 3
+	defaultDeliveryBackoff  = time.Second
+)
+
+// delivery is a single outbound notification made on behalf of a run.
+type delivery struct {
+	runID        string
+	workflowName string
+	step         string
+	url          string
+	headers      map[string]string
+	body         []byte
+	priority     int // the run's priority, ordering the delivery while it waits on a host
+}
+
+// header returns every header sent with the notification.
+func (d delivery) header() map[string]string {
+	h := map[string]string{"Content-Type": "application/json"}
+	for k, v := range d.headers {
+		h[k] = v
+	}
+	return h
+}
+
+// statusError reports a notification that was answered with a non-2xx status.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected response status %d", e.code)
+}
+
+// deliver POSTs the notification, retrying failed attempts with exponential
+// backoff. Once every attempt has failed the notification is moved to the
+// dead-letter queue so it can be inspected and replayed later. Nothing is
+// dead-lettered when ctx is cancelled, since the notification was abandoned
+// rather than found undeliverable.
+func (w *WorkflowService) deliver(ctx context.Context, d delivery) error {
+	attempts, err := w.attemptDelivery(ctx, d)
+	if err != nil && ctx.Err() == nil {
+		w.deadLetter(d, attempts, err)
+	}
+	return err
+}
+
+// attemptDelivery POSTs the notification until it succeeds, the attempts are
+// exhausted or ctx is done, returning the number of attempts made.
+func (w *WorkflowService) attemptDelivery(ctx context.Context, d delivery) (int, error) {
+	attempts, backoff := w.deliveryAttempts, w.deliveryBackoff
+	if attempts <= 0 {
+		attempts = defaultDeliveryAttempts
+	}
+	if backoff <= 0 {
+		backoff = defaultDeliveryBackoff
+	}
+
+	host := targetHost(d.url)
+
+	for attempt := 1; ; attempt++ {
+		// wait for the host's circuit breaker and concurrency limit; this
+		// defers the delivery while the host is unhealthy without using up
+		// an attempt
+		release, err := w.breakers.acquire(ctx, host, d.priority)
+		if err != nil {
+			return attempt - 1, err
+		}
+
+		err = w.post(ctx, d)
+		release(err)
+
+		if err == nil || attempt == attempts {
+			return attempt, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+			backoff *= 2
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		}
+	}
+}
+
+// post makes a single delivery attempt.
+func (w *WorkflowService) post(ctx context.Context, d delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.body))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	for k, v := range d.header() {
+		req.Header.Set(k, v)
+	}
+	w.sign(req, d.body)
+
+	span := w.startDeliverySpan(ctx, d, req)
+	sent := time.Now()
+	res, err := w.httpClient.Do(req)
+	if err != nil {
+		w.metrics.notificationAttempted(d.workflowName, 0, time.Since(sent))
+		endDeliverySpan(span, 0, err)
+		return err
+	}
+	_ = res.Body.Close()
+	w.metrics.notificationAttempted(d.workflowName, res.StatusCode, time.Since(sent))
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		err = &statusError{code: res.StatusCode}
+	}
+	endDeliverySpan(span, res.StatusCode, err)
+
+	return err
+}
+
+// responseStatus extracts the HTTP status code from a delivery error, if any.
+func responseStatus(err error) int {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code
+	}
+	return 0
+}
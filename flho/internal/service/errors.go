@@ -0,0 +1,39 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of the errors returned by the service. Callers tell them apart with
+// errors.Is, whatever the message of the error.
+var (
+	// ErrNotFound is the kind of errors about runs, workflows, bulk jobs and
+	// dead letters that do not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is the kind of errors about operations the status of a run
+	// does not allow, such as completing a cancelled run
+	ErrConflict = errors.New("conflict")
+	// ErrInvalid is the kind of errors about arguments that are not valid,
+	// such as an unknown sort key or a heartbeat's progress over 100
+	ErrInvalid = errors.New("invalid argument")
+)
+
+// kindError is an error of one of the kinds above, with its own message.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+func (e *kindError) Unwrap() error { return e.kind }
+
+// errorf returns an error of the kind, with the formatted message.
+func errorf(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// runNotFound returns the error about a run that does not exist.
+func runNotFound(runID string) error {
+	return errorf(ErrNotFound, "no data found for run ID: %s", runID)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKinds(t *testing.T) {
+	// run-1 is failed, run-4 and run-5 are ongoing
+	svc := setupBulkService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		call     func() error
+		expected error
+		message  string
+	}{
+		{name: "unknown run", call: func() error { return svc.CancelWorkflow(ctx, "missing", "") }, expected: ErrNotFound, message: "no data found for run ID: missing"},
+		{name: "unknown workflow", call: func() error { return svc.CheckWorkflow("missing") }, expected: ErrNotFound, message: "workflow missing does not exist"},
+		{name: "unknown bulk job", call: func() error { _, err := svc.BulkJob("missing"); return err }, expected: ErrNotFound, message: "bulk job not found"},
+		{name: "finished run", call: func() error { return svc.CompleteWorkflow(ctx, "run-1") }, expected: ErrConflict, message: "run is already failed"},
+		{name: "ongoing run resumed", call: func() error { return svc.ResumeWorkflow(ctx, "run-4") }, expected: ErrConflict, message: "run is ongoing, only failed or timed out runs can be resumed"},
+		{name: "invalid progress", call: func() error { p := 101.0; return svc.Heartbeat("run-4", &p, "") }, expected: ErrInvalid, message: "progress must be between 0 and 100, got 101"},
+		{name: "invalid filter", call: func() error { return (&RunsFilter{Sort: "size"}).Validate() }, expected: ErrInvalid, message: `unknown sort key "size"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			require.ErrorIs(t, err, tt.expected)
+			require.EqualError(t, err, tt.message)
+		})
+	}
+
+	require.NoError(t, svc.CheckWorkflow("export"))
+}
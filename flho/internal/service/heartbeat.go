@@ -0,0 +1,48 @@
+package service
+
+import "time"
+
+// Heartbeat is the latest sign of life reported for a run's current step.
+type Heartbeat struct {
+	At       time.Time `json:"at"`
+	Step     int       `json:"step"`
+	Progress *float64  `json:"progress,omitempty"` // optional percentage complete, 0-100
+	Message  string    `json:"message,omitempty"`
+}
+
+// Heartbeat records that the run's current step is still making progress and
+// restarts its retry countdown, without advancing the run. Heartbeats do not
+// extend the step timeout or the workflow deadline.
+func (w *WorkflowService) Heartbeat(runID string, progress *float64, message string) error {
+	if progress != nil && (*progress < 0 || *progress > 100) {
+		return errorf(ErrInvalid, "progress must be between 0 and 100, got %v", *progress)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, ok := w.getRun(runID)
+	if !ok {
+		return runNotFound(runID)
+	}
+
+	if status := run.status(); status != RunStatusOngoing {
+		return errorf(ErrConflict, "cannot heartbeat a run that is %s", status)
+	}
+
+	run.heartbeat = &Heartbeat{
+		At:       w.timeProvider.Now(),
+		Step:     run.currStep,
+		Progress: progress,
+		Message:  message,
+	}
+	w.saveRun(runID, run)
+
+	// a countdown reset is already pending if the buffer is full
+	select {
+	case run.heartbeats <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func TestHeartbeat(t *testing.T) {
+	progress := 42.5
+	invalidProgress := 120.0
+
+	tests := []struct {
+		name        string
+		runID       string
+		progress    *float64
+		setupStore  func(*WorkflowService)
+		expectedErr string
+	}{
+		{
+			name:     "records the heartbeat",
+			runID:    "valid-run-id",
+			progress: &progress,
+			setupStore: func(svc *WorkflowService) {
+				svc.store.Set("valid-run-id", &Run{currStep: 1, heartbeats: make(chan struct{}, 1)})
+			},
+		},
+		{
+			name:        "run not found",
+			runID:       "missing-run-id",
+			setupStore:  func(_ *WorkflowService) {},
+			expectedErr: "no data found for run ID: missing-run-id",
+		},
+		{
+			name:  "run already finished",
+			runID: "failed-run-id",
+			setupStore: func(svc *WorkflowService) {
+				end := time.Now()
+				svc.store.Set("failed-run-id", &Run{failed: true, end: &end})
+			},
+			expectedErr: "cannot heartbeat a run that is failed",
+		},
+		{
+			name:        "progress out of range",
+			runID:       "valid-run-id",
+			progress:    &invalidProgress,
+			setupStore:  func(_ *WorkflowService) {},
+			expectedErr: "progress must be between 0 and 100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, timeProvider, store := setupService(t)
+			fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+			timeProvider.On("Now").Return(fixedTime)
+
+			tt.setupStore(svc)
+
+			err := svc.Heartbeat(tt.runID, tt.progress, "transcoding")
+
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			runValue, _ := store.Get(tt.runID)
+			run := runValue.(*Run)
+			require.Equal(t, &Heartbeat{At: fixedTime, Step: 1, Progress: tt.progress, Message: "transcoding"}, run.heartbeat)
+			require.Len(t, run.heartbeats, 1)
+
+			// repeated heartbeats never block
+			require.NoError(t, svc.Heartbeat(tt.runID, nil, ""))
+		})
+	}
+}
+
+func TestHeartbeatExtendsRetryCountdown(t *testing.T) {
+	svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(workflow.Workflows{
+		"transcode": {{"step0": {RetryAfter: 250 * time.Millisecond, RetryURL: "http://example.com/retry"}}},
+	}, nil)
+	uuidProvider.On("NewString").Return("transcode-run-id")
+	timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+	httpClient.On("Do", mock.Anything).Return(okResponse(), nil)
+
+	runID := svc.InitiateWorkflow(context.Background(), "transcode")
+
+	// keep beating for well past the retry interval
+	for range 12 {
+		time.Sleep(25 * time.Millisecond)
+		require.NoError(t, svc.Heartbeat(runID, nil, "still working"))
+	}
+
+	httpClient.AssertNotCalled(t, "Do", mock.Anything)
+	runs := svc.GetRuns(RunsFilter{Page: 1, PageSize: 10})
+	require.Equal(t, RunStatusOngoing, runs.Runs[0].Status)
+	require.Equal(t, "still working", runs.Runs[0].LastHeartbeat.Message)
+
+	// once the heartbeats stop, the countdown runs out as usual
+	waitForRuns(t, svc)
+	httpClient.AssertNumberOfCalls(t, "Do", 1)
+	runs = svc.GetRuns(RunsFilter{Page: 1, PageSize: 10})
+	require.Equal(t, RunStatusFailed, runs.Runs[0].Status)
+}
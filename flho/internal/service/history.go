@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// maxRunHistory bounds the events kept per run, the oldest being dropped
+// first.
+const maxRunHistory = 100
+
+// RunEventType represents what happened to a run.
+type RunEventType string
+
+const (
+	// RunEventCreated represents a run being initiated
+	RunEventCreated RunEventType = "created"
+	// RunEventQueued represents a run waiting for a concurrency slot
+	RunEventQueued RunEventType = "queued"
+	// RunEventStarted represents a run starting its first step
+	RunEventStarted RunEventType = "started"
+	// RunEventAdvanced represents a run moving on to its next step
+	RunEventAdvanced RunEventType = "advanced"
+	// RunEventResumed represents a failed or timed out run being resumed
+	RunEventResumed RunEventType = "resumed"
+)
+
+// A run's final status is recorded as an event of the same name: completed,
+// failed, timed_out or cancelled.
+
+// RunEvent is an entry of a run's history.
+type RunEvent struct {
+	Time   time.Time    `json:"time"`
+	Type   RunEventType `json:"type"`
+	Step   int          `json:"step"`            // the run's current step once the event happened
+	Actor  string       `json:"actor,omitempty"` // who caused the event, such as an API key ID, or empty for flho itself
+	Detail string       `json:"detail,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx naming actor, such as the ID of the API key
+// making a request, as the cause of the run events recorded with it.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor named in ctx, if any.
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// recordEvent appends an event to the run's history, caused by the actor in
+// ctx. The caller must hold w.mu and save the run.
+func (w *WorkflowService) recordEvent(ctx context.Context, run *Run, eventType RunEventType, detail string) {
+	if len(run.history) >= maxRunHistory {
+		run.history = slices.Delete(run.history, 0, len(run.history)-maxRunHistory+1)
+	}
+
+	run.history = append(run.history, RunEvent{
+		Time:   w.timeProvider.Now(),
+		Type:   eventType,
+		Step:   run.currStep,
+		Actor:  Actor(ctx),
+		Detail: detail,
+	})
+}
+
+// createdDetail describes how a run came to be created, and when it starts if
+// it is delayed until startAt.
+func createdDetail(run *Run, startAt time.Time) string {
+	switch {
+	case run.parentRunID != "":
+		return "started by step" + strconv.Itoa(run.parentStep) + " of run " + run.parentRunID
+	case !startAt.IsZero():
+		return "due to start at " + startAt.Format(time.RFC3339)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func TestRunHistory(t *testing.T) {
+	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("events record who caused them", func(t *testing.T) {
+		svc := setupQueueService(t, 1)
+		svc.config = workflow.NewConfigStore(
+			workflow.Workflows{"export": {
+				{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+				{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			}},
+			map[string]workflow.Settings{"export": {MaxConcurrentRuns: 1}},
+		)
+
+		ci := WithActor(context.Background(), "ci")
+		ops := WithActor(context.Background(), "ops")
+
+		first := svc.InitiateWorkflow(ci, "export")
+		second := svc.InitiateWorkflow(ci, "export")
+		require.NoError(t, svc.UpdateWorkflow(ops, first))
+		require.NoError(t, svc.CancelWorkflow(ops, first, "duplicate order"))
+
+		run, err := svc.GetRun(first)
+		require.NoError(t, err)
+		require.Equal(t, []RunEvent{
+			{Time: fixedTime, Type: RunEventCreated, Step: 0, Actor: "ci"},
+			{Time: fixedTime, Type: RunEventStarted, Step: 0, Actor: "ci"},
+			{Time: fixedTime, Type: RunEventAdvanced, Step: 1, Actor: "ops"},
+			{Time: fixedTime, Type: RunEventType(RunStatusCancelled), Step: 1, Actor: "ops", Detail: "duplicate order"},
+		}, run.History)
+
+		// the queued run was started by flho once the slot freed up
+		run, err = svc.GetRun(second)
+		require.NoError(t, err)
+		require.Equal(t, []RunEvent{
+			{Time: fixedTime, Type: RunEventCreated, Step: 0, Actor: "ci"},
+			{Time: fixedTime, Type: RunEventQueued, Step: 0, Actor: "ci"},
+			{Time: fixedTime, Type: RunEventStarted, Step: 0},
+		}, run.History)
+
+		// histories are only returned for a single run
+		for _, info := range svc.GetRuns(RunsFilter{}).Runs {
+			require.Nil(t, info.History)
+		}
+	})
+
+	t.Run("bulk jobs act on behalf of their actor", func(t *testing.T) {
+		svc := setupBulkService(t)
+
+		job, err := svc.StartBulk(WithActor(context.Background(), "ops"), BulkRequest{Action: BulkResume, RunIDs: []string{"run-1"}})
+		require.NoError(t, err)
+		require.Equal(t, "ops", job.Actor)
+		waitForBulkJob(t, svc, job.ID)
+
+		run, err := svc.GetRun("run-1")
+		require.NoError(t, err)
+		require.Equal(t, RunEvent{Time: fixedTime, Type: RunEventType(RunStatusFailed), Step: 0, Detail: reasonRetryElapsed}, run.History[2])
+		require.Equal(t, RunEvent{Time: fixedTime, Type: RunEventResumed, Step: 0, Actor: "ops"}, run.History[3])
+	})
+
+	t.Run("only the latest events are kept", func(t *testing.T) {
+		svc, _, timeProvider, _ := setupService(t)
+		timeProvider.On("Now").Return(fixedTime)
+
+		run := &Run{}
+		for i := range maxRunHistory + 5 {
+			run.currStep = i
+			svc.recordEvent(context.Background(), run, RunEventAdvanced, "")
+		}
+
+		require.Len(t, run.history, maxRunHistory)
+		require.Equal(t, 5, run.history[0].Step)
+		require.Equal(t, maxRunHistory+4, run.history[maxRunHistory-1].Step)
+	})
+}
@@ -0,0 +1,604 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/windevkay/forge/flho/internal/label"
+)
+
+const (
+	defaultRunsPageSize = 20
+	maxRunsPageSize     = 500
+
+	// maxPageOffset is the number of runs a page reached by number may start
+	// after. Cursors have no such limit.
+	maxPageOffset = 10000
+
+	// missingKey sorts runs that lack the sorted value, such as the end time
+	// of an ongoing run, below every run that has it.
+	missingKey = math.MinInt64
+)
+
+// runSortKeys are the keys runs can be sorted by, each yielding the values a
+// run is ordered on. The first is the default. Runs of equal values are
+// ordered by when they were first indexed, newest first in descending order
+// unless oldestFirst is set.
+var runSortKeys = [...]struct {
+	name        string
+	key         func(*Run) (int64, int64)
+	oldestFirst bool
+}{
+	{RunsSortStartTime, func(r *Run) (int64, int64) { return unixNano(r.start), 0 }, false},
+	{RunsSortEndTime, func(r *Run) (int64, int64) { return unixNano(r.end), 0 }, false},
+	{RunsSortDuration, func(r *Run) (int64, int64) {
+		if r.start == nil || r.end == nil {
+			return missingKey, 0
+		}
+		return int64(r.end.Sub(*r.start)), 0
+	}, false},
+	// the start time is negated so that, in descending order, runs of a
+	// priority are listed oldest first, as queued runs start
+	{RunsSortPriority, func(r *Run) (int64, int64) {
+		if r.start == nil {
+			return int64(r.priority), missingKey
+		}
+		return int64(r.priority), -r.start.UnixNano()
+	}, true},
+}
+
+func unixNano(t *time.Time) int64 {
+	if t == nil {
+		return missingKey
+	}
+	return t.UnixNano()
+}
+
+// sortKeyIndex returns the position of the named sort key in runSortKeys, or
+// -1 if there is no such key.
+func sortKeyIndex(name string) int {
+	if name == "" {
+		return 0
+	}
+	for i, s := range runSortKeys {
+		if s.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexKey is a run's position under one sort key. Runs with equal values
+// are ordered by when they were first indexed, so every position is unique.
+type indexKey struct {
+	k1, k2 int64
+	seq    uint64
+}
+
+func (a indexKey) compare(b indexKey) int {
+	switch {
+	case a.k1 != b.k1:
+		return cmpInt64(a.k1, b.k1)
+	case a.k2 != b.k2:
+		return cmpInt64(a.k2, b.k2)
+	case a.seq < b.seq:
+		return -1
+	case a.seq > b.seq:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func cmpInt64(a, b int64) int {
+	if a < b {
+		return -1
+	}
+	return 1
+}
+
+// indexedRun is a run's entry in a sorted partition.
+type indexedRun struct {
+	key   indexKey
+	runID string
+}
+
+// partitionKey identifies the runs of one workflow in one status.
+type partitionKey struct {
+	namespace    string
+	workflowName string
+	status       RunStatus
+}
+
+// partition holds the runs of a partitionKey sorted ascending under every
+// sort key.
+type partition [len(runSortKeys)][]indexedRun
+
+// indexEntry records where a run is indexed, to find it again when it
+// changes.
+type indexEntry struct {
+	partition partitionKey
+	keys      [len(runSortKeys)]indexKey
+}
+
+// runIndex keeps every run sorted under each sort key, partitioned by
+// namespace, workflow and status, so that a page of runs is found by seeking into the
+// matching partitions rather than visiting every run. Runs are also indexed
+// by each of their labels, so that a label selector requiring a value visits
+// only the runs carrying it. It is guarded by w.mu.
+type runIndex struct {
+	seq        uint64
+	entries    map[string]indexEntry
+	partitions map[partitionKey]*partition
+
+	// labels holds the IDs of the runs carrying each label, keyed by
+	// labelKey, and runLabels the labels each run is indexed under.
+	labels    map[string]map[string]struct{}
+	runLabels map[string]map[string]string
+}
+
+// labelKey is the key a label is indexed under in runIndex.labels.
+func labelKey(key, value string) string {
+	return key + "=" + value
+}
+
+// update indexes the run, moving it if its status or sorted values changed.
+func (x *runIndex) update(runID string, run *Run) {
+	if x.entries == nil {
+		x.entries = make(map[string]indexEntry)
+		x.partitions = make(map[partitionKey]*partition)
+		x.labels = make(map[string]map[string]struct{})
+		x.runLabels = make(map[string]map[string]string)
+	}
+	x.updateLabels(runID, run.labels)
+
+	old, indexed := x.entries[runID]
+	seq := old.keys[0].seq
+	if !indexed {
+		x.seq++
+		seq = x.seq
+	}
+
+	entry := indexEntry{partition: partitionKey{namespace: run.namespace, workflowName: run.workflowName, status: run.status()}}
+	for i, s := range runSortKeys {
+		k1, k2 := s.key(run)
+		entry.keys[i] = indexKey{k1: k1, k2: k2, seq: seq}
+		if s.oldestFirst {
+			entry.keys[i].seq = math.MaxUint64 - seq
+		}
+	}
+
+	if indexed {
+		if old == entry {
+			return
+		}
+		x.remove(runID, old)
+	}
+
+	p, ok := x.partitions[entry.partition]
+	if !ok {
+		p = &partition{}
+		x.partitions[entry.partition] = p
+	}
+	for i, key := range entry.keys {
+		at, _ := slices.BinarySearchFunc(p[i], key, func(r indexedRun, k indexKey) int { return r.key.compare(k) })
+		p[i] = slices.Insert(p[i], at, indexedRun{key: key, runID: runID})
+	}
+	x.entries[runID] = entry
+}
+
+// remove takes the run out of the partition it is indexed in.
+func (x *runIndex) remove(runID string, entry indexEntry) {
+	p := x.partitions[entry.partition]
+	for i, key := range entry.keys {
+		if at, found := slices.BinarySearchFunc(p[i], key, func(r indexedRun, k indexKey) int { return r.key.compare(k) }); found {
+			p[i] = slices.Delete(p[i], at, at+1)
+		}
+	}
+	if len(p[0]) == 0 {
+		delete(x.partitions, entry.partition)
+	}
+	delete(x.entries, runID)
+}
+
+// drop takes the run out of the index altogether.
+func (x *runIndex) drop(runID string) {
+	if entry, ok := x.entries[runID]; ok {
+		x.remove(runID, entry)
+	}
+	x.updateLabels(runID, nil)
+}
+
+// updateLabels indexes the run under its labels, dropping it from those of
+// labels it no longer carries. Labels are replaced rather than modified when
+// a run is updated, see mergeLabels.
+func (x *runIndex) updateLabels(runID string, labels map[string]string) {
+	old := x.runLabels[runID]
+	if maps.Equal(old, labels) {
+		return
+	}
+
+	for k, v := range old {
+		if labels[k] == v {
+			continue
+		}
+		key := labelKey(k, v)
+		delete(x.labels[key], runID)
+		if len(x.labels[key]) == 0 {
+			delete(x.labels, key)
+		}
+	}
+	for k, v := range labels {
+		key := labelKey(k, v)
+		if x.labels[key] == nil {
+			x.labels[key] = make(map[string]struct{})
+		}
+		x.labels[key][runID] = struct{}{}
+	}
+
+	if len(labels) == 0 {
+		delete(x.runLabels, runID)
+	} else {
+		x.runLabels[runID] = labels
+	}
+}
+
+// labelled returns the IDs of the runs carrying every label the selector
+// requires a value of, or rather those of the rarest such label, which is
+// enough to narrow a query. ok is false if the selector requires no value.
+func (x *runIndex) labelled(selector label.Selector) (ids map[string]struct{}, ok bool) {
+	required, satisfiable := selector.Equalities()
+	if !satisfiable {
+		return nil, true
+	}
+	for k, v := range required {
+		runs := x.labels[labelKey(k, v)]
+		if !ok || len(runs) < len(ids) {
+			ids, ok = runs, true
+		}
+	}
+	return ids, ok
+}
+
+// getRun returns the run stored under runID.
+func (w *WorkflowService) getRun(runID string) (*Run, bool) {
+	r, _ := w.store.Get(runID)
+	run, ok := r.(*Run)
+	return run, ok
+}
+
+// saveRun stores the run, updates its place in the run index and signals
+// those watching it. The caller must hold w.mu.
+func (w *WorkflowService) saveRun(runID string, run *Run) {
+	w.store.Set(runID, run)
+	w.runs.update(runID, run)
+	w.watchers.notify(runID)
+}
+
+// runCursor marks the last run of a page, for the next page to continue
+// after it.
+type runCursor struct {
+	sort string
+	key  indexKey
+}
+
+func (c runCursor) String() string {
+	raw := fmt.Sprintf("%s:%d:%d:%d", c.sort, c.key.k1, c.key.k2, c.key.seq)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+var errInvalidCursor = errorf(ErrInvalid, "invalid cursor")
+
+func parseRunCursor(s string) (runCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return runCursor{}, errInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || parts[0] == "" || sortKeyIndex(parts[0]) < 0 {
+		return runCursor{}, errInvalidCursor
+	}
+
+	k1, err1 := strconv.ParseInt(parts[1], 10, 64)
+	k2, err2 := strconv.ParseInt(parts[2], 10, 64)
+	seq, err3 := strconv.ParseUint(parts[3], 10, 64)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return runCursor{}, errInvalidCursor
+	}
+
+	return runCursor{sort: parts[0], key: indexKey{k1: k1, k2: k2, seq: seq}}, nil
+}
+
+// Validate reports whether the filter can be used to query runs.
+func (f RunsFilter) Validate() error {
+	if sortKeyIndex(f.Sort) < 0 {
+		return errorf(ErrInvalid, "unknown sort key %q", f.Sort)
+	}
+	if f.Order != "" && f.Order != RunsOrderAsc && f.Order != RunsOrderDesc {
+		return errorf(ErrInvalid, "unknown sort order %q", f.Order)
+	}
+	if f.Cursor != "" {
+		c, err := parseRunCursor(f.Cursor)
+		if err != nil {
+			return err
+		}
+		if c.sort != f.sortKey() {
+			return errorf(ErrInvalid, "cursor was issued for sorting by %s", c.sort)
+		}
+	}
+	if f.Cursor == "" && f.Page > 1 && (f.Page-1)*f.pageSize() > maxPageOffset {
+		return errorf(ErrInvalid, "page %d starts past the first %d runs, follow the cursor instead", f.Page, maxPageOffset)
+	}
+	if !f.StartedAfter.IsZero() && !f.StartedBefore.IsZero() && !f.StartedAfter.Before(f.StartedBefore) {
+		return errorf(ErrInvalid, "started after must be before started before")
+	}
+	if !f.EndedAfter.IsZero() && !f.EndedBefore.IsZero() && !f.EndedAfter.Before(f.EndedBefore) {
+		return errorf(ErrInvalid, "ended after must be before ended before")
+	}
+	if f.MinDuration < 0 {
+		return errorf(ErrInvalid, "minimum duration must not be negative")
+	}
+	return nil
+}
+
+// sortKey returns the name of the key the filter sorts by.
+func (f RunsFilter) sortKey() string {
+	return runSortKeys[max(sortKeyIndex(f.Sort), 0)].name
+}
+
+// inNamespace reports whether the filter selects runs of the namespace.
+func (f RunsFilter) inNamespace(namespace string) bool {
+	return (f.Namespace == "" || namespace == f.Namespace) && InNamespaces(namespace, f.Namespaces)
+}
+
+// InNamespaces reports whether namespace is one of namespaces, nil standing
+// for every namespace.
+func InNamespaces(namespace string, namespaces []string) bool {
+	return namespaces == nil || slices.Contains(namespaces, namespace)
+}
+
+// pageSize returns the number of runs on a page.
+func (f RunsFilter) pageSize() int {
+	if f.PageSize <= 0 {
+		return defaultRunsPageSize
+	}
+	return min(f.PageSize, maxRunsPageSize)
+}
+
+// matches reports whether the run satisfies the filter's conditions beyond
+// its namespace, workflow name and status, which the index partitions by.
+func (f RunsFilter) matches(run *Run) bool {
+	inRange := func(t *time.Time, after, before time.Time) bool {
+		if after.IsZero() && before.IsZero() {
+			return true
+		}
+		return t != nil && (after.IsZero() || !t.Before(after)) && (before.IsZero() || t.Before(before))
+	}
+
+	switch {
+	case f.Priority != nil && run.priority != *f.Priority:
+		return false
+	case f.Step != nil && run.currStep != *f.Step:
+		return false
+	case !inRange(run.start, f.StartedAfter, f.StartedBefore):
+		return false
+	case !inRange(run.end, f.EndedAfter, f.EndedBefore):
+		return false
+	case f.MinDuration > 0 && (run.start == nil || run.end == nil || run.end.Sub(*run.start) < f.MinDuration):
+		return false
+	default:
+		return f.Labels.Matches(run.labels)
+	}
+}
+
+// keyRange returns the range [lo, hi) of the sort key's first value that the
+// filter selects, if the filter narrows the sort key at all, and whether that
+// range is the only condition of the filter beyond namespace, workflow name
+// and status.
+func (f RunsFilter) keyRange() (lo, hi int64, narrowed, exact bool) {
+	lo, hi = missingKey, math.MaxInt64
+
+	timeRange := func(after, before time.Time) {
+		lo = missingKey + 1 // runs without the time are outside any range
+		if !after.IsZero() {
+			lo = after.UnixNano()
+		}
+		if !before.IsZero() {
+			hi = before.UnixNano()
+		}
+	}
+
+	startRange := !f.StartedAfter.IsZero() || !f.StartedBefore.IsZero()
+	endRange := !f.EndedAfter.IsZero() || !f.EndedBefore.IsZero()
+
+	var handled bool
+	switch f.sortKey() {
+	case RunsSortStartTime:
+		if handled = startRange; handled {
+			timeRange(f.StartedAfter, f.StartedBefore)
+		}
+	case RunsSortEndTime:
+		if handled = endRange; handled {
+			timeRange(f.EndedAfter, f.EndedBefore)
+		}
+	case RunsSortDuration:
+		if handled = f.MinDuration > 0; handled {
+			lo = int64(f.MinDuration)
+		}
+	case RunsSortPriority:
+		if handled = f.Priority != nil; handled {
+			lo, hi = int64(*f.Priority), int64(*f.Priority)+1
+		}
+	}
+
+	conditions := 0
+	for _, set := range []bool{
+		f.Priority != nil, f.Step != nil, startRange, endRange, f.MinDuration > 0, !f.Labels.Empty(),
+	} {
+		if set {
+			conditions++
+		}
+	}
+
+	return lo, hi, handled, conditions == 0 || (handled && conditions == 1)
+}
+
+// queryRuns returns the IDs of a page of runs matching the filter and the
+// cursor of the next page, if there is one. Only the partitions of matching
+// workflows and statuses are visited, starting from the filter's cursor and
+// narrowed to the filter's range of the sort key, and the visit stops once
+// the page is full. When the filter's label selector requires a value and
+// fewer runs carry it than the partitions hold, only those runs are visited.
+// Other conditions, such as the current step, are checked run by run. The
+// count of matching runs is -1 when it cannot be told from the index alone.
+// The caller must hold w.mu.
+func (w *WorkflowService) queryRuns(filter RunsFilter) (ids []string, next string, count int) {
+	sortIdx := max(sortKeyIndex(filter.Sort), 0)
+	desc := filter.Order != RunsOrderAsc
+
+	size := filter.pageSize()
+
+	var after *indexKey
+	if c, err := parseRunCursor(filter.Cursor); err == nil && c.sort == runSortKeys[sortIdx].name {
+		after = &c.key
+	}
+
+	skip := 0
+	if after == nil && filter.Page > 1 {
+		skip = (filter.Page - 1) * size
+	}
+
+	lo, hi, narrowed, exact := filter.keyRange()
+	byK1 := func(r indexedRun, k int64) int {
+		if r.key.k1 < k {
+			return -1
+		}
+		return 1
+	}
+	narrow := func(runs []indexedRun) []indexedRun {
+		if !narrowed {
+			return runs
+		}
+		from, _ := slices.BinarySearchFunc(runs, lo, byK1)
+		to, _ := slices.BinarySearchFunc(runs, hi, byK1)
+		return runs[from:max(from, to)]
+	}
+	selected := func(pk partitionKey) bool {
+		return (filter.Status == "" || pk.status == RunStatus(filter.Status)) &&
+			(filter.WorkflowName == "" || strings.Contains(pk.workflowName, filter.WorkflowName)) &&
+			filter.inNamespace(pk.namespace)
+	}
+
+	// the runs of each matching partition in ascending order
+	var sources [][]indexedRun
+	total := 0
+	for pk, p := range w.runs.partitions {
+		if !selected(pk) {
+			continue
+		}
+		runs := narrow(p[sortIdx])
+		total += len(runs)
+		sources = append(sources, runs)
+	}
+
+	count = total
+	if !exact {
+		count = -1
+	}
+
+	// visit only the runs carrying a label the selector requires, when they
+	// are fewer
+	if labelled, ok := w.runs.labelled(filter.Labels); ok && len(labelled) < total {
+		runs := make([]indexedRun, 0, len(labelled))
+		for runID := range labelled {
+			if entry := w.runs.entries[runID]; selected(entry.partition) {
+				runs = append(runs, indexedRun{key: entry.keys[sortIdx], runID: runID})
+			}
+		}
+		slices.SortFunc(runs, func(a, b indexedRun) int { return a.key.compare(b.key) })
+		sources = [][]indexedRun{narrow(runs)}
+	}
+
+	// the runs still to visit in each source, in ascending order
+	var pending [][]indexedRun
+	for _, runs := range sources {
+		if after != nil {
+			at, found := slices.BinarySearchFunc(runs, *after, func(r indexedRun, k indexKey) int { return r.key.compare(k) })
+			if desc {
+				runs = runs[:at]
+			} else {
+				if found {
+					at++
+				}
+				runs = runs[at:]
+			}
+		}
+
+		if len(runs) > 0 {
+			pending = append(pending, runs)
+		}
+	}
+
+	// merge the partitions, taking the next run in order from whichever
+	// partition holds it
+	var page []indexedRun
+	for len(page) <= size {
+		best := -1
+		for i, runs := range pending {
+			if len(runs) == 0 {
+				continue
+			}
+			if best < 0 {
+				best = i
+				continue
+			}
+			if desc {
+				if runs[len(runs)-1].key.compare(pending[best][len(pending[best])-1].key) > 0 {
+					best = i
+				}
+			} else if runs[0].key.compare(pending[best][0].key) < 0 {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		var r indexedRun
+		if runs := pending[best]; desc {
+			r, pending[best] = runs[len(runs)-1], runs[:len(runs)-1]
+		} else {
+			r, pending[best] = runs[0], runs[1:]
+		}
+
+		run, ok := w.getRun(r.runID)
+		if !ok || !filter.matches(run) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		page = append(page, r)
+	}
+
+	if len(page) > size {
+		page = page[:size]
+		next = runCursor{sort: runSortKeys[sortIdx].name, key: page[size-1].key}.String()
+	}
+
+	ids = make([]string, len(page))
+	for i, r := range page {
+		ids[i] = r.runID
+	}
+
+	return ids, next, count
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/label"
+)
+
+func at(hour, minute int) *time.Time {
+	t := time.Date(2023, 1, 1, hour, minute, 0, 0, time.UTC)
+	return &t
+}
+
+// setupIndexedRuns indexes runs a to e, created in that order:
+//
+//	a  billing  completed  10:00-10:05  step 2  priority 0
+//	b  billing  failed     10:10-10:30  step 1  priority 1
+//	c  export   ongoing    10:20-       step 0  priority 0
+//	d  export   queued                  step 0  priority 5
+//	e  billing  completed  10:40-10:41  step 2  priority 0
+func setupIndexedRuns(t *testing.T) *WorkflowService {
+	svc, _, _, _ := setupService(t)
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.saveRun("a", &Run{workflowName: "billing", start: at(10, 0), end: at(10, 5), currStep: 2})
+	svc.saveRun("b", &Run{workflowName: "billing", failed: true, start: at(10, 10), end: at(10, 30), currStep: 1, priority: 1})
+	svc.saveRun("c", &Run{workflowName: "export", start: at(10, 20)})
+	svc.saveRun("d", &Run{workflowName: "export", queued: true, priority: 5})
+	svc.saveRun("e", &Run{workflowName: "billing", start: at(10, 40), end: at(10, 41), currStep: 2})
+
+	return svc
+}
+
+func runIDs(runs RunsResponse) []string {
+	ids := []string{}
+	for _, run := range runs.Runs {
+		ids = append(ids, run.ID)
+	}
+	return ids
+}
+
+func TestGetRuns_FiltersAndSorting(t *testing.T) {
+	step := 2
+	priority := 0
+
+	tests := []struct {
+		name          string
+		filter        RunsFilter
+		expectedIDs   []string
+		expectedCount int
+	}{
+		{name: "latest start first", expectedIDs: []string{"e", "c", "b", "a", "d"}, expectedCount: 5},
+		{name: "earliest start first", filter: RunsFilter{Order: RunsOrderAsc}, expectedIDs: []string{"d", "a", "b", "c", "e"}, expectedCount: 5},
+		{name: "status", filter: RunsFilter{Status: string(RunStatusCompleted)}, expectedIDs: []string{"e", "a"}, expectedCount: 2},
+		{name: "workflow name", filter: RunsFilter{WorkflowName: "bill"}, expectedIDs: []string{"e", "b", "a"}, expectedCount: 3},
+		{
+			name:          "start time range",
+			filter:        RunsFilter{StartedAfter: *at(10, 10), StartedBefore: *at(10, 40)},
+			expectedIDs:   []string{"c", "b"},
+			expectedCount: 2,
+		},
+		{
+			name:          "end time range sorted by end time",
+			filter:        RunsFilter{EndedAfter: *at(10, 5), Sort: RunsSortEndTime},
+			expectedIDs:   []string{"e", "b", "a"},
+			expectedCount: 3,
+		},
+		{
+			name:          "end time range sorted by start time",
+			filter:        RunsFilter{EndedBefore: *at(10, 30)},
+			expectedIDs:   []string{"a"},
+			expectedCount: -1,
+		},
+		{
+			name:          "minimum duration sorted by duration",
+			filter:        RunsFilter{MinDuration: 5 * time.Minute, Sort: RunsSortDuration},
+			expectedIDs:   []string{"b", "a"},
+			expectedCount: 2,
+		},
+		{name: "current step", filter: RunsFilter{Step: &step}, expectedIDs: []string{"e", "a"}, expectedCount: -1},
+		{
+			name:          "priority",
+			filter:        RunsFilter{Sort: RunsSortPriority},
+			expectedIDs:   []string{"d", "b", "a", "c", "e"},
+			expectedCount: 5,
+		},
+		{
+			name:          "priority filter sorted by priority",
+			filter:        RunsFilter{Priority: &priority, Sort: RunsSortPriority, Order: RunsOrderAsc},
+			expectedIDs:   []string{"e", "c", "a"},
+			expectedCount: 3,
+		},
+		{
+			name:          "runs without an end time come last",
+			filter:        RunsFilter{Sort: RunsSortEndTime},
+			expectedIDs:   []string{"e", "b", "a", "d", "c"},
+			expectedCount: 5,
+		},
+		{
+			name:          "conditions combine",
+			filter:        RunsFilter{WorkflowName: "billing", Step: &step, StartedAfter: *at(10, 30)},
+			expectedIDs:   []string{"e"},
+			expectedCount: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := setupIndexedRuns(t)
+
+			require.NoError(t, tt.filter.Validate())
+			runs := svc.GetRuns(tt.filter)
+			require.Equal(t, tt.expectedIDs, runIDs(runs))
+			require.Equal(t, tt.expectedCount, runs.TotalCount)
+			require.Empty(t, runs.NextCursor)
+		})
+	}
+}
+
+func TestGetRuns_CursorPagination(t *testing.T) {
+	t.Run("pages continue after the previous page", func(t *testing.T) {
+		svc := setupIndexedRuns(t)
+
+		first := svc.GetRuns(RunsFilter{PageSize: 2})
+		require.Equal(t, []string{"e", "c"}, runIDs(first))
+		require.NotEmpty(t, first.NextCursor)
+		require.Equal(t, 3, first.TotalPages)
+
+		// runs created and progressing between pages do not shift the pages
+		svc.mu.Lock()
+		svc.saveRun("f", &Run{workflowName: "billing", start: at(11, 0)})
+		c, _ := svc.store.Get("c")
+		c.(*Run).end = at(11, 5)
+		svc.saveRun("c", c.(*Run))
+		svc.mu.Unlock()
+
+		second := svc.GetRuns(RunsFilter{PageSize: 2, Cursor: first.NextCursor})
+		require.Equal(t, []string{"b", "a"}, runIDs(second))
+
+		third := svc.GetRuns(RunsFilter{PageSize: 2, Cursor: second.NextCursor})
+		require.Equal(t, []string{"d"}, runIDs(third))
+		require.Empty(t, third.NextCursor)
+	})
+
+	t.Run("pages follow the filter and order", func(t *testing.T) {
+		svc := setupIndexedRuns(t)
+
+		filter := RunsFilter{WorkflowName: "billing", Order: RunsOrderAsc, PageSize: 1}
+		var ids []string
+		for {
+			runs := svc.GetRuns(filter)
+			ids = append(ids, runIDs(runs)...)
+			if runs.NextCursor == "" {
+				break
+			}
+			filter.Cursor = runs.NextCursor
+		}
+		require.Equal(t, []string{"a", "b", "e"}, ids)
+	})
+
+	t.Run("page numbers without a cursor", func(t *testing.T) {
+		svc := setupIndexedRuns(t)
+
+		runs := svc.GetRuns(RunsFilter{Page: 2, PageSize: 2})
+		require.Equal(t, []string{"b", "a"}, runIDs(runs))
+		require.Equal(t, 2, runs.Page)
+	})
+}
+
+func TestGetRuns_IndexFollowsRuns(t *testing.T) {
+	svc, uuidProvider, timeProvider, _ := setupService(t)
+	uuidProvider.On("NewString").Return("indexed-run-id")
+	timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	runID := svc.InitiateWorkflow(t.Context(), "test-workflow")
+	require.Equal(t, []string{runID}, runIDs(svc.GetRuns(RunsFilter{Status: string(RunStatusOngoing)})))
+
+	require.NoError(t, svc.CompleteWorkflow(t.Context(), runID))
+	require.Empty(t, runIDs(svc.GetRuns(RunsFilter{Status: string(RunStatusOngoing)})))
+	require.Equal(t, []string{runID}, runIDs(svc.GetRuns(RunsFilter{Status: string(RunStatusCompleted)})))
+
+	svc.mu.Lock()
+	require.Len(t, svc.runs.entries, 1)
+	require.Len(t, svc.runs.partitions, 1)
+	svc.mu.Unlock()
+}
+
+func TestGetRuns_LabelIndex(t *testing.T) {
+	svc := setupIndexedRuns(t)
+
+	svc.mu.Lock()
+	svc.saveRun("f", &Run{workflowName: "billing", start: at(11, 0), labels: map[string]string{"customer_id": "42", "region": "eu"}})
+	svc.saveRun("g", &Run{workflowName: "billing", start: at(11, 5), labels: map[string]string{"customer_id": "42"}})
+	svc.saveRun("h", &Run{workflowName: "export", start: at(11, 10), labels: map[string]string{"customer_id": "7"}})
+	svc.mu.Unlock()
+
+	query := func(spec string, filter RunsFilter) []string {
+		filter.Labels = mustSelector(t, spec)
+		return runIDs(svc.GetRuns(filter))
+	}
+
+	require.Equal(t, []string{"g", "f"}, query("customer_id=42", RunsFilter{}))
+	require.Equal(t, []string{"f", "g"}, query("customer_id=42", RunsFilter{Order: RunsOrderAsc}))
+	require.Equal(t, []string{"f"}, query("customer_id=42,region=eu", RunsFilter{}))
+	require.Equal(t, []string{"g"}, query("customer_id=42,region!=eu", RunsFilter{}))
+	require.Equal(t, []string{"h"}, query("customer_id=7", RunsFilter{WorkflowName: "export"}))
+	require.Empty(t, query("customer_id=7", RunsFilter{WorkflowName: "billing"}))
+	require.Empty(t, query("customer_id=42,customer_id=7", RunsFilter{}))
+
+	// pages continue through the labelled runs
+	first := svc.GetRuns(RunsFilter{Labels: mustSelector(t, "customer_id=42"), PageSize: 1})
+	require.Equal(t, []string{"g"}, runIDs(first))
+	require.Equal(t, []string{"f"}, query("customer_id=42", RunsFilter{Cursor: first.NextCursor, PageSize: 1}))
+
+	// the index follows labels as runs are updated and purged
+	svc.mu.Lock()
+	svc.saveRun("g", &Run{workflowName: "billing", start: at(11, 5), labels: map[string]string{"customer_id": "7"}})
+	svc.runs.drop("f")
+	svc.mu.Unlock()
+
+	require.Empty(t, query("customer_id=42", RunsFilter{}))
+	require.Equal(t, []string{"h", "g"}, query("customer_id=7", RunsFilter{}))
+
+	svc.mu.Lock()
+	require.NotContains(t, svc.runs.labels, "customer_id=42")
+	require.NotContains(t, svc.runs.labels, "region=eu")
+	require.NotContains(t, svc.runs.runLabels, "f")
+	svc.mu.Unlock()
+}
+
+func mustSelector(t *testing.T, spec string) label.Selector {
+	s, err := label.ParseSelector(spec)
+	require.NoError(t, err)
+	return s
+}
+
+func TestRunsFilterValidate(t *testing.T) {
+	startCursor := runCursor{sort: RunsSortStartTime, key: indexKey{k1: 1, seq: 1}}.String()
+
+	require.NoError(t, RunsFilter{}.Validate())
+	require.NoError(t, RunsFilter{Cursor: startCursor}.Validate())
+	require.NoError(t, RunsFilter{Page: 101, PageSize: 100}.Validate())
+	require.NoError(t, RunsFilter{Cursor: startCursor, Page: 500, PageSize: 100}.Validate())
+
+	tests := []struct {
+		name   string
+		filter RunsFilter
+	}{
+		{name: "unknown sort key", filter: RunsFilter{Sort: "name"}},
+		{name: "unknown order", filter: RunsFilter{Order: "up"}},
+		{name: "malformed cursor", filter: RunsFilter{Cursor: "not-a-cursor"}},
+		{name: "cursor of another sort key", filter: RunsFilter{Cursor: startCursor, Sort: RunsSortDuration}},
+		{name: "empty start range", filter: RunsFilter{StartedAfter: *at(10, 0), StartedBefore: *at(10, 0)}},
+		{name: "reversed end range", filter: RunsFilter{EndedAfter: *at(11, 0), EndedBefore: *at(10, 0)}},
+		{name: "negative minimum duration", filter: RunsFilter{MinDuration: -time.Minute}},
+		{name: "page past the page offset limit", filter: RunsFilter{Page: 102, PageSize: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.filter.Validate())
+		})
+	}
+}
+
+func TestAllRuns(t *testing.T) {
+	svc := setupIndexedRuns(t)
+
+	collect := func(filter RunsFilter) []string {
+		var ids []string
+		for run := range svc.AllRuns(filter) {
+			ids = append(ids, run.ID)
+		}
+		return ids
+	}
+
+	// every matching run, whatever the page and page size
+	require.Equal(t, []string{"e", "b", "a"}, collect(RunsFilter{WorkflowName: "billing", Page: 2, PageSize: 1}))
+	require.Equal(t, []string{"a", "e"}, collect(RunsFilter{Status: string(RunStatusCompleted), Order: RunsOrderAsc}))
+
+	first := svc.GetRuns(RunsFilter{PageSize: 2})
+	require.Equal(t, []string{"b", "a", "d"}, collect(RunsFilter{Cursor: first.NextCursor}))
+
+	for run := range svc.AllRuns(RunsFilter{}) {
+		require.Equal(t, "e", run.ID)
+		break
+	}
+}
+
+func TestGetRuns_Namespaces(t *testing.T) {
+	svc := setupIndexedRuns(t)
+
+	svc.mu.Lock()
+	svc.saveRun("f", &Run{workflowName: "invoice", namespace: "billing", start: at(11, 0)})
+	svc.saveRun("g", &Run{workflowName: "invoice", namespace: "billing", start: at(11, 5)})
+	svc.saveRun("h", &Run{workflowName: "report", namespace: "analytics", start: at(11, 10)})
+	svc.mu.Unlock()
+
+	require.Equal(t, []string{"g", "f"}, runIDs(svc.GetRuns(RunsFilter{Namespace: "billing"})))
+	require.Equal(t, []string{"h", "g", "f"}, runIDs(svc.GetRuns(RunsFilter{Namespaces: []string{"billing", "analytics"}})))
+	require.Equal(t, []string{"h"}, runIDs(svc.GetRuns(RunsFilter{Namespace: "analytics", Namespaces: []string{"billing", "analytics"}})))
+	require.Empty(t, runIDs(svc.GetRuns(RunsFilter{Namespace: "analytics", Namespaces: []string{"billing"}})))
+	require.Empty(t, runIDs(svc.GetRuns(RunsFilter{Namespaces: []string{}})))
+	require.Len(t, runIDs(svc.GetRuns(RunsFilter{})), 8)
+}
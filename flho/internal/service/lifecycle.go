@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+// Reasons a run finished, reported in lifecycle callbacks.
+const (
+	reasonRetryElapsed = "retry_after_elapsed"
+	reasonCancelled    = "cancelled"
+)
+
+// errCompensated is returned when resuming a run whose completed steps have
+// been compensated, or are being compensated.
+var errCompensated = errorf(ErrConflict, "run has been compensated")
+
+// Lifecycle events, reported in lifecycle callbacks.
+const (
+	eventCompleted = "run.completed"
+	eventFailed    = "run.failed"
+	eventTimedOut  = "run.timed_out"
+	eventCancelled = "run.cancelled"
+)
+
+// callback is the body of a lifecycle callback. It carries the run summary
+// and the context the run finished in.
+type callback struct {
+	Event         string     `json:"event"`
+	WorkflowName  string     `json:"workflow_name"`
+	WorkflowStep  string     `json:"workflow_step"`
+	WorkflowRunID string     `json:"workflow_run_id"`
+	Status        RunStatus  `json:"status"`
+	Reason        string     `json:"reason,omitempty"`
+	StartTime     *time.Time `json:"start_time"`
+	EndTime       *time.Time `json:"end_time"`
+	DurationMS    int64      `json:"duration_ms"`
+	LastHeartbeat *Heartbeat `json:"last_heartbeat,omitempty"`
+}
+
+// lifecycleHook returns the event name and callback URL configured for a
+// run finishing with the given status.
+func lifecycleHook(settings workflow.Settings, status RunStatus) (event, url string) {
+	switch status {
+	case RunStatusCompleted:
+		return eventCompleted, settings.OnComplete
+	case RunStatusFailed:
+		return eventFailed, settings.OnFailure
+	case RunStatusTimedOut:
+		return eventTimedOut, settings.OnTimeout
+	case RunStatusCancelled:
+		return eventCancelled, settings.OnCancel
+	default:
+		return "", ""
+	}
+}
+
+// finishRun ends the run with the given status: it stops the run's retry
+// countdown and deadline, records the end time, hands the run's concurrency
+// slot to the next queued run, cancels the child runs and compensates the
+// completed steps of a run that did not complete, resumes the run's parent,
+// and sends the workflow's callback for the status, if one is configured. The
+// status is recorded in the run's history as caused by the actor in ctx. The
+// caller must hold w.mu.
+func (w *WorkflowService) finishRun(ctx context.Context, runID string, run *Run, status RunStatus, reason string) {
+	run.stopTimers()
+
+	previous := run.status()
+	switch previous {
+	case RunStatusScheduled:
+		w.unschedule(runID)
+	case RunStatusQueued:
+		w.dequeue(runID, run)
+	}
+
+	switch status {
+	case RunStatusFailed:
+		run.failed = true
+	case RunStatusTimedOut:
+		run.timedOut = true
+	case RunStatusCancelled:
+		run.cancelled = true
+	}
+
+	runEnd := w.timeProvider.Now()
+	run.end = &runEnd
+	w.recordEvent(ctx, run, RunEventType(status), reason)
+
+	w.saveRun(runID, run)
+
+	run.endRunSpan(status, reason)
+	w.metrics.runFinished(run, status)
+	if previous == RunStatusOngoing {
+		w.metrics.stepLeft(run, runEnd)
+		w.releaseSlot(run)
+	}
+
+	if status != RunStatusCompleted {
+		w.cancelChildren(run)
+		w.startCompensation(runID, run, status)
+	}
+
+	w.resumeParent(run, status)
+
+	event, url := lifecycleHook(w.config.GetSettings(run.workflowName), status)
+	if url == "" {
+		return
+	}
+
+	var duration time.Duration
+	if run.start != nil {
+		duration = run.end.Sub(*run.start)
+	}
+
+	step := fmt.Sprintf("step%v", run.currStep)
+	jsonData, _ := json.Marshal(callback{
+		Event:         event,
+		WorkflowName:  run.workflowName,
+		WorkflowStep:  step,
+		WorkflowRunID: runID,
+		Status:        status,
+		Reason:        reason,
+		StartTime:     run.start,
+		EndTime:       run.end,
+		DurationMS:    duration.Milliseconds(),
+		LastHeartbeat: run.heartbeat,
+	})
+
+	// callbacks go through the same delivery path as retry notifications,
+	// so they are retried, guarded by the circuit breakers and
+	// dead-lettered when undeliverable
+	d := delivery{
+		runID:        runID,
+		workflowName: run.workflowName,
+		step:         step,
+		url:          url,
+		body:         jsonData,
+		priority:     run.priority,
+	}
+
+	// the callback outlives the caller, so it is bound to the service
+	// lifetime
+	deliverCtx := w.runSpanContext(w.lifetime(), run)
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		if err := w.deliver(deliverCtx, d); err != nil {
+			w.logger.Error("lifecycle callback unsuccessful", "run_id", runID, "event", event, "error", err.Error())
+		}
+	}()
+}
+
+// CancelWorkflow cancels an ongoing run, stopping its retry countdown and
+// deadline, or a delayed or queued run that has not started yet. The optional
+// reason is passed on in the on_cancel callback.
+func (w *WorkflowService) CancelWorkflow(ctx context.Context, runID, reason string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, ok := w.getRun(runID)
+	if !ok {
+		return runNotFound(runID)
+	}
+
+	if err := checkCancellable(run); err != nil {
+		return err
+	}
+
+	if reason == "" {
+		reason = reasonCancelled
+	}
+	w.finishRun(ctx, runID, run, RunStatusCancelled, reason)
+
+	return nil
+}
+
+// ResumeWorkflow restarts a failed or timed out run at the step it stopped
+// on, as if the step had just been reached: the step's retry countdown and
+// timeout start over, and so does the workflow deadline. The run keeps its
+// start time, and takes a concurrency slot again, waiting in the queue if
+// none is free. Runs whose completed steps were compensated cannot be
+// resumed.
+func (w *WorkflowService) ResumeWorkflow(ctx context.Context, runID string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, ok := w.getRun(runID)
+	if !ok {
+		return runNotFound(runID)
+	}
+
+	if err := checkResumable(run); err != nil {
+		return err
+	}
+
+	run.failed = false
+	run.timedOut = false
+	run.end = nil
+	w.recordEvent(ctx, run, RunEventResumed, "")
+	w.startRunSpan(ctx, runID, run)
+	w.admit(ctx, runID, run)
+
+	w.logger.Info("resumed run", "run_id", runID, "step", run.currStep, "status", run.status())
+
+	return nil
+}
+
+// checkOngoing checks that the run can be advanced or completed.
+func checkOngoing(run *Run) error {
+	switch status := run.status(); status {
+	case RunStatusOngoing:
+		return nil
+	case RunStatusScheduled, RunStatusQueued:
+		return errNotStarted
+	default:
+		return errorf(ErrConflict, "run is already %s", status)
+	}
+}
+
+// checkCancellable checks that the run can be cancelled.
+func checkCancellable(run *Run) error {
+	switch status := run.status(); status {
+	case RunStatusOngoing, RunStatusScheduled, RunStatusQueued:
+		return nil
+	default:
+		return errorf(ErrConflict, "run is already %s", status)
+	}
+}
+
+// checkResumable checks that the run can be resumed.
+func checkResumable(run *Run) error {
+	switch status := run.status(); status {
+	case RunStatusFailed, RunStatusTimedOut:
+	default:
+		return errorf(ErrConflict, "run is %s, only failed or timed out runs can be resumed", status)
+	}
+
+	if run.compensation != nil {
+		return errCompensated
+	}
+	return nil
+}
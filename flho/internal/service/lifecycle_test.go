@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func setupLifecycleService(t *testing.T) (*WorkflowService, *MockHTTPClient, chan callback) {
+	svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(
+		workflow.Workflows{"payments": {
+			{"step0": {RetryAfter: 10 * time.Millisecond, RetryURL: "http://example.com/retry"}},
+			{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		}},
+		map[string]workflow.Settings{"payments": {
+			OnComplete: "http://example.com/complete",
+			OnFailure:  "http://example.com/failure",
+			OnCancel:   "http://example.com/cancel",
+		}},
+	)
+
+	uuidProvider.On("NewString").Return("payments-run-id")
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	// the run is created and started at start
+	timeProvider.On("Now").Return(start).Twice()
+	timeProvider.On("Now").Return(start.Add(90 * time.Second))
+
+	callbacks := make(chan callback, 1)
+	httpClient.On("Do", mock.MatchedBy(func(r *http.Request) bool {
+		return r.URL.Path != "/retry"
+	})).Run(func(args mock.Arguments) {
+		var cb callback
+		data, _ := io.ReadAll(args.Get(0).(*http.Request).Body)
+		_ = json.Unmarshal(data, &cb)
+		callbacks <- cb
+	}).Return(okResponse(), nil)
+	httpClient.On("Do", mock.Anything).Return(okResponse(), nil)
+
+	return svc, httpClient, callbacks
+}
+
+func TestLifecycleCallbacks(t *testing.T) {
+	tests := []struct {
+		name           string
+		finish         func(svc *WorkflowService, runID string)
+		expectedEvent  string
+		expectedStatus RunStatus
+		expectedReason string
+		expectedStep   string
+	}{
+		{
+			name: "completed",
+			finish: func(svc *WorkflowService, runID string) {
+				require.NoError(t, svc.UpdateWorkflow(context.Background(), runID))
+				require.NoError(t, svc.CompleteWorkflow(context.Background(), runID))
+			},
+			expectedEvent:  "run.completed",
+			expectedStatus: RunStatusCompleted,
+			expectedStep:   "step1",
+		},
+		{
+			name: "failed",
+			finish: func(svc *WorkflowService, _ string) {
+				// step0's retry countdown elapses
+				waitForRuns(t, svc)
+			},
+			expectedEvent:  "run.failed",
+			expectedStatus: RunStatusFailed,
+			expectedReason: "retry_after_elapsed",
+			expectedStep:   "step0",
+		},
+		{
+			name: "cancelled",
+			finish: func(svc *WorkflowService, runID string) {
+				require.NoError(t, svc.CancelWorkflow(context.Background(), runID, "customer closed account"))
+			},
+			expectedEvent:  "run.cancelled",
+			expectedStatus: RunStatusCancelled,
+			expectedReason: "customer closed account",
+			expectedStep:   "step0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, callbacks := setupLifecycleService(t)
+
+			runID := svc.InitiateWorkflow(context.Background(), "payments")
+			tt.finish(svc, runID)
+			waitForRuns(t, svc)
+
+			var cb callback
+			select {
+			case cb = <-callbacks:
+			case <-time.After(time.Second):
+				t.Fatal("no callback delivered")
+			}
+
+			require.Equal(t, tt.expectedEvent, cb.Event)
+			require.Equal(t, tt.expectedStatus, cb.Status)
+			require.Equal(t, tt.expectedReason, cb.Reason)
+			require.Equal(t, tt.expectedStep, cb.WorkflowStep)
+			require.Equal(t, runID, cb.WorkflowRunID)
+			require.Equal(t, "payments", cb.WorkflowName)
+			require.Equal(t, int64(90000), cb.DurationMS)
+			require.Empty(t, callbacks, "only a single callback is sent")
+		})
+	}
+}
+
+func TestCancelWorkflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		runID       string
+		setupStore  func(*WorkflowService)
+		expectedErr string
+	}{
+		{
+			name:  "successful cancellation",
+			runID: "valid-run-id",
+			setupStore: func(svc *WorkflowService) {
+				_, cancel := context.WithCancel(context.Background())
+				svc.store.Set("valid-run-id", &Run{workflowName: "test-workflow", retryCancel: cancel})
+			},
+		},
+		{
+			name:        "run not found",
+			runID:       "missing-run-id",
+			setupStore:  func(_ *WorkflowService) {},
+			expectedErr: "no data found for run ID: missing-run-id",
+		},
+		{
+			name:  "run already completed",
+			runID: "completed-run-id",
+			setupStore: func(svc *WorkflowService) {
+				end := time.Now()
+				svc.store.Set("completed-run-id", &Run{workflowName: "test-workflow", end: &end})
+			},
+			expectedErr: "run is already completed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, timeProvider, store := setupService(t)
+			timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+
+			tt.setupStore(svc)
+
+			err := svc.CancelWorkflow(context.Background(), tt.runID, "")
+
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			runValue, _ := store.Get(tt.runID)
+			run := runValue.(*Run)
+			require.Equal(t, RunStatusCancelled, run.status())
+			require.NotNil(t, run.end)
+		})
+	}
+}
+
+func TestCompleteWorkflowRejectsFinishedRun(t *testing.T) {
+	svc, _, _, store := setupService(t)
+
+	_, cancel := context.WithCancel(context.Background())
+	end := time.Now()
+	store.Set("failed-run-id", &Run{failed: true, retryCancel: cancel, end: &end})
+
+	err := svc.CompleteWorkflow(context.Background(), "failed-run-id")
+	require.EqualError(t, err, "run is already failed")
+}
+
+func TestResumeWorkflow(t *testing.T) {
+	t.Run("a resumed run restarts its step", func(t *testing.T) {
+		svc, _, callbacks := setupLifecycleService(t)
+
+		runID := svc.InitiateWorkflow(context.Background(), "payments")
+		waitForRuns(t, svc)
+		require.Equal(t, "run.failed", (<-callbacks).Event)
+
+		require.NoError(t, svc.ResumeWorkflow(context.Background(), runID))
+		requireStatus(t, svc, runID, RunStatusOngoing)
+
+		// step0's retry countdown elapses again
+		waitForRuns(t, svc)
+		require.Equal(t, "run.failed", (<-callbacks).Event)
+		requireStatus(t, svc, runID, RunStatusFailed)
+	})
+
+	t.Run("a resumed run waits for a slot and keeps its start time", func(t *testing.T) {
+		svc := setupQueueService(t, 1)
+		svc.InitiateWorkflow(context.Background(), "export")
+		svc.InitiateWorkflow(context.Background(), "export")
+		started, err := svc.GetRun("run-1")
+		require.NoError(t, err)
+
+		svc.markRunAsFailed("run-1")
+		requireStatus(t, svc, "run-2", RunStatusOngoing)
+		require.EqualError(t, svc.UpdateWorkflow(context.Background(), "run-1"), "run is already failed")
+
+		require.NoError(t, svc.ResumeWorkflow(context.Background(), "run-1"))
+		requireStatus(t, svc, "run-1", RunStatusQueued)
+
+		require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-2"))
+		resumed, err := svc.GetRun("run-1")
+		require.NoError(t, err)
+		require.Equal(t, RunStatusOngoing, resumed.Status)
+		require.Equal(t, started.StartTime, resumed.StartTime)
+		require.Nil(t, resumed.EndTime)
+	})
+
+	t.Run("only failed and timed out runs are resumed", func(t *testing.T) {
+		svc := setupQueueService(t, 0)
+		for range 4 {
+			svc.InitiateWorkflow(context.Background(), "export")
+		}
+		svc.markRunAsTimedOut("run-1", "step0", timeoutReasonStep)
+		require.NoError(t, svc.CancelWorkflow(context.Background(), "run-2", ""))
+		svc.markRunAsFailed("run-3")
+		svc.mu.Lock()
+		compensated, _ := svc.getRun("run-3")
+		compensated.compensation = &Compensation{Phase: CompensationDone, Steps: []string{"step0"}, Completed: 1}
+		svc.mu.Unlock()
+
+		require.NoError(t, svc.ResumeWorkflow(context.Background(), "run-1"))
+		requireStatus(t, svc, "run-1", RunStatusOngoing)
+		require.EqualError(t, svc.ResumeWorkflow(context.Background(), "run-2"), "run is cancelled, only failed or timed out runs can be resumed")
+		require.EqualError(t, svc.ResumeWorkflow(context.Background(), "run-3"), "run has been compensated")
+		require.EqualError(t, svc.ResumeWorkflow(context.Background(), "run-4"), "run is ongoing, only failed or timed out runs can be resumed")
+		require.Error(t, svc.ResumeWorkflow(context.Background(), "missing"))
+	})
+}
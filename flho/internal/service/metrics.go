@@ -0,0 +1,117 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/windevkay/forge/flho/internal/metrics"
+)
+
+// runDurationBuckets are the buckets, in seconds, of run and step durations,
+// which range from seconds to days.
+var runDurationBuckets = []float64{1, 5, 15, 60, 300, 900, 3600, 4 * 3600, 12 * 3600, 24 * 3600, 7 * 24 * 3600}
+
+// WithMetrics records the service's metrics on reg.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(w *WorkflowService) {
+		w.metrics = newServiceMetrics(reg)
+	}
+}
+
+// serviceMetrics are the metrics recorded by the service. A nil
+// *serviceMetrics records nothing.
+type serviceMetrics struct {
+	runsStarted         *metrics.CounterVec
+	runsFinished        *metrics.CounterVec
+	runDuration         *metrics.HistogramVec
+	stepDuration        *metrics.HistogramVec
+	notifications       *metrics.CounterVec
+	notificationLatency *metrics.HistogramVec
+	stepProcessors      *metrics.Gauge
+	runsActive          *metrics.GaugeVec
+	runsQueued          *metrics.GaugeVec
+}
+
+func newServiceMetrics(reg *metrics.Registry) *serviceMetrics {
+	return &serviceMetrics{
+		runsStarted: reg.Counter("flho_runs_started_total",
+			"Runs that started processing their first step.", "workflow"),
+		runsFinished: reg.Counter("flho_runs_finished_total",
+			"Runs that finished, by final status.", "workflow", "status"),
+		runDuration: reg.Histogram("flho_run_duration_seconds",
+			"Time from a run's start to its end, by final status.", runDurationBuckets, "workflow", "status"),
+		stepDuration: reg.Histogram("flho_step_duration_seconds",
+			"Time runs spent in a step before moving on or finishing.", runDurationBuckets, "workflow", "step"),
+		notifications: reg.Counter("flho_notification_attempts_total",
+			"Attempts to deliver a retry notification, callback or compensation, by response status code, or \"error\" when no response was received.", "workflow", "code"),
+		notificationLatency: reg.Histogram("flho_notification_duration_seconds",
+			"Time taken by notification attempts, by response status code.", metrics.DefBuckets, "code"),
+		stepProcessors: reg.Gauge("flho_step_processors_active",
+			"Steps being processed, waiting on their retry countdown, heartbeats or timeout.").With(),
+		runsActive: reg.Gauge("flho_runs_active",
+			"Runs in progress, holding a slot of their workflow.", "workflow"),
+		runsQueued: reg.Gauge("flho_runs_queued",
+			"Runs waiting for a slot under max_concurrent_runs or their namespace's max_active_runs.", "workflow"),
+	}
+}
+
+// runStarted records a run starting its first step.
+func (m *serviceMetrics) runStarted(workflowName string) {
+	if m == nil {
+		return
+	}
+	m.runsStarted.With(workflowName).Inc()
+}
+
+// runFinished records a run finishing, and how long it took if it started.
+func (m *serviceMetrics) runFinished(run *Run, status RunStatus) {
+	if m == nil {
+		return
+	}
+	m.runsFinished.With(run.workflowName, string(status)).Inc()
+	if run.start != nil && run.end != nil {
+		m.runDuration.With(run.workflowName, string(status)).Observe(run.end.Sub(*run.start).Seconds())
+	}
+}
+
+// stepLeft records how long the run spent in its current step, up to now.
+func (m *serviceMetrics) stepLeft(run *Run, now time.Time) {
+	if m == nil || run.stepStart == nil {
+		return
+	}
+	step := "step" + strconv.Itoa(run.currStep)
+	m.stepDuration.With(run.workflowName, step).Observe(now.Sub(*run.stepStart).Seconds())
+}
+
+// notificationAttempted records a single delivery attempt, answered with
+// code, or with no response at all when code is zero.
+func (m *serviceMetrics) notificationAttempted(workflowName string, code int, took time.Duration) {
+	if m == nil {
+		return
+	}
+	label := "error"
+	if code != 0 {
+		label = strconv.Itoa(code)
+	}
+	m.notifications.With(workflowName, label).Inc()
+	m.notificationLatency.With(label).Observe(took.Seconds())
+}
+
+// queueChanged records the runs in progress and queued of the workflow.
+func (m *serviceMetrics) queueChanged(workflowName string, q *runQueue) {
+	if m == nil {
+		return
+	}
+	m.runsActive.With(workflowName).Set(float64(q.active))
+	m.runsQueued.With(workflowName).Set(float64(len(q.waiting)))
+}
+
+// stepProcessing records a processStep goroutine starting, and returns the
+// func recording it returning.
+func (m *serviceMetrics) stepProcessing() func() {
+	if m == nil {
+		return func() {}
+	}
+	m.stepProcessors.Inc()
+	return m.stepProcessors.Dec
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/metrics"
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func scrape(t *testing.T, reg *metrics.Registry) string {
+	t.Helper()
+
+	var out strings.Builder
+	_, err := reg.WriteTo(&out)
+	require.NoError(t, err)
+	return out.String()
+}
+
+func TestMetrics(t *testing.T) {
+	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(workflow.Workflows{
+		"export": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		},
+		"billing": {
+			{"step0": {RetryAfter: 10 * time.Millisecond, RetryURL: "http://example.com/retry"}},
+		},
+	}, nil)
+	reg := metrics.NewRegistry()
+	WithMetrics(reg)(svc)
+
+	httpClient.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader(""))}, nil)
+	uuidProvider.On("NewString").Return("export-run").Once()
+	uuidProvider.On("NewString").Return("billing-run").Once()
+	uuidProvider.On("NewString").Return("dead-letter-id")
+	timeProvider.On("Now").Return(fixedTime)
+
+	svc.InitiateWorkflow(context.Background(), "export")
+	require.Contains(t, scrape(t, reg), `flho_runs_started_total{workflow="export"} 1`)
+	require.Eventually(t, func() bool {
+		return strings.Contains(scrape(t, reg), "flho_step_processors_active 1")
+	}, time.Second, time.Millisecond)
+
+	// the export run spent 30s in its first step and took two minutes in all
+	svc.mu.Lock()
+	run, _ := svc.getRun("export-run")
+	stepStart, start := fixedTime.Add(-30*time.Second), fixedTime.Add(-2*time.Minute)
+	run.stepStart, run.start = &stepStart, &start
+	svc.mu.Unlock()
+
+	require.NoError(t, svc.UpdateWorkflow(context.Background(), "export-run"))
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "export-run"))
+
+	// the billing run fails once its retry notification is undeliverable
+	svc.InitiateWorkflow(context.Background(), "billing")
+	require.Eventually(t, func() bool {
+		run, err := svc.GetRun("billing-run")
+		require.NoError(t, err)
+		return run.Status == RunStatusFailed
+	}, time.Second, time.Millisecond)
+	svc.wg.Wait()
+
+	out := scrape(t, reg)
+	for _, line := range []string{
+		`flho_runs_started_total{workflow="billing"} 1`,
+		`flho_runs_finished_total{workflow="billing",status="failed"} 1`,
+		`flho_runs_finished_total{workflow="export",status="completed"} 1`,
+		`flho_run_duration_seconds_bucket{workflow="export",status="completed",le="60"} 0`,
+		`flho_run_duration_seconds_bucket{workflow="export",status="completed",le="300"} 1`,
+		`flho_run_duration_seconds_sum{workflow="export",status="completed"} 120`,
+		`flho_step_duration_seconds_sum{workflow="export",step="step0"} 30`,
+		`flho_step_duration_seconds_count{workflow="export",step="step1"} 1`,
+		`flho_step_duration_seconds_count{workflow="billing",step="step0"} 1`,
+		`flho_notification_attempts_total{workflow="billing",code="502"} 2`,
+		`flho_notification_duration_seconds_count{code="502"} 2`,
+		"flho_step_processors_active 0",
+	} {
+		require.Contains(t, out, line)
+	}
+}
+
+func TestQueueMetrics(t *testing.T) {
+	svc := setupQueueService(t, 2)
+	reg := metrics.NewRegistry()
+	WithMetrics(reg)(svc)
+
+	requireGauges := func(active, queued int) {
+		t.Helper()
+		out := scrape(t, reg)
+		require.Contains(t, out, fmt.Sprintf(`flho_runs_active{workflow="export"} %d`, active))
+		require.Contains(t, out, fmt.Sprintf(`flho_runs_queued{workflow="export"} %d`, queued))
+	}
+
+	for range 4 {
+		svc.InitiateWorkflow(context.Background(), "export")
+	}
+	requireGauges(2, 2)
+
+	// a queued run takes the freed slot
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-1"))
+	requireGauges(2, 1)
+
+	// cancelling a queued run leaves the queue
+	require.NoError(t, svc.CancelWorkflow(context.Background(), "run-4", ""))
+	requireGauges(2, 0)
+
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-2"))
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-3"))
+	requireGauges(0, 0)
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	var m *serviceMetrics
+	run := &Run{workflowName: "export"}
+
+	require.NotPanics(t, func() {
+		m.runStarted("export")
+		m.runFinished(run, RunStatusCompleted)
+		m.stepLeft(run, time.Now())
+		m.notificationAttempted("export", http.StatusOK, time.Second)
+		m.stepProcessing()()
+		m.queueChanged("export", &runQueue{})
+	})
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sort"
+)
+
+// QueueStatus is a snapshot of a workflow's concurrency slots and queue, for
+// display purposes.
+type QueueStatus struct {
+	WorkflowName string
+	Namespace    string
+	Active       int // runs in progress
+	Queued       int // runs waiting for a slot
+	Limit        int // max_concurrent_runs, zero when unlimited
+}
+
+// QuotaStatus is a snapshot of a namespace's quota on active runs, for
+// display purposes.
+type QuotaStatus struct {
+	Namespace string
+	Active    int // runs in progress
+	Limit     int // max_active_runs
+}
+
+// runQueue tracks a workflow's runs in progress and the runs waiting for a
+// slot under its max_concurrent_runs or its namespace's max_active_runs,
+// highest priority first and oldest first within a priority.
+type runQueue struct {
+	active  int
+	waiting []queuedRun
+}
+
+// queuedRun is a run waiting for a slot.
+type queuedRun struct {
+	runID    string
+	priority int
+	seq      uint64 // orders runs of equal priority across the workflows of a namespace
+	child    bool   // exempt from the namespace's quota
+}
+
+// before reports whether r starts ahead of o when both wait for a slot.
+func (r queuedRun) before(o queuedRun) bool {
+	if r.priority != o.priority {
+		return r.priority > o.priority
+	}
+	return r.seq < o.seq
+}
+
+// enqueue adds the run behind the queued runs of the same or a higher
+// priority.
+func (q *runQueue) enqueue(run queuedRun) {
+	i := slices.IndexFunc(q.waiting, func(r queuedRun) bool { return r.priority < run.priority })
+	if i < 0 {
+		i = len(q.waiting)
+	}
+	q.waiting = slices.Insert(q.waiting, i, run)
+}
+
+// queue returns the workflow's run queue, creating it on first use. The
+// caller must hold w.mu.
+func (w *WorkflowService) queue(name string) *runQueue {
+	if w.queues == nil {
+		w.queues = make(map[string]*runQueue)
+	}
+
+	q, ok := w.queues[name]
+	if !ok {
+		q = &runQueue{}
+		w.queues[name] = q
+	}
+
+	return q
+}
+
+// hasSlot reports whether the workflow and its namespace both have room for
+// another run in progress. A child run only needs room in its workflow: its
+// parent holds a slot of the namespace while it waits on the child, so
+// counting the child against the quota as well could leave the two waiting on
+// each other. The caller must hold w.mu.
+func (w *WorkflowService) hasSlot(name, namespace string, child bool) bool {
+	if limit := w.config.GetSettings(name).MaxConcurrentRuns; limit > 0 && w.queue(name).active >= limit {
+		return false
+	}
+	if child {
+		return true
+	}
+	if quota := w.config.GetNamespace(namespace).MaxActiveRuns; quota > 0 && w.namespaceActive[namespace] >= quota {
+		return false
+	}
+	return true
+}
+
+// takeSlot counts the run as in progress in its workflow and, unless it is a
+// child run, its namespace. The caller must hold w.mu.
+func (w *WorkflowService) takeSlot(run *Run) {
+	if w.namespaceActive == nil {
+		w.namespaceActive = make(map[string]int)
+	}
+
+	q := w.queue(run.workflowName)
+	q.active++
+	if !run.isChild() {
+		w.namespaceActive[run.namespace]++
+	}
+	w.metrics.queueChanged(run.workflowName, q)
+}
+
+// isChild reports whether the run was started by a step of another run.
+func (r *Run) isChild() bool {
+	return r.parentRunID != ""
+}
+
+// admit begins the run if its workflow and namespace have a free slot, and
+// queues it otherwise. The caller must hold w.mu.
+func (w *WorkflowService) admit(ctx context.Context, runID string, run *Run) {
+	if !w.hasSlot(run.workflowName, run.namespace, run.isChild()) {
+		run.queued = true
+		w.queueSeq++
+		q := w.queue(run.workflowName)
+		q.enqueue(queuedRun{runID: runID, priority: run.priority, seq: w.queueSeq, child: run.isChild()})
+		w.metrics.queueChanged(run.workflowName, q)
+		w.recordEvent(ctx, run, RunEventQueued, "")
+		w.saveRun(runID, run)
+		return
+	}
+
+	w.takeSlot(run)
+	w.begin(ctx, runID, run)
+}
+
+// releaseSlot frees the slot of a run that was in progress and begins queued
+// runs of its namespace while slots are available. The caller must hold
+// w.mu.
+func (w *WorkflowService) releaseSlot(run *Run) {
+	q := w.queue(run.workflowName)
+	if q.active > 0 {
+		q.active--
+	}
+	w.metrics.queueChanged(run.workflowName, q)
+	if !run.isChild() && w.namespaceActive[run.namespace] > 0 {
+		w.namespaceActive[run.namespace]--
+	}
+
+	for {
+		runID, next, ok := w.nextQueued(run.namespace)
+		if !ok {
+			return
+		}
+
+		next.queued = false
+		w.takeSlot(next)
+		w.begin(w.lifetime(), runID, next)
+	}
+}
+
+// nextQueued takes the queued run of the namespace that should start next:
+// the first in priority and arrival order among the runs at the head of their
+// workflow's queue that have a free slot. The caller must hold w.mu.
+func (w *WorkflowService) nextQueued(namespace string) (string, *Run, bool) {
+	for {
+		var best *runQueue
+		var bestName string
+		for name, q := range w.queues {
+			if len(q.waiting) == 0 || w.config.NamespaceOf(name) != namespace || !w.hasSlot(name, namespace, q.waiting[0].child) {
+				continue
+			}
+			if best == nil || q.waiting[0].before(best.waiting[0]) {
+				best, bestName = q, name
+			}
+		}
+		if best == nil {
+			return "", nil, false
+		}
+
+		runID := best.waiting[0].runID
+		best.waiting = best.waiting[1:]
+		w.metrics.queueChanged(bestName, best)
+
+		if run, ok := w.getRun(runID); ok && run.status() == RunStatusQueued {
+			return runID, run, true
+		}
+	}
+}
+
+// dequeue removes a queued run that is finishing before it got a slot. The
+// caller must hold w.mu.
+func (w *WorkflowService) dequeue(runID string, run *Run) {
+	q := w.queue(run.workflowName)
+	q.waiting = slices.DeleteFunc(q.waiting, func(r queuedRun) bool { return r.runID == runID })
+	w.metrics.queueChanged(run.workflowName, q)
+}
+
+// queueStatuses returns the slots and queue of every workflow of the
+// namespaces with runs in progress or queued, sorted by workflow name. The
+// caller must hold w.mu.
+func (w *WorkflowService) queueStatuses(namespaces []string) []QueueStatus {
+	var statuses []QueueStatus
+	for name, q := range w.queues {
+		if q.active == 0 && len(q.waiting) == 0 {
+			continue
+		}
+		namespace := w.config.NamespaceOf(name)
+		if !InNamespaces(namespace, namespaces) {
+			continue
+		}
+		statuses = append(statuses, QueueStatus{
+			WorkflowName: name,
+			Namespace:    namespace,
+			Active:       q.active,
+			Queued:       len(q.waiting),
+			Limit:        w.config.GetSettings(name).MaxConcurrentRuns,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].WorkflowName < statuses[j].WorkflowName
+	})
+
+	return statuses
+}
+
+// quotaStatuses returns the quota of every namespace among namespaces that
+// has one, sorted by namespace. The caller must hold w.mu.
+func (w *WorkflowService) quotaStatuses(namespaces []string) []QuotaStatus {
+	var statuses []QuotaStatus
+	for _, namespace := range w.config.QuotaNamespaces() {
+		if !InNamespaces(namespace, namespaces) {
+			continue
+		}
+		statuses = append(statuses, QuotaStatus{
+			Namespace: namespace,
+			Active:    w.namespaceActive[namespace],
+			Limit:     w.config.GetNamespace(namespace).MaxActiveRuns,
+		})
+	}
+
+	return statuses
+}
+
+// Queues returns the slots and queue of every workflow with runs in progress
+// or queued, sorted by workflow name.
+func (w *WorkflowService) Queues() []QueueStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.queueStatuses(nil)
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func setupQueueService(t *testing.T, limit int) *WorkflowService {
+	svc, _, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(
+		workflow.Workflows{"export": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		}},
+		map[string]workflow.Settings{"export": {MaxConcurrentRuns: limit}},
+	)
+
+	for i := 1; i <= 5; i++ {
+		uuidProvider.On("NewString").Return(fmt.Sprintf("run-%d", i)).Once()
+	}
+	timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	return svc
+}
+
+func requireStatus(t *testing.T, svc *WorkflowService, runID string, expected RunStatus) {
+	t.Helper()
+
+	run, err := svc.GetRun(runID)
+	require.NoError(t, err)
+	require.Equal(t, expected, run.Status, runID)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	t.Run("runs over the limit are queued until a slot frees up", func(t *testing.T) {
+		svc := setupQueueService(t, 2)
+
+		for range 4 {
+			svc.InitiateWorkflow(context.Background(), "export")
+		}
+
+		requireStatus(t, svc, "run-1", RunStatusOngoing)
+		requireStatus(t, svc, "run-2", RunStatusOngoing)
+		requireStatus(t, svc, "run-3", RunStatusQueued)
+		requireStatus(t, svc, "run-4", RunStatusQueued)
+		require.Equal(t, []QueueStatus{{WorkflowName: "export", Namespace: workflow.DefaultNamespace, Active: 2, Queued: 2, Limit: 2}}, svc.Queues())
+
+		queued, err := svc.GetRun("run-3")
+		require.NoError(t, err)
+		require.Nil(t, queued.StartTime)
+
+		// the oldest queued run takes the freed slot
+		require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-1"))
+		requireStatus(t, svc, "run-3", RunStatusOngoing)
+		requireStatus(t, svc, "run-4", RunStatusQueued)
+
+		// failed runs free their slot too
+		svc.markRunAsFailed("run-2")
+		requireStatus(t, svc, "run-4", RunStatusOngoing)
+
+		runs := svc.GetRuns(RunsFilter{Page: 1, PageSize: 10})
+		require.Equal(t, []QueueStatus{{WorkflowName: "export", Namespace: workflow.DefaultNamespace, Active: 2, Queued: 0, Limit: 2}}, runs.Queues)
+	})
+
+	t.Run("queued runs can be cancelled but not advanced", func(t *testing.T) {
+		svc := setupQueueService(t, 1)
+
+		svc.InitiateWorkflow(context.Background(), "export")
+		svc.InitiateWorkflow(context.Background(), "export")
+		svc.InitiateWorkflow(context.Background(), "export")
+
+		require.EqualError(t, svc.UpdateWorkflow(context.Background(), "run-2"), "run has not started yet")
+		require.EqualError(t, svc.CompleteWorkflow(context.Background(), "run-2"), "run has not started yet")
+
+		require.NoError(t, svc.CancelWorkflow(context.Background(), "run-2", ""))
+		requireStatus(t, svc, "run-2", RunStatusCancelled)
+
+		// cancelling a queued run does not free a slot
+		requireStatus(t, svc, "run-3", RunStatusQueued)
+		require.Equal(t, []QueueStatus{{WorkflowName: "export", Namespace: workflow.DefaultNamespace, Active: 1, Queued: 1, Limit: 1}}, svc.Queues())
+
+		// and the cancelled run is skipped once one frees up
+		require.NoError(t, svc.CancelWorkflow(context.Background(), "run-1", ""))
+		requireStatus(t, svc, "run-2", RunStatusCancelled)
+		requireStatus(t, svc, "run-3", RunStatusOngoing)
+	})
+
+	t.Run("workflows without a limit are never queued", func(t *testing.T) {
+		svc := setupQueueService(t, 0)
+
+		for range 5 {
+			svc.InitiateWorkflow(context.Background(), "export")
+		}
+
+		runs := svc.GetRuns(RunsFilter{Status: string(RunStatusQueued), Page: 1, PageSize: 10})
+		require.Zero(t, runs.TotalCount)
+		require.Equal(t, []QueueStatus{{WorkflowName: "export", Namespace: workflow.DefaultNamespace, Active: 5}}, svc.Queues())
+	})
+}
+
+func TestRunPriority(t *testing.T) {
+	svc := setupQueueService(t, 1)
+
+	for _, priority := range []int{0, 0, 5, 5, 1} {
+		svc.InitiateWorkflowWithOptions(context.Background(), "export", RunOptions{Priority: priority})
+	}
+
+	// higher priorities take freed slots first, oldest first within a priority
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-1"))
+	requireStatus(t, svc, "run-3", RunStatusOngoing)
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-3"))
+	requireStatus(t, svc, "run-4", RunStatusOngoing)
+	requireStatus(t, svc, "run-5", RunStatusQueued)
+	requireStatus(t, svc, "run-2", RunStatusQueued)
+
+	runs := svc.GetRuns(RunsFilter{Sort: RunsSortPriority, Page: 1, PageSize: 10})
+	var ids []string
+	for _, run := range runs.Runs {
+		ids = append(ids, run.ID)
+	}
+	// runs of a priority are listed oldest first
+	require.Equal(t, []string{"run-3", "run-4", "run-5", "run-1", "run-2"}, ids)
+
+	priority := 5
+	runs = svc.GetRuns(RunsFilter{Priority: &priority, Sort: RunsSortPriority, Page: 1, PageSize: 10})
+	require.Equal(t, 2, runs.TotalCount)
+	for _, run := range runs.Runs {
+		require.Equal(t, 5, run.Priority)
+	}
+}
+
+func TestNamespaceQuota(t *testing.T) {
+	svc := setupQueueService(t, 0)
+	svc.config = workflow.NewConfigStore(
+		workflow.Workflows{
+			"invoice": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+			"refund":  {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+			"export":  {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+		},
+		map[string]workflow.Settings{
+			"invoice": {Namespace: "billing"},
+			"refund":  {Namespace: "billing", MaxConcurrentRuns: 1},
+		},
+	).WithNamespaces(map[string]workflow.Namespace{"billing": {MaxActiveRuns: 2}})
+
+	svc.InitiateWorkflow(context.Background(), "invoice")
+	svc.InitiateWorkflow(context.Background(), "refund")
+	svc.InitiateWorkflowWithOptions(context.Background(), "invoice", RunOptions{Priority: 1})
+	svc.InitiateWorkflow(context.Background(), "refund")
+	// other namespaces are not limited by the quota
+	svc.InitiateWorkflow(context.Background(), "export")
+
+	requireStatus(t, svc, "run-1", RunStatusOngoing)
+	requireStatus(t, svc, "run-2", RunStatusOngoing)
+	requireStatus(t, svc, "run-3", RunStatusQueued)
+	requireStatus(t, svc, "run-4", RunStatusQueued)
+	requireStatus(t, svc, "run-5", RunStatusOngoing)
+
+	run, err := svc.GetRun("run-1")
+	require.NoError(t, err)
+	require.Equal(t, "billing", run.Namespace)
+
+	runs := svc.GetRuns(RunsFilter{Namespaces: []string{"billing"}})
+	require.Equal(t, []QuotaStatus{{Namespace: "billing", Active: 2, Limit: 2}}, runs.Quotas)
+	require.Len(t, runs.Queues, 2)
+
+	// the freed slot goes to the highest priority run of the namespace
+	// whose workflow has room
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-1"))
+	requireStatus(t, svc, "run-3", RunStatusOngoing)
+	requireStatus(t, svc, "run-4", RunStatusQueued)
+
+	// refund is at its own limit, so a slot of the namespace is not enough
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-3"))
+	requireStatus(t, svc, "run-4", RunStatusQueued)
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), "run-2"))
+	requireStatus(t, svc, "run-4", RunStatusOngoing)
+}
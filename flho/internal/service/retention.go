@@ -0,0 +1,223 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+const (
+	// janitorInterval is how often finished runs are checked against their
+	// retention.
+	janitorInterval = time.Minute
+	// maxPurgeBatch bounds how many runs are purged at once, so that w.mu is
+	// not held for long when a large backlog of runs expires together.
+	maxPurgeBatch = 1000
+)
+
+// WithRetention sets how long finished runs are kept, per final status, for
+// workflows that do not set their own retention. Expired runs are purged from
+// the store and the run index by a background janitor.
+func WithRetention(retention workflow.Retention) Option {
+	return func(w *WorkflowService) {
+		w.retention = retention
+	}
+}
+
+// WithRunArchive archives runs to gzip-compressed JSON Lines files in dir
+// before they are purged, one file per day. Runs are not purged while they
+// cannot be archived.
+func WithRunArchive(dir string) Option {
+	return func(w *WorkflowService) {
+		w.archiveDir = dir
+	}
+}
+
+// retentionPeriod returns how long finished runs of the workflow in the given
+// status are kept, or zero if they are kept forever. Runs that have not
+// finished are always kept.
+func (w *WorkflowService) retentionPeriod(workflowName string, status RunStatus) time.Duration {
+	retention := w.config.GetSettings(workflowName).Retention.Or(w.retention)
+
+	switch status {
+	case RunStatusCompleted:
+		return retention.Completed
+	case RunStatusFailed:
+		return retention.Failed
+	case RunStatusTimedOut:
+		return retention.TimedOut
+	case RunStatusCancelled:
+		return retention.Cancelled
+	default:
+		return 0
+	}
+}
+
+// purgesRuns reports whether any workflow's finished runs expire.
+func (w *WorkflowService) purgesRuns() bool {
+	if !w.retention.IsZero() {
+		return true
+	}
+	for name := range w.config.GetWorkflows() {
+		if !w.config.GetSettings(name).Retention.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// runJanitor purges expired runs every janitorInterval, until ctx is done.
+func (w *WorkflowService) runJanitor(ctx context.Context) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			purged, err := w.purgeExpiredRuns(w.timeProvider.Now())
+			if err != nil {
+				w.logger.Error("failed to purge expired runs", "error", err.Error())
+				break
+			}
+			if purged < maxPurgeBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpiredRuns archives and purges up to maxPurgeBatch runs whose
+// retention has passed by now, oldest first, and returns how many were
+// purged.
+func (w *WorkflowService) purgeExpiredRuns(now time.Time) (int, error) {
+	w.mu.Lock()
+	expired := w.expiredRuns(now)
+	w.mu.Unlock()
+
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	// archive outside w.mu, so that archiving does not hold up other runs
+	if w.archiveDir != "" {
+		if err := w.archiveRuns(now, expired); err != nil {
+			return 0, fmt.Errorf("archiving runs: %w", err)
+		}
+	}
+
+	purged := w.purgeRuns(expired)
+	w.logger.Info("purged expired runs", "count", purged, "archived", w.archiveDir != "")
+
+	return purged, nil
+}
+
+// purgeRuns removes the expired runs from the store and the run index, and
+// returns how many were removed. Runs that were resumed since they were found
+// to have expired are kept, as are runs that have ended again since.
+func (w *WorkflowService) purgeRuns(expired []RunInfo) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	purged := 0
+	for _, info := range expired {
+		run, ok := w.getRun(info.ID)
+		if !ok || run.end == nil || info.EndTime == nil || !run.end.Equal(*info.EndTime) {
+			continue
+		}
+		w.store.Delete(info.ID)
+		w.runs.drop(info.ID)
+		w.watchers.notify(info.ID)
+		purged++
+	}
+
+	return purged
+}
+
+// expiredRuns returns up to maxPurgeBatch finished runs whose retention has
+// passed by now, ordered by end time. Runs that are still being compensated
+// are kept until compensation is over. Each partition is visited only up to
+// its first maxPurgeBatch expired runs, which are already ordered by end
+// time, so a backlog of expired runs does not make every pass longer. The
+// caller must hold w.mu.
+func (w *WorkflowService) expiredRuns(now time.Time) []RunInfo {
+	byEnd := sortKeyIndex(RunsSortEndTime)
+
+	var expired []indexedRun
+	for key, p := range w.runs.partitions {
+		period := w.retentionPeriod(key.workflowName, key.status)
+		if period == 0 {
+			continue
+		}
+
+		cutoff := now.Add(-period).UnixNano()
+		taken := 0
+		for _, r := range p[byEnd] {
+			if r.key.k1 >= cutoff || taken == maxPurgeBatch {
+				break
+			}
+			if run, ok := w.getRun(r.runID); !ok || (run.compensation != nil && run.compensation.Phase == CompensationRunning) {
+				continue
+			}
+			expired = append(expired, r)
+			taken++
+		}
+	}
+
+	slices.SortFunc(expired, func(a, b indexedRun) int { return a.key.compare(b.key) })
+	expired = expired[:min(len(expired), maxPurgeBatch)]
+
+	runs := make([]RunInfo, 0, len(expired))
+	for _, r := range expired {
+		if run, ok := w.getRun(r.runID); ok {
+			runs = append(runs, runInfo(r.runID, run))
+		}
+	}
+	return runs
+}
+
+// archiveRuns appends the runs to the archive file of the day, as a gzip
+// member of one JSON document per line. Concatenated gzip members read back
+// as a single stream, so the file stays readable with zcat or gzip.Reader.
+func (w *WorkflowService) archiveRuns(now time.Time, runs []RunInfo) (err error) {
+	if err := os.MkdirAll(w.archiveDir, 0o750); err != nil {
+		return err
+	}
+
+	path := filepath.Join(w.archiveDir, "runs-"+now.UTC().Format(time.DateOnly)+".jsonl.gz")
+	// #nosec G304 - the archive directory is set by the operator
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	gz := gzip.NewWriter(file)
+	enc := json.NewEncoder(gz)
+	for _, run := range runs {
+		if err := enc.Encode(run); err != nil {
+			return err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
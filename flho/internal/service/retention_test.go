@@ -0,0 +1,180 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+// setupRetention applies a one hour retention to billing's completed runs,
+// and a default one day retention to failed runs.
+func setupRetention(t *testing.T) *WorkflowService {
+	svc := setupIndexedRuns(t)
+	svc.config = workflow.NewConfigStore(
+		workflow.Workflows{"billing": {}, "export": {}},
+		map[string]workflow.Settings{"billing": {Retention: workflow.Retention{Completed: time.Hour}}},
+	)
+	svc.retention = workflow.Retention{Failed: 24 * time.Hour}
+
+	return svc
+}
+
+func readArchive(t *testing.T, path string) []string {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+
+	var ids []string
+	dec := json.NewDecoder(gz)
+	for dec.More() {
+		var run RunInfo
+		require.NoError(t, dec.Decode(&run))
+		ids = append(ids, run.ID)
+	}
+	return ids
+}
+
+func TestPurgeExpiredRuns(t *testing.T) {
+	svc := setupRetention(t)
+	require.True(t, svc.purgesRuns())
+
+	purged, err := svc.purgeExpiredRuns(*at(11, 30))
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+	require.Equal(t, []string{"e", "c", "b", "d"}, runIDs(svc.GetRuns(RunsFilter{})))
+
+	_, err = svc.GetRun("a")
+	require.Error(t, err)
+	_, ok := svc.store.Get("a")
+	require.False(t, ok)
+
+	purged, err = svc.purgeExpiredRuns(at(11, 0).Add(24 * time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, purged)
+	require.Equal(t, []string{"c", "d"}, runIDs(svc.GetRuns(RunsFilter{})))
+
+	// runs that have not finished are kept however old they are
+	purged, err = svc.purgeExpiredRuns(at(10, 0).AddDate(1, 0, 0))
+	require.NoError(t, err)
+	require.Zero(t, purged)
+}
+
+func TestPurgeExpiredRuns_KeepsCompensatingRuns(t *testing.T) {
+	svc := setupRetention(t)
+
+	svc.mu.Lock()
+	b, _ := svc.getRun("b")
+	b.compensation = &Compensation{Phase: CompensationRunning, Steps: []string{"step0"}}
+	svc.mu.Unlock()
+
+	purged, err := svc.purgeExpiredRuns(at(10, 0).AddDate(0, 0, 2))
+	require.NoError(t, err)
+	require.Equal(t, 2, purged)
+	require.Equal(t, []string{"c", "b", "d"}, runIDs(svc.GetRuns(RunsFilter{})))
+}
+
+func TestPurgeExpiredRuns_KeepsRunsResumedWhileArchiving(t *testing.T) {
+	svc := setupRetention(t)
+	svc.timeProvider.(*MockTimeProvider).On("Now").Return(at(10, 0).AddDate(0, 0, 2))
+
+	svc.mu.Lock()
+	expired := svc.expiredRuns(at(10, 0).AddDate(0, 0, 2))
+	svc.mu.Unlock()
+
+	// the failed run is resumed while the expired runs are being archived
+	require.NoError(t, svc.ResumeWorkflow(context.Background(), "b"))
+
+	require.Equal(t, 2, svc.purgeRuns(expired))
+	run, err := svc.GetRun("b")
+	require.NoError(t, err)
+	require.Equal(t, RunStatusOngoing, run.Status)
+	_, ok := svc.store.Get("b")
+	require.True(t, ok)
+}
+
+func TestPurgeExpiredRuns_Batch(t *testing.T) {
+	svc, _, _, _ := setupService(t)
+	svc.retention = workflow.Retention{Completed: time.Hour, Failed: time.Hour}
+
+	// two partitions of expired runs, ending a minute apart in turns
+	start := *at(0, 0)
+	svc.mu.Lock()
+	for i := range maxPurgeBatch {
+		end := start.Add(time.Duration(i) * time.Minute)
+		svc.saveRun(fmt.Sprintf("run-%04d", i), &Run{workflowName: "test-workflow", failed: i%2 == 1, start: &start, end: &end})
+	}
+	svc.mu.Unlock()
+
+	purged, err := svc.purgeExpiredRuns(start.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	require.Equal(t, maxPurgeBatch, purged)
+
+	svc.mu.Lock()
+	for i := range maxPurgeBatch {
+		end := start.Add(time.Duration(maxPurgeBatch+i) * time.Minute)
+		svc.saveRun(fmt.Sprintf("run-%04d", maxPurgeBatch+i), &Run{workflowName: "test-workflow", failed: i%2 == 1, start: &start, end: &end})
+	}
+	for i := range 10 {
+		end := start.Add(time.Duration(2*maxPurgeBatch+i) * time.Minute)
+		svc.saveRun(fmt.Sprintf("run-%04d", 2*maxPurgeBatch+i), &Run{workflowName: "test-workflow", start: &start, end: &end})
+	}
+	svc.mu.Unlock()
+
+	// the oldest runs across both partitions go first
+	purged, err = svc.purgeExpiredRuns(start.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	require.Equal(t, maxPurgeBatch, purged)
+
+	remaining := runIDs(svc.GetRuns(RunsFilter{Sort: RunsSortEndTime, Order: RunsOrderAsc}))
+	require.Equal(t, []string{"run-2000", "run-2001", "run-2002"}, remaining[:3])
+	require.Len(t, remaining, 10)
+}
+
+func TestPurgeExpiredRuns_Archive(t *testing.T) {
+	svc := setupRetention(t)
+	svc.archiveDir = filepath.Join(t.TempDir(), "archive")
+
+	_, err := svc.purgeExpiredRuns(*at(11, 30))
+	require.NoError(t, err)
+	_, err = svc.purgeExpiredRuns(*at(23, 0))
+	require.NoError(t, err)
+
+	// both purges append to the day's archive
+	path := filepath.Join(svc.archiveDir, "runs-2023-01-01.jsonl.gz")
+	require.Equal(t, []string{"a", "e"}, readArchive(t, path))
+
+	_, err = svc.purgeExpiredRuns(at(11, 0).Add(24 * time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, readArchive(t, filepath.Join(svc.archiveDir, "runs-2023-01-02.jsonl.gz")))
+
+	t.Run("runs are kept when the archive cannot be written", func(t *testing.T) {
+		svc := setupRetention(t)
+		svc.archiveDir = filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(svc.archiveDir, nil, 0o600))
+
+		_, err := svc.purgeExpiredRuns(*at(11, 30))
+		require.Error(t, err)
+		require.Len(t, runIDs(svc.GetRuns(RunsFilter{})), 5)
+	})
+}
+
+func TestPurgesRuns(t *testing.T) {
+	svc, _, _, _ := setupService(t)
+	svc.config = workflow.NewConfigStore(workflow.Workflows{"billing": {}}, nil)
+	require.False(t, svc.purgesRuns())
+
+	svc.retention = workflow.Retention{Cancelled: time.Hour}
+	require.True(t, svc.purgesRuns())
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/windevkay/forge/flho/internal/schedule"
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+// schedulesKey is the genie store key the schedules' last fire times are
+// persisted under.
+const schedulesKey = "flho:schedules"
+
+const (
+	// scheduleGrace is how late a fire may be handled and still count as on
+	// time. Fires that are later than this were missed while flho was down
+	// and are handled by the workflow's missed_runs policy.
+	scheduleGrace = time.Minute
+	// maxCatchUp bounds how many missed fires are looked at, so a frequent
+	// schedule after a long outage does not start an unbounded number of runs.
+	maxCatchUp = 100
+	// maxSchedulerSleep bounds how long the scheduler sleeps between checks.
+	maxSchedulerSleep = time.Minute
+)
+
+// ScheduleStatus is a snapshot of a scheduled workflow, for display purposes.
+type ScheduleStatus struct {
+	WorkflowName string
+	Namespace    string
+	Schedule     string
+	Timezone     string
+	MissedRuns   string
+	LastRunAt    *time.Time // fire time of the last run the schedule initiated
+	LastRunID    string
+	NextFire     *time.Time
+}
+
+// scheduleState is what is persisted for each scheduled workflow, so that a
+// restart neither fires a schedule twice nor loses the fires it missed.
+type scheduleState struct {
+	LastFire  time.Time  `json:"last_fire"` // fires up to this time have been handled
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastRunID string     `json:"last_run_id,omitempty"`
+}
+
+// scheduler holds the state of the scheduled workflows. It is loaded lazily
+// from the store and written back after every change.
+type scheduler struct {
+	mu     sync.Mutex
+	loaded bool
+	state  map[string]scheduleState
+}
+
+// lockSchedules locks the scheduler, loading its state from the store on
+// first use.
+func (w *WorkflowService) lockSchedules() *scheduler {
+	s := &w.schedules
+	s.mu.Lock()
+
+	if !s.loaded {
+		if v, ok := w.store.Get(schedulesKey); ok {
+			if err := decodeStored(v, &s.state); err != nil {
+				w.logger.Error("failed to restore schedules", "error", err.Error())
+			}
+		}
+		if s.state == nil {
+			s.state = make(map[string]scheduleState)
+		}
+		s.loaded = true
+	}
+
+	return s
+}
+
+// persistSchedules writes the scheduler state back to the store. The caller
+// must hold s.mu.
+func (w *WorkflowService) persistSchedules(s *scheduler) {
+	state := make(map[string]scheduleState, len(s.state))
+	for name, st := range s.state {
+		state[name] = st
+	}
+	w.store.Set(schedulesKey, state)
+}
+
+// scheduledWorkflows returns the names of the workflows that declare a
+// schedule, sorted.
+func (w *WorkflowService) scheduledWorkflows() []string {
+	var names []string
+	for name := range w.config.GetWorkflows() {
+		if w.config.GetSettings(name).Schedule != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// parseSchedule parses the workflow's schedule and timezone.
+func parseSchedule(settings workflow.Settings) (schedule.Schedule, *time.Location, error) {
+	sched, err := schedule.Parse(settings.Schedule)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc, err := settings.Location()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sched, loc, nil
+}
+
+// runScheduler initiates scheduled workflows as they fall due, until ctx is
+// done.
+func (w *WorkflowService) runScheduler(ctx context.Context) {
+	defer w.wg.Done()
+
+	for {
+		now := w.timeProvider.Now()
+		wait := maxSchedulerSleep
+		if next := w.fireSchedules(now); !next.IsZero() && next.Sub(now) < wait {
+			wait = max(next.Sub(now), 0)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// fireSchedules initiates a run for every schedule that has fallen due by
+// now, applying each workflow's missed_runs policy to the fires that are
+// overdue, and returns the earliest upcoming fire time.
+func (w *WorkflowService) fireSchedules(now time.Time) time.Time {
+	s := w.lockSchedules()
+	defer s.mu.Unlock()
+
+	var earliest time.Time
+	for _, name := range w.scheduledWorkflows() {
+		settings := w.config.GetSettings(name)
+		sched, loc, err := parseSchedule(settings)
+		if err != nil {
+			w.logger.Error("invalid workflow schedule", "workflow_name", name, "error", err.Error())
+			continue
+		}
+
+		state, seen := s.state[name]
+		if !seen {
+			// fires are counted from the first time the schedule is seen
+			state.LastFire = now
+			s.state[name] = state
+			w.persistSchedules(s)
+		}
+
+		var due []time.Time
+		for t := sched.Next(state.LastFire.In(loc)); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+			due = append(due, t)
+			if len(due) == maxCatchUp {
+				break
+			}
+		}
+
+		if len(due) > 0 {
+			runs := scheduledRuns(due, now, settings.MissedRuns)
+
+			// the fire is recorded before the runs are initiated, so a
+			// crash in between can never lead to the same fire twice
+			state.LastFire = due[len(due)-1]
+			if len(due) == maxCatchUp {
+				state.LastFire = now
+			}
+			s.state[name] = state
+			w.persistSchedules(s)
+
+			for _, fire := range due[len(due)-runs:] {
+				state.LastRunAt = &fire
+				state.LastRunID = w.InitiateWorkflow(w.lifetime(), name)
+				w.logger.Info("initiated scheduled workflow", "workflow_name", name, "run_id", state.LastRunID)
+			}
+			if runs > 0 {
+				s.state[name] = state
+				w.persistSchedules(s)
+			}
+			if missed := len(due) - runs; missed > 0 {
+				w.logger.Warn("skipped missed scheduled runs", "workflow_name", name, "missed", missed)
+			}
+		}
+
+		if next := sched.Next(state.LastFire.In(loc)); !next.IsZero() && (earliest.IsZero() || next.Before(earliest)) {
+			earliest = next
+		}
+	}
+
+	return earliest
+}
+
+// scheduledRuns returns how many runs to initiate for the fires that are
+// due. Fires handled within the grace period are on time and always run;
+// the remaining, missed, fires are run according to the policy.
+func scheduledRuns(due []time.Time, now time.Time, policy string) int {
+	var runs, missed int
+	for _, t := range due {
+		if now.Sub(t) <= scheduleGrace {
+			runs++
+		} else {
+			missed++
+		}
+	}
+
+	switch policy {
+	case workflow.MissedRunsAll:
+		runs += missed
+	case workflow.MissedRunsOnce:
+		runs += min(missed, 1)
+	}
+
+	return runs
+}
+
+// Schedules returns the status of every scheduled workflow, sorted by name.
+func (w *WorkflowService) Schedules() []ScheduleStatus {
+	s := w.lockSchedules()
+	defer s.mu.Unlock()
+
+	now := w.timeProvider.Now()
+
+	var statuses []ScheduleStatus
+	for _, name := range w.scheduledWorkflows() {
+		settings := w.config.GetSettings(name)
+		status := ScheduleStatus{
+			WorkflowName: name,
+			Namespace:    w.config.NamespaceOf(name),
+			Schedule:     settings.Schedule,
+			Timezone:     settings.Timezone,
+			MissedRuns:   settings.MissedRuns,
+		}
+		if status.Timezone == "" {
+			status.Timezone = "UTC"
+		}
+		if status.MissedRuns == "" {
+			status.MissedRuns = workflow.MissedRunsSkip
+		}
+
+		from := now
+		if state, ok := s.state[name]; ok {
+			status.LastRunAt = state.LastRunAt
+			status.LastRunID = state.LastRunID
+			from = state.LastFire
+		}
+
+		if sched, loc, err := parseSchedule(settings); err == nil {
+			if next := sched.Next(from.In(loc)); !next.IsZero() {
+				status.NextFire = &next
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func setupSchedulerService(t *testing.T, settings workflow.Settings) (*WorkflowService, *MockUUIDProvider) {
+	svc, _, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(
+		workflow.Workflows{
+			"nightly": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+			"manual":  {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+		},
+		map[string]workflow.Settings{"nightly": settings},
+	)
+
+	uuidProvider.On("NewString").Return("scheduled-run-id")
+	timeProvider.On("Now").Return(time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC))
+
+	return svc, uuidProvider
+}
+
+func TestFireSchedules(t *testing.T) {
+	svc, uuidProvider := setupSchedulerService(t, workflow.Settings{Schedule: "0 2 * * *"})
+
+	// the first check only records when the schedule was first seen
+	next := svc.fireSchedules(time.Date(2024, 1, 10, 1, 0, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2024, 1, 10, 2, 0, 0, 0, time.UTC), next)
+	uuidProvider.AssertNotCalled(t, "NewString")
+
+	// a fire handled on time initiates a run
+	next = svc.fireSchedules(time.Date(2024, 1, 10, 2, 0, 1, 0, time.UTC))
+	require.Equal(t, time.Date(2024, 1, 11, 2, 0, 0, 0, time.UTC), next)
+	uuidProvider.AssertNumberOfCalls(t, "NewString", 1)
+
+	// checking again does not fire twice
+	svc.fireSchedules(time.Date(2024, 1, 10, 2, 0, 30, 0, time.UTC))
+	uuidProvider.AssertNumberOfCalls(t, "NewString", 1)
+
+	// nor does a restart, which reloads the persisted state
+	svc.schedules = scheduler{}
+	svc.fireSchedules(time.Date(2024, 1, 10, 2, 0, 30, 0, time.UTC))
+	uuidProvider.AssertNumberOfCalls(t, "NewString", 1)
+
+	runs := svc.GetRuns(RunsFilter{Page: 1, PageSize: 10})
+	require.Equal(t, 1, runs.TotalCount)
+	require.Equal(t, "nightly", runs.Runs[0].WorkflowName)
+}
+
+func TestFireSchedules_MissedRuns(t *testing.T) {
+	tests := []struct {
+		policy       string
+		expectedRuns int
+	}{
+		{policy: "", expectedRuns: 0},
+		{policy: workflow.MissedRunsSkip, expectedRuns: 0},
+		{policy: workflow.MissedRunsOnce, expectedRuns: 1},
+		{policy: workflow.MissedRunsAll, expectedRuns: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run("policy "+tt.policy, func(t *testing.T) {
+			svc, uuidProvider := setupSchedulerService(t, workflow.Settings{Schedule: "0 2 * * *", MissedRuns: tt.policy})
+
+			// the state as restored from a backup after flho was down for
+			// the fires of the 11th, 12th and 13th
+			svc.store.Set(schedulesKey, map[string]any{
+				"nightly": map[string]any{"last_fire": "2024-01-10T02:00:00Z"},
+			})
+
+			next := svc.fireSchedules(time.Date(2024, 1, 13, 9, 0, 0, 0, time.UTC))
+			require.Equal(t, time.Date(2024, 1, 14, 2, 0, 0, 0, time.UTC), next)
+			uuidProvider.AssertNumberOfCalls(t, "NewString", tt.expectedRuns)
+
+			// the missed fires are handled once, whatever the policy
+			svc.fireSchedules(time.Date(2024, 1, 13, 9, 1, 0, 0, time.UTC))
+			uuidProvider.AssertNumberOfCalls(t, "NewString", tt.expectedRuns)
+		})
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	svc, _ := setupSchedulerService(t, workflow.Settings{
+		Schedule: "0 2 * * *",
+		Timezone: "America/New_York",
+	})
+
+	statuses := svc.Schedules()
+	require.Len(t, statuses, 1, "workflows without a schedule are not listed")
+
+	status := statuses[0]
+	require.Equal(t, "nightly", status.WorkflowName)
+	require.Equal(t, "America/New_York", status.Timezone)
+	require.Equal(t, workflow.MissedRunsSkip, status.MissedRuns)
+	require.Nil(t, status.LastRunAt)
+
+	// 02:00 in New York is 07:00 UTC in winter
+	require.NotNil(t, status.NextFire)
+	require.Equal(t, time.Date(2024, 1, 11, 7, 0, 0, 0, time.UTC), status.NextFire.UTC())
+
+	svc.fireSchedules(time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC))
+	svc.fireSchedules(time.Date(2024, 1, 11, 7, 0, 0, 0, time.UTC))
+
+	status = svc.Schedules()[0]
+	require.Equal(t, "scheduled-run-id", status.LastRunID)
+	require.Equal(t, time.Date(2024, 1, 11, 7, 0, 0, 0, time.UTC), status.LastRunAt.UTC())
+	require.Equal(t, time.Date(2024, 1, 12, 7, 0, 0, 0, time.UTC), status.NextFire.UTC())
+}
+
+func TestStartRunsScheduler(t *testing.T) {
+	svc, _ := setupSchedulerService(t, workflow.Settings{Schedule: "@every 1h"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	svc.Start(ctx)
+
+	// the scheduler's first check records the schedule
+	require.Eventually(t, func() bool {
+		_, ok := svc.store.Get(schedulesKey)
+		return ok
+	}, time.Second, time.Millisecond)
+
+	// the scheduler stops with the service
+	cancel()
+	waitForRuns(t, svc)
+}
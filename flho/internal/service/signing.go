@@ -0,0 +1,51 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+)
+
+// Headers that sign outbound notifications, so that receivers can tell they
+// come from flho and were not altered or replayed later.
+const (
+	// SignatureHeader carries "v1=" followed by the hex HMAC-SHA256 of the
+	// timestamp, a '.' and the request body, keyed with the signing secret.
+	SignatureHeader = "X-Flho-Signature"
+	// TimestampHeader carries the Unix time, in seconds, the request was
+	// signed at.
+	TimestampHeader = "X-Flho-Timestamp"
+)
+
+// WithSigningSecret signs every outbound notification with secret: retry
+// notifications, compensation requests and lifecycle callbacks alike. Each
+// attempt is signed when it is sent, so retries and dead-letter replays carry
+// a fresh timestamp.
+func WithSigningSecret(secret []byte) Option {
+	return func(w *WorkflowService) {
+		w.signingSecret = secret
+	}
+}
+
+// Signature returns the value of SignatureHeader for a body sent at the
+// given Unix time.
+func Signature(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign sets the signature headers of a notification, if the service has a
+// signing secret.
+func (w *WorkflowService) sign(req *http.Request, body []byte) {
+	if len(w.signingSecret) == 0 {
+		return
+	}
+
+	timestamp := w.timeProvider.Now().Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Signature(w.signingSecret, timestamp, body))
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	body := []byte(`{"event":"run.completed"}`)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("1672574400." + string(body)))
+	require.Equal(t, "v1="+hex.EncodeToString(mac.Sum(nil)), Signature(secret, 1672574400, body))
+
+	require.NotEqual(t, Signature(secret, 1672574400, body), Signature(secret, 1672574401, body))
+	require.NotEqual(t, Signature(secret, 1672574400, body), Signature([]byte("another secret"), 1672574400, body))
+}
+
+func TestDeliveriesAreSigned(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	sent := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"event":"run.completed"}`)
+
+	deliver := func(t *testing.T, secret []byte) *http.Request {
+		svc, httpClient, _, timeProvider := setupDeliveryService(t)
+		svc.signingSecret = secret
+		timeProvider.On("Now").Return(sent)
+
+		requests := make(chan *http.Request, 1)
+		httpClient.On("Do", mock.Anything).Run(func(args mock.Arguments) {
+			requests <- args.Get(0).(*http.Request)
+		}).Return(okResponse(), nil)
+
+		require.NoError(t, svc.deliver(context.Background(), delivery{url: "http://example.com/complete", body: body}))
+		return <-requests
+	}
+
+	t.Run("with a signing secret", func(t *testing.T) {
+		req := deliver(t, secret)
+		require.Equal(t, strconv.FormatInt(sent.Unix(), 10), req.Header.Get(TimestampHeader))
+		require.Equal(t, Signature(secret, sent.Unix(), body), req.Header.Get(SignatureHeader))
+	})
+
+	t.Run("without a signing secret", func(t *testing.T) {
+		req := deliver(t, nil)
+		require.Empty(t, req.Header.Get(TimestampHeader))
+		require.Empty(t, req.Header.Get(SignatureHeader))
+	})
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Reasons a run times out, reported in the on-timeout notification.
+const (
+	timeoutReasonDeadline = "deadline_exceeded"
+	timeoutReasonStep     = "step_timeout"
+)
+
+// watchDeadline times the run out once its workflow deadline has passed,
+// unless ctx is cancelled first because the run finished.
+func (w *WorkflowService) watchDeadline(ctx context.Context, runID string, deadline time.Duration) {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		w.mu.Lock()
+		step := ""
+		if run, ok := w.getRun(runID); ok {
+			step = fmt.Sprintf("step%v", run.currStep)
+		}
+		w.mu.Unlock()
+
+		w.markRunAsTimedOut(runID, step, timeoutReasonDeadline)
+	case <-ctx.Done():
+	}
+}
+
+// markRunAsTimedOut moves an ongoing run to the timed out status, which
+// stops its retry countdown and deadline and sends the workflow's on_timeout
+// callback if one is configured.
+func (w *WorkflowService) markRunAsTimedOut(runID, step, reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, ok := w.getRun(runID)
+	if !ok {
+		return
+	}
+	if run.end != nil {
+		return
+	}
+
+	w.logger.Warn("run timed out", "run_id", runID, "workflow", run.workflowName, "step", step, "reason", reason)
+
+	w.finishRun(w.lifetime(), runID, run, RunStatusTimedOut, reason)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+func setupTimeoutService(t *testing.T, step workflow.Step, settings workflow.Settings) (*WorkflowService, *MockHTTPClient) {
+	svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(
+		workflow.Workflows{"timed": {{"step0": step}}},
+		map[string]workflow.Settings{"timed": settings},
+	)
+
+	uuidProvider.On("NewString").Return("timed-run-id")
+	timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	return svc, httpClient
+}
+
+func waitForRuns(t *testing.T, svc *WorkflowService) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		svc.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("run goroutines did not finish")
+	}
+}
+
+func TestStepTimeout(t *testing.T) {
+	svc, httpClient := setupTimeoutService(t,
+		workflow.Step{RetryAfter: time.Hour, RetryURL: "http://example.com/retry", Timeout: 10 * time.Millisecond},
+		workflow.Settings{OnTimeout: "http://example.com/timeout"},
+	)
+
+	var body callback
+	httpClient.On("Do", mock.MatchedBy(func(r *http.Request) bool {
+		return r.URL.Path == "/timeout"
+	})).Run(func(args mock.Arguments) {
+		data, _ := io.ReadAll(args.Get(0).(*http.Request).Body)
+		_ = json.Unmarshal(data, &body)
+	}).Return(okResponse(), nil)
+
+	runID := svc.InitiateWorkflow(context.Background(), "timed")
+	waitForRuns(t, svc)
+
+	runs := svc.GetRuns(RunsFilter{Status: string(RunStatusTimedOut), Page: 1, PageSize: 10})
+	require.Equal(t, 1, runs.TotalCount)
+	require.Equal(t, runID, runs.Runs[0].ID)
+	require.NotNil(t, runs.Runs[0].EndTime)
+
+	httpClient.AssertNumberOfCalls(t, "Do", 1)
+	require.Equal(t, "run.timed_out", body.Event)
+	require.Equal(t, runID, body.WorkflowRunID)
+	require.Equal(t, "step0", body.WorkflowStep)
+	require.Equal(t, "step_timeout", body.Reason)
+}
+
+func TestWorkflowDeadline(t *testing.T) {
+	svc, httpClient := setupTimeoutService(t,
+		workflow.Step{RetryAfter: time.Hour, RetryURL: "http://example.com/retry"},
+		workflow.Settings{Deadline: 10 * time.Millisecond},
+	)
+
+	runID := svc.InitiateWorkflow(context.Background(), "timed")
+	waitForRuns(t, svc)
+
+	runValue, _ := svc.store.Get(runID)
+	run := runValue.(*Run)
+	require.Equal(t, RunStatusTimedOut, run.status())
+
+	// no on-timeout URL is configured, and the retry countdown was stopped
+	httpClient.AssertNotCalled(t, "Do", mock.Anything)
+
+	// a timed out run cannot be failed afterwards
+	svc.markRunAsFailed(runID)
+	require.Equal(t, RunStatusTimedOut, run.status())
+}
+
+func TestCompleteWorkflowStopsDeadline(t *testing.T) {
+	svc, _ := setupTimeoutService(t,
+		workflow.Step{RetryAfter: time.Hour, RetryURL: "http://example.com/retry"},
+		workflow.Settings{Deadline: time.Hour},
+	)
+
+	runID := svc.InitiateWorkflow(context.Background(), "timed")
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), runID))
+
+	// both the retry countdown and the deadline watcher return promptly
+	waitForRuns(t, svc)
+
+	runValue, _ := svc.store.Get(runID)
+	require.Equal(t, RunStatusCompleted, runValue.(*Run).status())
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the service's spans.
+const tracerName = "github.com/windevkay/forge/flho/internal/service"
+
+// Span attributes identifying the run a span belongs to.
+const (
+	attrRunID    = attribute.Key("flho.run.id")
+	attrWorkflow = attribute.Key("flho.workflow.name")
+	attrStep     = attribute.Key("flho.workflow.step")
+	attrPriority = attribute.Key("flho.run.priority")
+	attrStatus   = attribute.Key("flho.run.status")
+	attrResumed  = attribute.Key("flho.run.resumed")
+)
+
+var (
+	noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+	// traceContext propagates spans in W3C traceparent and tracestate headers
+	traceContext = propagation.TraceContext{}
+)
+
+// WithTracerProvider traces every run with tp: a span covers the run from its
+// creation to its end, with a child span for each step and for each delivery
+// made on the run's behalf. Deliveries carry the W3C traceparent header of
+// their span.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(w *WorkflowService) {
+		w.tracer = tp.Tracer(tracerName)
+	}
+}
+
+func (w *WorkflowService) tracing() trace.Tracer {
+	if w.tracer == nil {
+		return noopTracer
+	}
+	return w.tracer
+}
+
+// startRunSpan starts the span covering the run, as a child of the span in
+// ctx, if any, such as that of the request initiating the run. A resumed run
+// gets a new span, linked to the span of its previous attempt. The caller must
+// hold w.mu.
+func (w *WorkflowService) startRunSpan(ctx context.Context, runID string, run *Run) {
+	opts := []trace.SpanStartOption{
+		trace.WithAttributes(
+			attrRunID.String(runID),
+			attrWorkflow.String(run.workflowName),
+			attrPriority.Int(run.priority),
+		),
+	}
+	if run.span != nil {
+		opts = append(opts,
+			trace.WithLinks(trace.Link{SpanContext: run.span.SpanContext()}),
+			trace.WithAttributes(attrResumed.Bool(true)),
+		)
+	}
+
+	_, run.span = w.tracing().Start(ctx, "run "+run.workflowName, opts...)
+}
+
+// startStepSpan starts the span of the run's current step, as a child of the
+// run's span, and returns ctx carrying it. When ctx carries a span of its own,
+// such as that of the request advancing the run, the step span links to it.
+// The caller must hold w.mu.
+func (w *WorkflowService) startStepSpan(ctx context.Context, runID string, run *Run) context.Context {
+	step := "step" + strconv.Itoa(run.currStep)
+	opts := []trace.SpanStartOption{
+		trace.WithAttributes(
+			attrRunID.String(runID),
+			attrWorkflow.String(run.workflowName),
+			attrStep.String(step),
+		),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() && (run.span == nil || !sc.Equal(run.span.SpanContext())) {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+	}
+
+	ctx, run.stepSpan = w.tracing().Start(w.runSpanContext(ctx, run), step, opts...)
+	return ctx
+}
+
+// endStepSpan ends the span of the run's current step, if any.
+func (r *Run) endStepSpan() {
+	if r.stepSpan != nil {
+		r.stepSpan.End()
+		r.stepSpan = nil
+	}
+}
+
+// endRunSpan ends the run's span, and that of its current step, recording the
+// run's final status.
+func (r *Run) endRunSpan(status RunStatus, reason string) {
+	r.endStepSpan()
+	if r.span == nil {
+		return
+	}
+
+	r.span.SetAttributes(attrStatus.String(string(status)))
+	if status != RunStatusCompleted {
+		r.span.SetStatus(codes.Error, reason)
+	}
+	r.span.End()
+}
+
+// runSpanContext returns ctx carrying the run's span, so that spans started
+// from it belong to the run's trace.
+func (w *WorkflowService) runSpanContext(ctx context.Context, run *Run) context.Context {
+	if run.span == nil {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, run.span)
+}
+
+// startDeliverySpan starts the span of a delivery attempt, as a child of the
+// span in ctx, and injects it into the request's traceparent header.
+func (w *WorkflowService) startDeliverySpan(ctx context.Context, d delivery, req *http.Request) trace.Span {
+	ctx, span := w.tracing().Start(ctx, "POST "+d.step,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodPost,
+			semconv.URLFull(d.url),
+			semconv.ServerAddress(req.URL.Hostname()),
+			attrRunID.String(d.runID),
+			attrWorkflow.String(d.workflowName),
+			attrStep.String(d.step),
+		),
+	)
+	traceContext.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	return span
+}
+
+// endDeliverySpan ends the span of a delivery attempt answered with code, or
+// with no response at all when code is zero.
+func endDeliverySpan(span trace.Span, code int, err error) {
+	if code != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(code))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+// spansByName indexes the ended spans by name.
+func spansByName(exporter *tracetest.InMemoryExporter) map[string]tracetest.SpanStub {
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	return spans
+}
+
+func TestTracing(t *testing.T) {
+	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	svc, httpClient, uuidProvider, timeProvider := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(workflow.Workflows{
+		"export": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			{"step1": {RetryAfter: 10 * time.Millisecond, RetryURL: "http://example.com/retry"}},
+		},
+	}, nil)
+	exporter := tracetest.NewInMemoryExporter()
+	WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))(svc)
+
+	var traceparent string
+	httpClient.On("Do", mock.Anything).Run(func(args mock.Arguments) {
+		traceparent = args.Get(0).(*http.Request).Header.Get("Traceparent")
+	}).Return(okResponse(), nil)
+	uuidProvider.On("NewString").Return("export-run")
+	timeProvider.On("Now").Return(fixedTime)
+
+	// the run joins the trace of the request initiating it
+	caller := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	runID := svc.InitiateWorkflow(trace.ContextWithRemoteSpanContext(context.Background(), caller), "export")
+
+	// the request advancing the run is linked from the next step
+	updater := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{2},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	require.NoError(t, svc.UpdateWorkflow(trace.ContextWithRemoteSpanContext(context.Background(), updater), runID))
+
+	// step1's retry notification is delivered and fails the run
+	require.Eventually(t, func() bool {
+		run, err := svc.GetRun(runID)
+		require.NoError(t, err)
+		return run.Status == RunStatusFailed
+	}, time.Second, time.Millisecond)
+	svc.wg.Wait()
+
+	spans := spansByName(exporter)
+	require.Len(t, spans, 4)
+
+	run := spans["run export"]
+	require.Equal(t, caller.TraceID(), run.SpanContext.TraceID())
+	require.Equal(t, caller.SpanID(), run.Parent.SpanID())
+	require.Equal(t, codes.Error, run.Status.Code)
+	require.Contains(t, run.Attributes, attrRunID.String(runID))
+	require.Contains(t, run.Attributes, attrStatus.String(string(RunStatusFailed)))
+
+	step0, step1 := spans["step0"], spans["step1"]
+	require.Equal(t, run.SpanContext.SpanID(), step0.Parent.SpanID())
+	require.Equal(t, run.SpanContext.SpanID(), step1.Parent.SpanID())
+	require.Len(t, step1.Links, 1)
+	require.Equal(t, updater.SpanID(), step1.Links[0].SpanContext.SpanID())
+
+	post := spans["POST step1"]
+	require.Equal(t, step1.SpanContext.SpanID(), post.Parent.SpanID())
+	require.Equal(t, trace.SpanKindClient, post.SpanKind)
+	require.Equal(t, "00-"+post.SpanContext.TraceID().String()+"-"+post.SpanContext.SpanID().String()+"-01", traceparent)
+}
+
+func TestTracingResumedRun(t *testing.T) {
+	svc, _, _, _ := setupDeliveryService(t)
+	svc.config = workflow.NewConfigStore(workflow.Workflows{
+		"export": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+	}, nil)
+	exporter := tracetest.NewInMemoryExporter()
+	WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))(svc)
+	svc.uuidProvider.(*MockUUIDProvider).On("NewString").Return("export-run")
+	svc.timeProvider.(*MockTimeProvider).On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	runID := svc.InitiateWorkflow(context.Background(), "export")
+	svc.markRunAsFailed(runID)
+	require.NoError(t, svc.ResumeWorkflow(context.Background(), runID))
+	require.NoError(t, svc.CompleteWorkflow(context.Background(), runID))
+
+	var runs []tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "run export" {
+			runs = append(runs, span)
+		}
+	}
+	require.Len(t, runs, 2)
+	require.Equal(t, codes.Unset, runs[1].Status.Code)
+	require.Contains(t, runs[1].Attributes, attrResumed.Bool(true))
+	require.Len(t, runs[1].Links, 1)
+	require.Equal(t, runs[0].SpanContext, runs[1].Links[0].SpanContext)
+}
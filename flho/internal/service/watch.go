@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"iter"
+)
+
+// runWatchers holds, per run, the channels of those watching it, each
+// signalled when the run changes. It is guarded by w.mu.
+type runWatchers map[string]map[chan struct{}]struct{}
+
+func (ws *runWatchers) add(runID string, changed chan struct{}) {
+	if *ws == nil {
+		*ws = make(runWatchers)
+	}
+	if (*ws)[runID] == nil {
+		(*ws)[runID] = make(map[chan struct{}]struct{})
+	}
+	(*ws)[runID][changed] = struct{}{}
+}
+
+func (ws runWatchers) remove(runID string, changed chan struct{}) {
+	delete(ws[runID], changed)
+	if len(ws[runID]) == 0 {
+		delete(ws, runID)
+	}
+}
+
+// notify signals the watchers of the run. Watchers already signalled are not
+// signalled again, so that a watcher catching up sees the latest state once.
+func (ws runWatchers) notify(runID string) {
+	for changed := range ws[runID] {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// WatchRun yields the run, with its history, and then again every time it
+// changes, until it is finished, ctx is done or the service stops. Changes
+// in quick succession may be yielded once, as the latest state. A run that
+// does not exist, or is purged while watched, ends the iteration with an
+// ErrNotFound error.
+//
+// A run is finished once it has a final status and is not compensating, so
+// a failed run that is later resumed needs to be watched again.
+func (w *WorkflowService) WatchRun(ctx context.Context, runID string) iter.Seq2[RunInfo, error] {
+	return func(yield func(RunInfo, error) bool) {
+		changed := make(chan struct{}, 1)
+
+		w.mu.Lock()
+		w.watchers.add(runID, changed)
+		w.mu.Unlock()
+
+		defer func() {
+			w.mu.Lock()
+			w.watchers.remove(runID, changed)
+			w.mu.Unlock()
+		}()
+
+		for {
+			run, err := w.GetRun(runID)
+			if err != nil {
+				yield(RunInfo{}, err)
+				return
+			}
+			if !yield(run, nil) || watchEnded(run) {
+				return
+			}
+
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			case <-w.lifetime().Done():
+				return
+			}
+		}
+	}
+}
+
+// watchEnded reports whether the run will not change again, short of being
+// resumed.
+func watchEnded(run RunInfo) bool {
+	switch run.Status {
+	case RunStatusCompleted, RunStatusFailed, RunStatusTimedOut, RunStatusCancelled:
+		return run.Compensation == nil || run.Compensation.Phase != CompensationRunning
+	}
+	return false
+}
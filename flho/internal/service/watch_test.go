@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/windevkay/forge/flho/internal/workflow"
+)
+
+// watch collects what WatchRun yields on a channel, closed once the
+// iteration ends.
+func watch(ctx context.Context, svc *WorkflowService, runID string) <-chan RunInfo {
+	updates := make(chan RunInfo)
+	go func() {
+		defer close(updates)
+		for run, err := range svc.WatchRun(ctx, runID) {
+			if err != nil {
+				return
+			}
+			updates <- run
+		}
+	}()
+	return updates
+}
+
+// nextUpdate waits for the watcher to yield a state of the run matching cond.
+func nextUpdate(t *testing.T, updates <-chan RunInfo, cond func(RunInfo) bool) RunInfo {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case run, ok := <-updates:
+			require.True(t, ok, "the watch ended early")
+			if cond(run) {
+				return run
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the run to change")
+		}
+	}
+}
+
+func TestWatchRun(t *testing.T) {
+	setup := func(t *testing.T) *WorkflowService {
+		svc := setupQueueService(t, 0)
+		svc.config = workflow.NewConfigStore(workflow.Workflows{"export": {
+			{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			{"step1": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+		}}, nil)
+		return svc
+	}
+
+	t.Run("yields the run as it changes until it finishes", func(t *testing.T) {
+		svc := setup(t)
+		ctx := context.Background()
+		runID := svc.InitiateWorkflow(ctx, "export")
+
+		updates := watch(ctx, svc, runID)
+		run := nextUpdate(t, updates, func(RunInfo) bool { return true })
+		require.Equal(t, RunStatusOngoing, run.Status)
+		require.NotEmpty(t, run.History)
+
+		require.NoError(t, svc.Heartbeat(runID, nil, "working"))
+		nextUpdate(t, updates, func(r RunInfo) bool { return r.LastHeartbeat != nil })
+
+		require.NoError(t, svc.UpdateWorkflow(ctx, runID))
+		nextUpdate(t, updates, func(r RunInfo) bool { return r.CurrentStep == 1 })
+
+		require.NoError(t, svc.CompleteWorkflow(ctx, runID))
+		nextUpdate(t, updates, func(r RunInfo) bool { return r.Status == RunStatusCompleted })
+
+		_, ok := <-updates
+		require.False(t, ok, "expected the watch to end once the run finished")
+		require.Empty(t, svc.watchers)
+	})
+
+	t.Run("finished runs are yielded once", func(t *testing.T) {
+		svc := setup(t)
+		ctx := context.Background()
+		runID := svc.InitiateWorkflow(ctx, "export")
+		require.NoError(t, svc.CancelWorkflow(ctx, runID, ""))
+
+		var statuses []RunStatus
+		for run, err := range svc.WatchRun(ctx, runID) {
+			require.NoError(t, err)
+			statuses = append(statuses, run.Status)
+		}
+		require.Equal(t, []RunStatus{RunStatusCancelled}, statuses)
+	})
+
+	t.Run("unknown runs end the watch with an error", func(t *testing.T) {
+		svc := setup(t)
+
+		for _, err := range svc.WatchRun(context.Background(), "missing") {
+			require.ErrorIs(t, err, ErrNotFound)
+		}
+	})
+
+	t.Run("the watch ends with its context", func(t *testing.T) {
+		svc := setup(t)
+		runID := svc.InitiateWorkflow(context.Background(), "export")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		updates := watch(ctx, svc, runID)
+		nextUpdate(t, updates, func(RunInfo) bool { return true })
+		cancel()
+
+		require.Eventually(t, func() bool {
+			select {
+			case _, ok := <-updates:
+				return !ok
+			default:
+				return false
+			}
+		}, time.Second, time.Millisecond)
+
+		require.NoError(t, svc.CancelWorkflow(context.Background(), runID, ""))
+	})
+}
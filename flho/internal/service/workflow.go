@@ -7,24 +7,9 @@
 // state management and supports workflow cancellation, completion tracking,
 // and step progression.
 //
-// Key Features:
-//   - Asynchronous workflow execution with goroutine-based step processing
-//   - Automatic retry mechanisms with configurable intervals
-//   - Thread-safe workflow state management using sync.Map
-//   - Persistent state storage via the genie key-value store
-//   - HTTP-based retry notifications to external services
-//   - Context-based cancellation and timeout support
-//   - Workflow run tracking with start/end timestamps
-//
-// Architecture:
-//
-// The WorkflowService orchestrates workflow execution by:
-//  1. Initiating workflows and generating unique run IDs
-//  2. Processing individual workflow steps in separate goroutines
-//  3. Managing retry timers for failed steps
-//  4. Persisting workflow state for resumption after failures
-//  5. Sending HTTP notifications to retry URLs when steps need attention
-//  6. Tracking active runs and their cancellation functions
+// Behaviour beyond the step lifecycle, such as delivery retries, circuit
+// breakers, retention, metrics and tracing, is enabled with the Options given
+// to NewWorkflowService.
 //
 // Workflow Lifecycle:
 //
@@ -39,40 +24,40 @@
 //
 // Usage Example (direct vs using REST endpoints):
 //
-//	config := workflow.NewConfigStore()
 //	store, _ := genie.NewStore()
-//	wg := &sync.WaitGroup{}
-//
-//	service := NewWorkflowService(config, store, wg)
+//	service := NewWorkflowService(config, store, &sync.WaitGroup{}, logger)
+//	service.Start(ctx)
 //
 //	// Start a workflow
-//	runID, err := service.InitiateWorkflow(context.Background(), "user_onboarding")
-//	if err != nil {
-//		log.Fatal(err)
-//	}
+//	runID := service.InitiateWorkflow(ctx, "user_onboarding")
 //
 //	// Progress to next step
-//	err = service.UpdateWorkflow(context.Background(), runID)
+//	err := service.UpdateWorkflow(ctx, runID)
 //
 //	// Mark as complete
-//	err = service.CompleteWorkflow(runID)
+//	err = service.CompleteWorkflow(ctx, runID)
+//
+//	// Or give up on it
+//	err = service.CancelWorkflow(ctx, runID, "customer closed account")
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"log/slog"
+	"maps"
 	"net/http"
-	"sort"
-	"strings"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/windevkay/forge/flho/internal/label"
 	"github.com/windevkay/forge/flho/internal/workflow"
 	"github.com/windevkay/forge/genie/v2"
 )
@@ -117,7 +102,7 @@ func (p *DefaultTimeProvider) Now() time.Time {
 
 // NewWorkflowService creates a new WorkflowService with default production implementations
 // for HTTP client, UUID provider, and time provider.
-func NewWorkflowService(cfg *workflow.ConfigStore, store *genie.Store, wg *sync.WaitGroup, logger *slog.Logger) *WorkflowService {
+func NewWorkflowService(cfg *workflow.ConfigStore, store *genie.Store, wg *sync.WaitGroup, logger *slog.Logger, opts ...Option) *WorkflowService {
 	return NewService(
 		cfg,
 		store,
@@ -126,11 +111,29 @@ func NewWorkflowService(cfg *workflow.ConfigStore, store *genie.Store, wg *sync.
 		&http.Client{},
 		&DefaultUUIDProvider{},
 		&DefaultTimeProvider{},
+		opts...,
 	)
 }
 
+// Option configures optional behaviour of a WorkflowService.
+type Option func(*WorkflowService)
+
+// WithDeliveryRetries sets how many times an outbound notification is attempted
+// before it is moved to the dead-letter queue, and the initial backoff between
+// attempts. The backoff doubles after every failed attempt.
+func WithDeliveryRetries(attempts int, backoff time.Duration) Option {
+	return func(w *WorkflowService) {
+		w.deliveryAttempts = attempts
+		w.deliveryBackoff = backoff
+	}
+}
+
 // WorkflowService manages workflow execution, including step processing,
-// retry logic, and run state management.
+// retry logic, and run state management. Runs are kept in the genie store and
+// indexed in memory for querying. A run may be delayed, wait in a queue for a
+// slot under its workflow's concurrency limit or its namespace's quota, and
+// start child runs from its steps; every change to it is recorded in its
+// history.
 type WorkflowService struct {
 	config       *workflow.ConfigStore
 	httpClient   HTTPClient
@@ -139,13 +142,32 @@ type WorkflowService struct {
 	logger       *slog.Logger
 	store        *genie.Store
 	wg           *sync.WaitGroup
-	runIDs       sync.Map // Track run IDs since genie store doesn't support iteration
+	runs         runIndex // runs sorted for querying, since genie store doesn't support iteration
+	ctx          context.Context
+	mu           sync.Mutex // guards mutable run state shared with processStep goroutines
+
+	deliveryAttempts int
+	deliveryBackoff  time.Duration
+	deadLetters      deadLetterQueue
+	breakers         breakers
+	schedules        scheduler
+	pending          pendingStarts
+	queues           map[string]*runQueue // guarded by mu
+	namespaceActive  map[string]int       // runs in progress per namespace, guarded by mu
+	queueSeq         uint64               // orders queued runs across workflows, guarded by mu
+	bulk             bulkJobs
+	watchers         runWatchers        // guarded by mu
+	retention        workflow.Retention // defaults for workflows without their own retention
+	archiveDir       string             // where runs are archived before they are purged, if set
+	metrics          *serviceMetrics    // nil unless WithMetrics is given
+	tracer           trace.Tracer       // nil unless WithTracerProvider is given
+	signingSecret    []byte             // signs outbound notifications, unless empty
 }
 
 // NewService creates a new instance of WorkflowService with the provided configuration,
 // store, and wait group for managing workflow executions.
-func NewService(cfg *workflow.ConfigStore, store *genie.Store, wg *sync.WaitGroup, logger *slog.Logger, httpClient HTTPClient, uuidProvider UUIDProvider, timeProvider TimeProvider) *WorkflowService {
-	return &WorkflowService{
+func NewService(cfg *workflow.ConfigStore, store *genie.Store, wg *sync.WaitGroup, logger *slog.Logger, httpClient HTTPClient, uuidProvider UUIDProvider, timeProvider TimeProvider, opts ...Option) *WorkflowService {
+	w := &WorkflowService{
 		config:       cfg,
 		httpClient:   httpClient,
 		uuidProvider: uuidProvider,
@@ -154,16 +176,101 @@ func NewService(cfg *workflow.ConfigStore, store *genie.Store, wg *sync.WaitGrou
 		store:        store,
 		wg:           wg,
 	}
+	w.breakers.timeProvider = timeProvider
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Start binds the service to ctx, which bounds the lifetime of every run's
+// background work. Runs outlive the HTTP request that created them, so once
+// ctx is cancelled all pending retry countdowns stop and processStep
+// goroutines return, allowing a graceful shutdown to wait on the WaitGroup.
+// Delayed runs persisted before a restart are rescheduled, workflows that
+// declare a schedule are initiated from then on, and finished runs are purged
+// once their retention passes.
+func (w *WorkflowService) Start(ctx context.Context) {
+	w.ctx = ctx
+
+	w.restorePendingStarts(ctx)
+
+	if len(w.scheduledWorkflows()) > 0 {
+		w.wg.Add(1)
+		go w.runScheduler(ctx)
+	}
+
+	if w.purgesRuns() {
+		w.wg.Add(1)
+		go w.runJanitor(ctx)
+	}
+}
+
+// lifetime returns the context that run contexts are derived from.
+func (w *WorkflowService) lifetime() context.Context {
+	if w.ctx == nil {
+		return context.Background()
+	}
+	return w.ctx
 }
 
 // Run represents a workflow execution instance with its current state
 // and step information.
 type Run struct {
-	currStep     int
-	failed       bool
-	workflowName string
-	retryCancel  context.CancelFunc
-	start, end   *time.Time
+	currStep       int
+	failed         bool
+	timedOut       bool
+	cancelled      bool
+	workflowName   string
+	namespace      string // of the workflow when the run was created
+	retryCancel    context.CancelFunc
+	deadlineCancel context.CancelFunc
+	heartbeats     chan struct{} // signals processStep to restart the retry countdown
+	heartbeat      *Heartbeat
+	compensation   *Compensation
+	parentRunID    string     // run whose step started this run, if any
+	parentStep     int        // index of the parent's step that started this run
+	children       []string   // child runs started by the run's steps, oldest first
+	startAt        *time.Time // when a delayed run is due to start
+	queued         bool       // waiting for a slot under the workflow's max_concurrent_runs
+	priority       int        // orders the run's queued start and deliveries, higher first
+	labels         map[string]string
+	stepStart      *time.Time // when the run reached its current step
+	history        []RunEvent // oldest first
+	span, stepSpan trace.Span // trace the run and its current step
+	start, end     *time.Time
+}
+
+// status derives the run's status from its state.
+func (r *Run) status() RunStatus {
+	switch {
+	case r.failed:
+		return RunStatusFailed
+	case r.timedOut:
+		return RunStatusTimedOut
+	case r.cancelled:
+		return RunStatusCancelled
+	case r.end != nil:
+		return RunStatusCompleted
+	case r.queued:
+		return RunStatusQueued
+	case r.startAt != nil && r.start == nil:
+		return RunStatusScheduled
+	default:
+		return RunStatusOngoing
+	}
+}
+
+// stopTimers cancels the run's pending retry countdown and deadline.
+func (r *Run) stopTimers() {
+	if r.retryCancel != nil {
+		r.retryCancel()
+	}
+	if r.deadlineCancel != nil {
+		r.deadlineCancel()
+	}
 }
 
 // RunStatus represents the status of a workflow run
@@ -176,110 +283,315 @@ const (
 	RunStatusCompleted RunStatus = "completed"
 	// RunStatusFailed represents runs that have encountered a failure
 	RunStatusFailed RunStatus = "failed"
+	// RunStatusTimedOut represents runs that exceeded their workflow deadline or a step timeout
+	RunStatusTimedOut RunStatus = "timed_out"
+	// RunStatusCancelled represents runs that were cancelled before completing
+	RunStatusCancelled RunStatus = "cancelled"
+	// RunStatusScheduled represents delayed runs that have not started yet
+	RunStatusScheduled RunStatus = "scheduled"
+	// RunStatusQueued represents runs waiting for a slot under their workflow's concurrency limit
+	RunStatusQueued RunStatus = "queued"
 )
 
 // RunInfo represents run information for display purposes
 type RunInfo struct {
-	ID           string
-	WorkflowName string
-	Status       RunStatus
-	CurrentStep  int
-	StartTime    *time.Time
-	EndTime      *time.Time
-	Duration     *time.Duration
+	ID            string            `json:"id"`
+	WorkflowName  string            `json:"workflow_name"`
+	Namespace     string            `json:"namespace"` // the namespace of its workflow, which API keys and quotas are scoped to
+	Status        RunStatus         `json:"status"`
+	CurrentStep   int               `json:"current_step"`
+	StartTime     *time.Time        `json:"start_time,omitempty"`
+	EndTime       *time.Time        `json:"end_time,omitempty"`
+	Duration      *time.Duration    `json:"duration,omitempty"` // in nanoseconds when encoded
+	LastHeartbeat *Heartbeat        `json:"last_heartbeat,omitempty"`
+	Compensation  *Compensation     `json:"compensation,omitempty"`  // set once a failed or cancelled run starts compensating
+	ParentRunID   string            `json:"parent_run_id,omitempty"` // run whose step started this run, if any
+	ChildRunIDs   []string          `json:"child_run_ids,omitempty"` // runs started by this run's steps, oldest first
+	ScheduledFor  *time.Time        `json:"scheduled_for,omitempty"` // when a delayed run is due to start
+	Priority      int               `json:"priority"`                // see RunOptions
+	Labels        map[string]string `json:"labels,omitempty"`        // see RunOptions, searchable with a label selector
+	History       []RunEvent        `json:"history,omitempty"`       // oldest first, only set for a single run
 }
 
-// RunsFilter represents filtering options for retrieving runs
+// RunsFilter represents filtering options for retrieving runs. Time ranges
+// include their start and exclude their end, and a zero time leaves that end
+// of the range open.
 type RunsFilter struct {
-	Status       string // "ongoing", "completed", "failed", or empty for all
-	WorkflowName string // partial match on workflow name
-	Page         int    // page number (1-based)
-	PageSize     int    // items per page
+	Status        string   // "scheduled", "queued", "ongoing", "completed", "failed", "timed_out", "cancelled", or empty for all
+	WorkflowName  string   // partial match on workflow name
+	Namespace     string   // exact match on namespace, or empty for all
+	Namespaces    []string // restricts the runs to these namespaces, such as those of an API key, or nil for all
+	Priority      *int     // exact match on priority, or nil for all
+	Labels        label.Selector
+	Step          *int          // exact match on the current step, or nil for all
+	StartedAfter  time.Time     // runs started at or after this time
+	StartedBefore time.Time     // runs started before this time
+	EndedAfter    time.Time     // runs finished at or after this time
+	EndedBefore   time.Time     // runs finished before this time
+	MinDuration   time.Duration // finished runs that took at least this long
+	Sort          string        // one of the RunsSort keys, or empty for start time
+	Order         string        // RunsOrderAsc or RunsOrderDesc, or empty for descending
+	Cursor        string        // the NextCursor of the previous page, or empty for the first page
+	PageSize      int           // items per page, 20 by default and at most 500
+
+	// Page is the page number (1-based), used when no cursor is given.
+	//
+	// Deprecated: follow NextCursor instead. Reaching a page by number
+	// visits every matching run of the pages before it, so pages starting
+	// past the first 10000 runs are rejected.
+	Page int
 }
 
+// Keys runs can be sorted by. Runs of equal value are ordered by when they
+// were created, and runs without a value, such as the end time of an ongoing
+// run, come last in descending order and first in ascending order.
+const (
+	// RunsSortStartTime orders runs by start time.
+	RunsSortStartTime = "start_time"
+	// RunsSortEndTime orders runs by end time.
+	RunsSortEndTime = "end_time"
+	// RunsSortDuration orders finished runs by how long they took.
+	RunsSortDuration = "duration"
+	// RunsSortPriority orders runs by priority, and runs of a priority by
+	// start time, oldest first, and then by when they were created: the
+	// order queued runs start in. Ascending order reverses it.
+	RunsSortPriority = "priority"
+)
+
+// Sort orders of runs.
+const (
+	RunsOrderAsc  = "asc"
+	RunsOrderDesc = "desc"
+)
+
 // RunsResponse represents the response structure for runs data
 type RunsResponse struct {
 	Runs       []RunInfo
-	TotalCount int
+	Queues     []QueueStatus // workflows with runs in progress or queued
+	Quotas     []QuotaStatus // namespaces with a quota on active runs
+	NextCursor string        // continues with the next page, empty on the last page
+	TotalCount int           // -1 when counting the matching runs would take a scan of them
 	Page       int
 	PageSize   int
-	TotalPages int
+	TotalPages int // zero when TotalCount is unknown
+}
+
+// RunOptions configures how a run is initiated.
+type RunOptions struct {
+	// StartAt delays the start of the run until the given time. The run is
+	// created in the scheduled state and its first step is processed once
+	// the time is reached. A zero or past time starts the run immediately.
+	StartAt time.Time
+	// StartAfter delays the start of the run by the given duration. It is
+	// ignored when StartAt is set.
+	StartAfter time.Duration
+	// Priority orders the run against other runs of its workflow waiting for
+	// a concurrency slot, and its notifications against other deliveries
+	// waiting on the same host. Higher values go first; runs of equal
+	// priority keep their arrival order. The default is zero.
+	Priority int
+	// Labels are free-form key/value pairs stored with the run, such as
+	// customer_id or region, to find it by with a label selector. They
+	// should pass label.Validate. Labels with an empty value are not stored.
+	Labels map[string]string
+}
+
+// UpdateOptions configures how a run is progressed.
+type UpdateOptions struct {
+	// Labels are set on the run, replacing the value of labels it already
+	// carries. A label with an empty value is removed.
+	Labels map[string]string
 }
 
 // InitiateWorkflow starts a new workflow instance with the given name, returning a unique run ID.
 // It initiates the first step of the workflow in a separate goroutine.
 func (w *WorkflowService) InitiateWorkflow(ctx context.Context, name string) string {
+	return w.InitiateWorkflowWithOptions(ctx, name, RunOptions{})
+}
+
+// InitiateWorkflowWithOptions creates a new workflow instance with the given
+// name and options, returning a unique run ID.
+func (w *WorkflowService) InitiateWorkflowWithOptions(ctx context.Context, name string, opts RunOptions) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.startRun(ctx, name, opts, nil)
+}
+
+// startRun creates a run of the named workflow and starts processing its
+// first step, or schedules it to start later. A non-nil configure is applied
+// to the run before it is stored. The caller must hold w.mu.
+func (w *WorkflowService) startRun(ctx context.Context, name string, opts RunOptions, configure func(*Run)) string {
 	index := 0 // starting a new workflow so defaulting to first step
 
 	runID := w.uuidProvider.NewString()
-	runCtx, cancel := context.WithCancel(ctx)
-	runstart := w.timeProvider.Now()
 	run := &Run{
 		currStep:     index,
 		workflowName: name,
-		retryCancel:  cancel,
-		start:        &runstart,
+		namespace:    w.config.NamespaceOf(name),
+		heartbeats:   make(chan struct{}, 1),
+		priority:     opts.Priority,
+		labels:       mergeLabels(nil, opts.Labels),
+	}
+
+	if configure != nil {
+		configure(run)
 	}
 
-	w.store.Set(runID, run)
-	w.runIDs.Store(runID, true) // Track run ID
+	w.startRunSpan(ctx, runID, run)
 
-	w.wg.Add(1)
-	go w.processStep(runCtx, index, runID, name)
+	startAt := opts.StartAt
+	if startAt.IsZero() && opts.StartAfter > 0 {
+		startAt = w.timeProvider.Now().Add(opts.StartAfter)
+	}
+	if !startAt.IsZero() && startAt.After(w.timeProvider.Now()) {
+		w.recordEvent(ctx, run, RunEventCreated, createdDetail(run, startAt))
+		w.scheduleStart(ctx, runID, run, startAt)
+		return runID
+	}
+
+	w.recordEvent(ctx, run, RunEventCreated, createdDetail(run, time.Time{}))
+	w.admit(ctx, runID, run)
 
 	return runID
 }
 
+// begin starts the run: it records the start time, processes the run's
+// current step and watches the workflow deadline, if any. A resumed run keeps
+// the start time of its first start. The caller must hold w.mu.
+func (w *WorkflowService) begin(ctx context.Context, runID string, run *Run) {
+	if run.retryCancel != nil {
+		// a delayed run was waiting on its own countdown
+		run.retryCancel()
+	}
+
+	runCtx, cancel := w.runContext(ctx)
+	run.retryCancel = cancel
+	runCtx = w.startStepSpan(runCtx, runID, run)
+	now := w.timeProvider.Now()
+	if run.start == nil {
+		run.start = &now
+		w.metrics.runStarted(run.workflowName)
+		w.recordEvent(ctx, run, RunEventStarted, "")
+	}
+	run.stepStart = &now
+
+	// the deadline covers the whole run, so it is watched separately from
+	// the per-step retry countdown that is replaced on every update
+	deadline := w.config.GetSettings(run.workflowName).Deadline
+	var deadlineCtx context.Context
+	if deadline > 0 {
+		deadlineCtx, run.deadlineCancel = w.runContext(ctx)
+	}
+
+	w.saveRun(runID, run)
+
+	w.wg.Add(1)
+	go w.processStep(runCtx, run.currStep, runID, run.workflowName)
+
+	if deadlineCtx != nil {
+		w.wg.Add(1)
+		go w.watchDeadline(deadlineCtx, runID, deadline)
+	}
+}
+
 // UpdateWorkflow progresses the specified workflow by one step.
 // It retrieves the current step index and processes the next step.
 func (w *WorkflowService) UpdateWorkflow(ctx context.Context, runID string) error {
-	r, existing := w.store.Get(runID)
+	return w.UpdateWorkflowWithOptions(ctx, runID, UpdateOptions{})
+}
+
+// UpdateWorkflowWithOptions progresses the specified workflow by one step,
+// applying the options to the run.
+func (w *WorkflowService) UpdateWorkflowWithOptions(ctx context.Context, runID string, opts UpdateOptions) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, existing := w.getRun(runID)
 	if !existing {
-		return fmt.Errorf("no data found for run ID: %s", runID)
+		return runNotFound(runID)
+	}
+	if err := checkOngoing(run); err != nil {
+		return err
 	}
-
-	run := r.(*Run)
-	nextStep := run.currStep + 1
 
 	run, err := w.cancelRetryCountdown(runID)
 	if err != nil {
 		return err
 	}
 
+	run.labels = mergeLabels(run.labels, opts.Labels)
+	w.advance(ctx, runID, run)
+
+	return nil
+}
+
+// advance moves the run on to its next step, replacing the current step's
+// retry countdown. The caller must hold w.mu.
+func (w *WorkflowService) advance(ctx context.Context, runID string, run *Run) {
+	run.retryCancel()
+
 	// create a fresh run context and cancel func
 	// also update the current runs step
-	runCtx, cancel := context.WithCancel(ctx)
+	runCtx, cancel := w.runContext(ctx)
 	run.retryCancel = cancel
-	run.currStep = nextStep
+	now := w.timeProvider.Now()
+	w.metrics.stepLeft(run, now)
+	run.endStepSpan()
+	run.currStep++
+	run.stepStart = &now
+	w.recordEvent(ctx, run, RunEventAdvanced, "")
+	runCtx = w.startStepSpan(runCtx, runID, run)
 
-	w.store.Set(runID, run)
+	w.saveRun(runID, run)
 
 	w.wg.Add(1)
-	go w.processStep(runCtx, nextStep, runID, run.workflowName)
-
-	return nil
+	go w.processStep(runCtx, run.currStep, runID, run.workflowName)
 }
 
 // CompleteWorkflow finalizes the specified workflow run.
 // It cancels any pending retries and marks the workflow end time.
-func (w *WorkflowService) CompleteWorkflow(runID string) error {
+func (w *WorkflowService) CompleteWorkflow(ctx context.Context, runID string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, existing := w.getRun(runID)
+	if !existing {
+		return errors.New("run information missing. Did a previous step fail?")
+	}
+	if err := checkOngoing(run); err != nil {
+		return err
+	}
+
 	run, err := w.cancelRetryCountdown(runID)
 	if err != nil {
 		return err
 	}
 
-	runEnd := w.timeProvider.Now()
-	run.end = &runEnd
-
-	w.store.Set(runID, run)
+	w.finishRun(ctx, runID, run, RunStatusCompleted, "")
 
 	return nil
 }
 
+// runContext derives a cancellable context for a run's background work. The
+// caller's values are kept but not its cancellation, since a run outlives the
+// request that started or advanced it; the run is bound to the service
+// lifetime instead.
+func (w *WorkflowService) runContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(w.lifetime(), cancel)
+
+	return runCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
 // processStep executes a single step in the workflow, managing retries and HTTP notifications.
 // It stops when the context is done or after a successful HTTP POST request.
 func (w *WorkflowService) processStep(ctx context.Context, index int, runID, name string) {
 	defer w.wg.Done()
+	defer w.metrics.stepProcessing()()
 
 	step := fmt.Sprintf("step%v", index)
 
@@ -296,11 +608,41 @@ func (w *WorkflowService) processStep(ctx context.Context, index int, runID, nam
 
 	stepData := workflow[index][step]
 
-	ticker := time.NewTicker(stepData.RetryAfter)
+	if stepData.Workflow != "" {
+		w.startChild(ctx, runID, index, stepData.Workflow)
+	}
+
+	// a step without a retry interval, such as one awaiting a child run,
+	// is never retried
+	var retry <-chan time.Time
+	var ticker *time.Ticker
+	if stepData.RetryAfter > 0 {
+		ticker = time.NewTicker(stepData.RetryAfter)
+		defer ticker.Stop()
+		retry = ticker.C
+	}
+
+	// heartbeats restart the retry countdown without advancing the run
+	var heartbeats <-chan struct{}
+	var priority int
+	w.mu.Lock()
+	if run, ok := w.getRun(runID); ok {
+		heartbeats, priority = run.heartbeats, run.priority
+	}
+	w.mu.Unlock()
+
+	// the step timeout bounds the whole time spent in the step
+	var timeout <-chan time.Time
+	if stepData.Timeout > 0 {
+		timer := time.NewTimer(stepData.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-retry:
+			ticker.Stop()
 			// curate the data the client can utilize for retries within their app
 			// ideally this information can be used as a key to fetch the appropriate
 			// function that needs to be called/retried + its arguments
@@ -316,26 +658,33 @@ func (w *WorkflowService) processStep(ctx context.Context, index int, runID, nam
 
 			jsonData, _ := json.Marshal(retryData)
 
-			// Create HTTP request with context
-			req, err := http.NewRequestWithContext(ctx, "POST", stepData.RetryURL, bytes.NewBuffer(jsonData))
-			if err != nil {
-				w.logger.Error("failed to create HTTP request")
+			err := w.deliver(ctx, delivery{
+				runID:        runID,
+				workflowName: name,
+				step:         step,
+				url:          stepData.RetryURL,
+				body:         jsonData,
+				priority:     priority,
+			})
+			if ctx.Err() != nil {
+				// the run moved on or the service is shutting down while
+				// we were delivering, so the notification no longer applies
 				return
 			}
-			req.Header.Set("Content-Type", "application/json")
-
-			client := w.httpClient
-			res, err := client.Do(req)
 			if err != nil {
-				w.logger.Error("POST to retryURL unsuccessful")
-				return
+				w.logger.Error("POST to retryURL unsuccessful", "run_id", runID, "error", err.Error())
 			}
-			_ = res.Body.Close()
 			// mark run as failed
 			w.markRunAsFailed(runID)
 			return
+		case <-heartbeats:
+			if ticker != nil {
+				ticker.Reset(stepData.RetryAfter)
+			}
+		case <-timeout:
+			w.markRunAsTimedOut(runID, step, timeoutReasonStep)
+			return
 		case <-ctx.Done():
-			ticker.Stop()
 			return
 		}
 	}
@@ -344,116 +693,225 @@ func (w *WorkflowService) processStep(ctx context.Context, index int, runID, nam
 // cancelRetryCountdown cancels any pending retries for the specified run ID.
 // It retrieves and returns the run information.
 func (w *WorkflowService) cancelRetryCountdown(runID string) (*Run, error) {
-	r, ok := w.store.Get(runID)
+	run, ok := w.getRun(runID)
 	if !ok {
 		return nil, errors.New("run information missing. Did a previous step fail?")
 	}
-	run := r.(*Run)
 
-	run.retryCancel()
+	// runs that have not started have no retry countdown yet
+	if run.retryCancel != nil {
+		run.retryCancel()
+	}
 
 	return run, nil
 }
 
 // help to mark a failed run and update the end timestamp
 func (w *WorkflowService) markRunAsFailed(runID string) {
-	r, _ := w.store.Get(runID)
-	run := r.(*Run)
-	run.failed = true
-	runEnd := w.timeProvider.Now()
-	run.end = &runEnd
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-	w.store.Set(runID, run)
+	run, ok := w.getRun(runID)
+	if !ok || run.end != nil {
+		// the run already finished, e.g. it timed out while notifying
+		return
+	}
+
+	w.finishRun(w.lifetime(), runID, run, RunStatusFailed, reasonRetryElapsed)
 }
 
-// GetRuns retrieves paginated run data filtered by status and name
+// GetRuns retrieves a page of runs matching the filter. Pages follow each
+// other through the NextCursor of the response, which stays stable while runs
+// are created and progress; a deprecated Page number is still honoured when
+// no cursor is given.
 func (w *WorkflowService) GetRuns(filter RunsFilter) RunsResponse {
-	var runs []RunInfo
-
-	// Iterate over tracked run IDs
-	w.runIDs.Range(func(key, _ any) bool {
-		runID := key.(string)
-
-		// Get the run data from the store
-		runData, exists := w.store.Get(runID)
-		if !exists {
-			// Clean up orphaned run ID
-			w.runIDs.Delete(runID)
-			return true
+	w.mu.Lock()
+	ids, next, total := w.queryRuns(filter)
+
+	runs := make([]RunInfo, 0, len(ids))
+	for _, runID := range ids {
+		if run, ok := w.getRun(runID); ok {
+			runs = append(runs, runInfo(runID, run))
 		}
+	}
+	queues := w.queueStatuses(filter.Namespaces)
+	quotas := w.quotaStatuses(filter.Namespaces)
+	w.mu.Unlock()
+
+	pageSize := filter.pageSize()
+	totalPages := 0
+	if total >= 0 {
+		totalPages = max((total+pageSize-1)/pageSize, 1)
+	}
 
-		run := runData.(*Run)
+	return RunsResponse{
+		Runs:       runs,
+		Queues:     queues,
+		Quotas:     quotas,
+		NextCursor: next,
+		TotalCount: total,
+		Page:       filter.Page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}
+}
 
-		// Filtering by status
-		status := RunStatusOngoing
-		if run.failed {
-			status = RunStatusFailed
-		} else if run.end != nil {
-			status = RunStatusCompleted
-		}
+// CheckWorkflow returns an ErrNotFound error if no workflow is configured
+// with the name.
+func (w *WorkflowService) CheckWorkflow(name string) error {
+	if _, ok := w.config.GetWorkflows()[name]; !ok {
+		return errorf(ErrNotFound, "workflow %s does not exist", name)
+	}
+	return nil
+}
 
-		if filter.Status != "" && status != RunStatus(filter.Status) {
-			return true
-		}
+// WorkflowNamespace returns the namespace the named workflow belongs to.
+func (w *WorkflowService) WorkflowNamespace(name string) string {
+	return w.config.NamespaceOf(name)
+}
 
-		// Filtering by workflow name
-		if filter.WorkflowName != "" && !strings.Contains(run.workflowName, filter.WorkflowName) {
-			return true
+// WorkflowInfo describes a configured workflow.
+type WorkflowInfo struct {
+	Name              string   `json:"name"`
+	Namespace         string   `json:"namespace"`
+	Steps             []string `json:"steps"`              // step keys, in order
+	Schedule          string   `json:"schedule,omitempty"` // cron expression or interval the workflow is initiated on
+	MaxConcurrentRuns int      `json:"max_concurrent_runs,omitempty"`
+}
+
+// Workflows returns the workflows of the given namespaces, nil standing for
+// every namespace, sorted by name.
+func (w *WorkflowService) Workflows(namespaces []string) []WorkflowInfo {
+	workflows := []WorkflowInfo{}
+	for _, name := range slices.Sorted(maps.Keys(w.config.GetWorkflows())) {
+		namespace := w.config.NamespaceOf(name)
+		if !InNamespaces(namespace, namespaces) {
+			continue
 		}
 
-		// Duration calculation
-		var duration *time.Duration
-		if run.end != nil {
-			d := run.end.Sub(*run.start)
-			duration = &d
+		settings := w.config.GetSettings(name)
+		info := WorkflowInfo{
+			Name:              name,
+			Namespace:         namespace,
+			Steps:             []string{},
+			Schedule:          settings.Schedule,
+			MaxConcurrentRuns: settings.MaxConcurrentRuns,
+		}
+		for _, step := range w.config.GetWorkflows()[name] {
+			for key := range step {
+				info.Steps = append(info.Steps, key)
+			}
 		}
+		workflows = append(workflows, info)
+	}
+	return workflows
+}
+
+// GetRun retrieves a single run by ID.
+func (w *WorkflowService) GetRun(runID string) (RunInfo, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, ok := w.getRun(runID)
+	if !ok {
+		return RunInfo{}, runNotFound(runID)
+	}
+
+	info := runInfo(runID, run)
+	info.History = slices.Clone(run.history)
+
+	return info, nil
+}
 
-		runs = append(runs, RunInfo{
-			ID:           runID,
-			CurrentStep:  run.currStep,
-			WorkflowName: run.workflowName,
-			Status:       status,
-			StartTime:    run.start,
-			EndTime:      run.end,
-			Duration:     duration,
-		})
-
-		return true
-	})
-
-	// Sort runs by start time (latest first)
-	sort.Slice(runs, func(i, j int) bool {
-		if runs[i].StartTime == nil {
-			return false
+// AllRuns returns the runs matching the filter, in the filter's order and
+// continuing from its cursor, if any. The filter's page and page size are
+// ignored. Runs are read a page at a time, so that exporting many runs
+// neither holds them all in memory nor holds w.mu while they are written out.
+func (w *WorkflowService) AllRuns(filter RunsFilter) iter.Seq[RunInfo] {
+	filter.Page = 1
+	filter.PageSize = maxRunsPageSize
+
+	return func(yield func(RunInfo) bool) {
+		for {
+			w.mu.Lock()
+			ids, next, _ := w.queryRuns(filter)
+			runs := make([]RunInfo, 0, len(ids))
+			for _, runID := range ids {
+				if run, ok := w.getRun(runID); ok {
+					runs = append(runs, runInfo(runID, run))
+				}
+			}
+			w.mu.Unlock()
+
+			for _, run := range runs {
+				if !yield(run) {
+					return
+				}
+			}
+			if next == "" {
+				return
+			}
+			filter.Cursor = next
 		}
-		if runs[j].StartTime == nil {
-			return true
+	}
+}
+
+// mergeLabels returns labels with updates applied, removing the labels
+// updated to an empty value. labels is not modified.
+func mergeLabels(labels, updates map[string]string) map[string]string {
+	if len(updates) == 0 {
+		return labels
+	}
+
+	merged := maps.Clone(labels)
+	if merged == nil {
+		merged = make(map[string]string, len(updates))
+	}
+	for k, v := range updates {
+		if v == "" {
+			delete(merged, k)
+			continue
 		}
-		return runs[i].StartTime.After(*runs[j].StartTime)
-	})
+		merged[k] = v
+	}
 
-	// Implement pagination
-	total := len(runs)
-	start := (filter.Page - 1) * filter.PageSize
-	if start > total {
-		start = total
+	if len(merged) == 0 {
+		return nil
 	}
-	end := start + filter.PageSize
-	if end > total {
-		end = total
+	return merged
+}
+
+// runInfo builds the display information of a run. The caller must hold w.mu.
+func runInfo(runID string, run *Run) RunInfo {
+	// Copy the compensation progress, which is still being updated
+	var compensation *Compensation
+	if run.compensation != nil {
+		c := *run.compensation
+		compensation = &c
 	}
-	paginatedRuns := runs[start:end]
 
-	totalPages := (total + filter.PageSize - 1) / filter.PageSize
-	if totalPages == 0 {
-		totalPages = 1
+	// Duration calculation
+	var duration *time.Duration
+	if run.end != nil && run.start != nil {
+		d := run.end.Sub(*run.start)
+		duration = &d
 	}
 
-	return RunsResponse{
-		Runs:       paginatedRuns,
-		TotalCount: total,
-		Page:       filter.Page,
-		PageSize:   filter.PageSize,
-		TotalPages: totalPages,
+	return RunInfo{
+		ID:            runID,
+		CurrentStep:   run.currStep,
+		WorkflowName:  run.workflowName,
+		Namespace:     run.namespace,
+		Status:        run.status(),
+		StartTime:     run.start,
+		EndTime:       run.end,
+		Duration:      duration,
+		LastHeartbeat: run.heartbeat,
+		Compensation:  compensation,
+		ParentRunID:   run.parentRunID,
+		ChildRunIDs:   append([]string(nil), run.children...),
+		ScheduledFor:  run.startAt,
+		Priority:      run.priority,
+		Labels:        maps.Clone(run.labels),
 	}
 }
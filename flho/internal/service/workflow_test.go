@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/windevkay/forge/flho/internal/label"
 	"github.com/windevkay/forge/flho/internal/workflow"
 	"github.com/windevkay/forge/genie/v2"
 )
@@ -201,7 +203,8 @@ func TestUpdateWorkflow(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			svc, _, _, store := setupService(t)
+			svc, _, timeProvider, store := setupService(t)
+			timeProvider.On("Now").Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
 
 			tt.setupStore(store)
 
@@ -266,7 +269,7 @@ func TestCompleteWorkflow(t *testing.T) {
 
 			tt.setupStore(store)
 
-			err := svc.CompleteWorkflow(tt.runID)
+			err := svc.CompleteWorkflow(context.Background(), tt.runID)
 
 			if tt.expectedErr != "" {
 				require.Error(t, err)
@@ -411,3 +414,66 @@ func TestMarkRunAsFailed(t *testing.T) {
 		timeProvider.AssertExpectations(t)
 	})
 }
+
+func TestRunLabels(t *testing.T) {
+	svc := setupQueueService(t, 0)
+	ctx := context.Background()
+
+	svc.InitiateWorkflowWithOptions(ctx, "export", RunOptions{Labels: map[string]string{"customer_id": "42", "region": "us"}})
+	svc.InitiateWorkflowWithOptions(ctx, "export", RunOptions{Labels: map[string]string{"customer_id": "42", "region": "eu"}})
+	svc.InitiateWorkflowWithOptions(ctx, "export", RunOptions{Labels: map[string]string{"customer_id": "7", "trial": ""}})
+
+	search := func(selector string) []string {
+		s, err := label.ParseSelector(selector)
+		require.NoError(t, err)
+
+		var ids []string
+		for _, run := range svc.GetRuns(RunsFilter{Labels: s, Page: 1, PageSize: 10}).Runs {
+			ids = append(ids, run.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	require.Equal(t, []string{"run-1", "run-2"}, search("customer_id=42"))
+	require.Equal(t, []string{"run-1"}, search("customer_id=42,region!=eu"))
+	require.Equal(t, []string{"run-3"}, search("!region"))
+	// labels with an empty value are not stored
+	require.Empty(t, search("trial"))
+
+	// updates set and remove labels
+	require.NoError(t, svc.UpdateWorkflowWithOptions(ctx, "run-2", UpdateOptions{
+		Labels: map[string]string{"region": "", "status": "invoiced"},
+	}))
+
+	run, err := svc.GetRun("run-2")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"customer_id": "42", "status": "invoiced"}, run.Labels)
+	require.Equal(t, 1, run.CurrentStep)
+	require.Equal(t, []string{"run-2"}, search("status=invoiced"))
+}
+
+func TestWorkflows(t *testing.T) {
+	svc := setupQueueService(t, 0)
+	svc.config = workflow.NewConfigStore(
+		workflow.Workflows{
+			"invoice": {
+				{"draft": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+				{"send": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}},
+			},
+			"export": {{"step0": {RetryAfter: time.Hour, RetryURL: "http://example.com/retry"}}},
+		},
+		map[string]workflow.Settings{"invoice": {Namespace: "billing", Schedule: "@every 1h", MaxConcurrentRuns: 2}},
+	)
+
+	require.Equal(t, []WorkflowInfo{
+		{Name: "export", Namespace: workflow.DefaultNamespace, Steps: []string{"step0"}},
+		{Name: "invoice", Namespace: "billing", Steps: []string{"draft", "send"}, Schedule: "@every 1h", MaxConcurrentRuns: 2},
+	}, svc.Workflows(nil))
+
+	billing := svc.Workflows([]string{"billing"})
+	require.Len(t, billing, 1)
+	require.Equal(t, "invoice", billing[0].Name)
+
+	require.Empty(t, svc.Workflows([]string{"ops"}))
+}
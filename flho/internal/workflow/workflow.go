@@ -7,7 +7,10 @@
 // Key components:
 //   - ConfigStore: Manages workflow configurations loaded from YAML files
 //   - Workflow: Represents a sequence of named steps
-//   - Step: Individual workflow step with retry configuration
+//   - Step: Individual workflow step with retry and compensation configuration
+//   - Settings: Workflow-level configuration such as the run deadline and schedule
+//   - Retention: How long finished runs are kept before they are purged
+//   - Namespace: Quotas shared by the workflows of a namespace
 //
 // The package supports loading workflow configurations from YAML files with the
 // following structure:
@@ -23,6 +26,75 @@
 //	        retryafter: "10s"
 //	        retryurl: "https://example.com/retry2"
 //
+// Workflows that need workflow-level settings are written as a mapping, with
+// the steps listed under "steps":
+//
+//	workflows:
+//	  timed-workflow:
+//	    deadline: "1h"
+//	    max_concurrent_runs: 50
+//	    on_timeout: "https://example.com/timeout"
+//	    on_complete: "https://example.com/complete"
+//	    on_failure: "https://example.com/failure"
+//	    on_cancel: "https://example.com/cancel"
+//	    steps:
+//	      - step0:
+//	          name: "First Step"
+//	          retryafter: "5s"
+//	          retryurl: "https://example.com/retry"
+//	          timeout: "10m"
+//	          compensateurl: "https://example.com/undo"
+//
+// A step can start another workflow and wait for it to finish, so shared
+// sub-processes are only written once:
+//
+//	workflows:
+//	  onboarding:
+//	    - step0:
+//	        name: "Send Verification"
+//	        workflow: "send-verification"
+//	        onchildfailure: "continue"
+//
+// Workflows can also be initiated on a schedule, given as a cron expression
+// or a fixed interval, evaluated in an optional timezone:
+//
+//	workflows:
+//	  nightly-report:
+//	    schedule: "0 2 * * *"
+//	    timezone: "Europe/London"
+//	    missed_runs: "once"
+//	    steps:
+//	      - step0:
+//	          retryafter: "1h"
+//	          retryurl: "https://example.com/retry"
+//
+// Finished runs are kept until they are purged by a retention period, given
+// per final status:
+//
+//	workflows:
+//	  high-volume:
+//	    retention:
+//	      completed: "168h"
+//	      failed: "720h"
+//	    steps:
+//	      - step0:
+//	          retryafter: "5m"
+//	          retryurl: "https://example.com/retry"
+//
+// Workflows belong to a namespace, "default" unless they name one, and each
+// namespace can cap how many of its runs are in progress at once:
+//
+//	namespaces:
+//	  billing:
+//	    max_active_runs: 100
+//	workflows:
+//	  invoice:
+//	    namespace: billing
+//	    steps:
+//	      - step0:
+//	          retryafter: "5m"
+//	          retryurl: "https://example.com/retry"
+//
 // Example usage:
 //
 //	configStore, err := NewConfigStoreFromFile("workflows.yaml")
@@ -34,14 +106,19 @@ package workflow
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/windevkay/forge/flho/internal/schedule"
 )
 
 // Step represents a single step in a workflow with its configuration.
@@ -49,17 +126,187 @@ type Step struct {
 	Name       string        `yaml:"name"`
 	RetryAfter time.Duration `yaml:"retryafter"`
 	RetryURL   string        `yaml:"retryurl"`
+	Timeout    time.Duration `yaml:"timeout"` // maximum time a run may spend in the step, regardless of retries
+	// CompensateURL undoes the step once it has completed, should the run
+	// later fail or be cancelled.
+	CompensateURL string `yaml:"compensateurl"`
+	// Workflow names a child workflow that is started when the step is
+	// reached. The step completes when the child run does.
+	Workflow string `yaml:"workflow"`
+	// OnChildFailure decides what a child run that does not complete does to
+	// the run: ChildFailureFail (the default) or ChildFailureContinue.
+	OnChildFailure string `yaml:"onchildfailure"`
 }
 
+// Policies for a child run that fails, times out or is cancelled.
+const (
+	ChildFailureFail     = "fail"     // fail the parent run
+	ChildFailureContinue = "continue" // advance the parent run as if the child had completed
+)
+
 // Workflow represents a complete workflow as a slice of step maps.
 type Workflow []map[string]Step
 
 // Workflows represents a collection of named workflows.
 type Workflows map[string]Workflow
 
+// Settings holds workflow-level configuration that applies to every run of a
+// workflow.
+type Settings struct {
+	Deadline   time.Duration `yaml:"deadline"`    // maximum time a run may take from start to finish
+	OnTimeout  string        `yaml:"on_timeout"`  // URL notified when a run exceeds its deadline or a step timeout
+	OnComplete string        `yaml:"on_complete"` // URL notified when a run completes
+	OnFailure  string        `yaml:"on_failure"`  // URL notified when a run fails
+	OnCancel   string        `yaml:"on_cancel"`   // URL notified when a run is cancelled
+	Schedule   string        `yaml:"schedule"`    // cron expression or "@every <duration>" the workflow is initiated on
+	Timezone   string        `yaml:"timezone"`    // IANA timezone the schedule is evaluated in, UTC by default
+	MissedRuns string        `yaml:"missed_runs"` // what to do about fires missed while flho was down
+	// MaxConcurrentRuns caps how many runs of the workflow are in progress at
+	// once. Runs over the limit are queued until a slot frees up. Zero means
+	// no limit.
+	MaxConcurrentRuns int `yaml:"max_concurrent_runs"`
+	// Retention overrides, per final status, how long the workflow's
+	// finished runs are kept.
+	Retention Retention `yaml:"retention"`
+	// Namespace is the namespace the workflow and its runs belong to,
+	// DefaultNamespace if empty.
+	Namespace string `yaml:"namespace"`
+}
+
+// DefaultNamespace is the namespace of workflows that do not name one.
+const DefaultNamespace = "default"
+
+// Namespace holds the quotas shared by the workflows of a namespace.
+type Namespace struct {
+	// MaxActiveRuns caps how many runs of the namespace's workflows are in
+	// progress at once. Runs over the quota are queued until a run of the
+	// namespace finishes. Zero means no quota.
+	MaxActiveRuns int `yaml:"max_active_runs"`
+}
+
+// validNamespace matches namespace names: lowercase letters, digits, '-' and
+// '_', starting with a letter or digit.
+var validNamespace = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidateNamespace checks that name can be used as a namespace.
+func ValidateNamespace(name string) error {
+	if !validNamespace.MatchString(name) {
+		return fmt.Errorf("invalid namespace %q: use up to 63 lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Retention is how long finished runs are kept after they end, per final
+// status, before they are purged. Zero keeps runs of that status forever.
+type Retention struct {
+	Completed time.Duration `yaml:"completed"`
+	Failed    time.Duration `yaml:"failed"`
+	TimedOut  time.Duration `yaml:"timed_out"`
+	Cancelled time.Duration `yaml:"cancelled"`
+}
+
+// Or returns the retention with every period that is not set taken from
+// defaults.
+func (r Retention) Or(defaults Retention) Retention {
+	if r.Completed == 0 {
+		r.Completed = defaults.Completed
+	}
+	if r.Failed == 0 {
+		r.Failed = defaults.Failed
+	}
+	if r.TimedOut == 0 {
+		r.TimedOut = defaults.TimedOut
+	}
+	if r.Cancelled == 0 {
+		r.Cancelled = defaults.Cancelled
+	}
+	return r
+}
+
+// IsZero reports whether the retention keeps runs of every status forever.
+func (r Retention) IsZero() bool {
+	return r == Retention{}
+}
+
+// Validate checks that no retention period is negative.
+func (r Retention) Validate() error {
+	for status, period := range map[string]time.Duration{
+		"completed": r.Completed,
+		"failed":    r.Failed,
+		"timed_out": r.TimedOut,
+		"cancelled": r.Cancelled,
+	} {
+		if period < 0 {
+			return fmt.Errorf("invalid %s retention %s", status, period)
+		}
+	}
+	return nil
+}
+
+// Policies for scheduled fires that were missed while flho was not running.
+const (
+	MissedRunsSkip = "skip" // drop missed fires and wait for the next one (the default)
+	MissedRunsOnce = "once" // initiate a single run for any number of missed fires
+	MissedRunsAll  = "all"  // initiate a run for every missed fire
+)
+
+// Location returns the timezone the workflow's schedule is evaluated in.
+func (s Settings) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
 // Root represents the root configuration structure containing all workflows.
 type Root struct {
-	Workflows Workflows `yaml:"workflows"`
+	Workflows  Workflows            `yaml:"workflows"`
+	Settings   map[string]Settings  `yaml:"-"`
+	Namespaces map[string]Namespace `yaml:"namespaces"`
+}
+
+// definition is a single workflow as written in YAML. A workflow is either a
+// plain list of steps, or a mapping of workflow-level settings with the steps
+// listed under "steps".
+type definition struct {
+	Settings `yaml:",inline"`
+	Steps    Workflow `yaml:"steps"`
+}
+
+// UnmarshalYAML decodes both the list and the mapping form of a workflow.
+func (d *definition) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.SequenceNode:
+		return value.Decode(&d.Steps)
+	case yaml.MappingNode:
+		type plain definition
+		return value.Decode((*plain)(d))
+	default:
+		return fmt.Errorf("line %d: workflow must be a list of steps or a mapping", value.Line)
+	}
+}
+
+// UnmarshalYAML decodes the workflows, splitting each into its steps and its
+// workflow-level settings.
+func (r *Root) UnmarshalYAML(value *yaml.Node) error {
+	var raw struct {
+		Workflows  map[string]definition `yaml:"workflows"`
+		Namespaces map[string]Namespace  `yaml:"namespaces"`
+	}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+
+	r.Namespaces = raw.Namespaces
+
+	r.Workflows = make(Workflows, len(raw.Workflows))
+	r.Settings = make(map[string]Settings, len(raw.Workflows))
+	for name, def := range raw.Workflows {
+		r.Workflows[name] = def.Steps
+		r.Settings[name] = def.Settings
+	}
+
+	return nil
 }
 
 // ConfigStore manages workflow configurations loaded from YAML files.
@@ -67,6 +314,19 @@ type ConfigStore struct {
 	data Root
 }
 
+// NewConfigStore creates a new ConfigStore holding the given workflows and
+// their optional workflow-level settings.
+func NewConfigStore(workflows Workflows, settings map[string]Settings) *ConfigStore {
+	return &ConfigStore{data: Root{Workflows: workflows, Settings: settings}}
+}
+
+// WithNamespaces sets the quotas of the named namespaces and returns the
+// store.
+func (s *ConfigStore) WithNamespaces(namespaces map[string]Namespace) *ConfigStore {
+	s.data.Namespaces = namespaces
+	return s
+}
+
 // NewConfigStoreFromFile creates a new ConfigStore by loading workflow
 // configurations from the specified YAML file path.
 func NewConfigStoreFromFile(path string) (*ConfigStore, error) {
@@ -102,10 +362,165 @@ func NewConfigStoreFromFile(path string) (*ConfigStore, error) {
 		return nil, err
 	}
 
-	return &ConfigStore{data: root}, nil
+	if err := root.Workflows.validate(); err != nil {
+		return nil, err
+	}
+
+	for name, settings := range root.Settings {
+		if err := settings.validate(); err != nil {
+			return nil, fmt.Errorf("workflow %s: %w", name, err)
+		}
+	}
+
+	store := &ConfigStore{data: root}
+	if err := store.validateNamespaces(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
 }
 
 // GetWorkflows returns the collection of workflows managed by this ConfigStore.
 func (s *ConfigStore) GetWorkflows() Workflows {
 	return s.data.Workflows
 }
+
+// GetSettings returns the workflow-level settings of the named workflow. A
+// workflow without settings, or an unknown one, yields the zero Settings.
+func (s *ConfigStore) GetSettings(name string) Settings {
+	return s.data.Settings[name]
+}
+
+// GetNamespace returns the quotas of the named namespace. A namespace without
+// quotas, or an unknown one, yields the zero Namespace.
+func (s *ConfigStore) GetNamespace(name string) Namespace {
+	return s.data.Namespaces[name]
+}
+
+// QuotaNamespaces returns the names of the namespaces with a quota on active
+// runs, sorted.
+func (s *ConfigStore) QuotaNamespaces() []string {
+	var names []string
+	for name, ns := range s.data.Namespaces {
+		if ns.MaxActiveRuns > 0 {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NamespaceOf returns the namespace the named workflow belongs to.
+func (s *ConfigStore) NamespaceOf(workflow string) string {
+	if ns := s.data.Settings[workflow].Namespace; ns != "" {
+		return ns
+	}
+	return DefaultNamespace
+}
+
+// validateNamespaces checks the namespace names and quotas, and that child
+// workflows belong to the namespace of the workflows starting them, so that
+// a run never acts outside its namespace.
+func (s *ConfigStore) validateNamespaces() error {
+	for name, ns := range s.data.Namespaces {
+		if err := ValidateNamespace(name); err != nil {
+			return err
+		}
+		if ns.MaxActiveRuns < 0 {
+			return fmt.Errorf("namespace %s: invalid max_active_runs %d", name, ns.MaxActiveRuns)
+		}
+	}
+
+	for name, wf := range s.data.Workflows {
+		ns := s.NamespaceOf(name)
+		if err := ValidateNamespace(ns); err != nil {
+			return fmt.Errorf("workflow %s: %w", name, err)
+		}
+		for _, step := range wf {
+			for key, st := range step {
+				if st.Workflow != "" && s.NamespaceOf(st.Workflow) != ns {
+					return fmt.Errorf("workflow %s: %s starts workflow %q of another namespace", name, key, st.Workflow)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate checks that every child workflow a step starts is defined, and
+// that no workflow ends up starting itself.
+func (wfs Workflows) validate() error {
+	for name, wf := range wfs {
+		for _, step := range wf {
+			for key, s := range step {
+				if s.Workflow != "" {
+					if _, ok := wfs[s.Workflow]; !ok {
+						return fmt.Errorf("workflow %s: %s starts unknown workflow %q", name, key, s.Workflow)
+					}
+				}
+				switch s.OnChildFailure {
+				case "", ChildFailureFail, ChildFailureContinue:
+				default:
+					return fmt.Errorf("workflow %s: %s has invalid onchildfailure %q", name, key, s.OnChildFailure)
+				}
+			}
+		}
+	}
+
+	for name := range wfs {
+		if wfs.startsItself(name, name, map[string]bool{}) {
+			return fmt.Errorf("workflow %s starts itself through its child workflows", name)
+		}
+	}
+
+	return nil
+}
+
+// startsItself reports whether the workflow current, reached from root,
+// leads back to root through the child workflows of its steps.
+func (wfs Workflows) startsItself(root, current string, seen map[string]bool) bool {
+	if seen[current] {
+		return false
+	}
+	seen[current] = true
+
+	for _, step := range wfs[current] {
+		for _, s := range step {
+			if s.Workflow == "" {
+				continue
+			}
+			if s.Workflow == root || wfs.startsItself(root, s.Workflow, seen) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// validate checks the schedule, timezone, missed-run policy, concurrency
+// limit and retention of the settings.
+func (s Settings) validate() error {
+	if s.Schedule != "" {
+		if _, err := schedule.Parse(s.Schedule); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.Location(); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+
+	switch s.MissedRuns {
+	case "", MissedRunsSkip, MissedRunsOnce, MissedRunsAll:
+	default:
+		return fmt.Errorf("invalid missed_runs %q", s.MissedRuns)
+	}
+
+	if s.MaxConcurrentRuns < 0 {
+		return fmt.Errorf("invalid max_concurrent_runs %d", s.MaxConcurrentRuns)
+	}
+
+	return s.Retention.Validate()
+}
@@ -115,3 +115,292 @@ func TestNewStoreFromFile_Errors(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestNewStoreFromFile_Settings(t *testing.T) {
+	filePath := writeTempFile(t, `
+workflows:
+  timed:
+    deadline: 1h
+    on_timeout: "https://example.com/timeout"
+    steps:
+      - step0:
+          name: timed_step0
+          retryafter: 5m
+          timeout: 30m
+      - step1:
+          name: timed_step1
+          retryafter: 10m
+  legacy:
+    - step0:
+        name: legacy_step0
+        retryafter: 5m
+`)
+
+	store, err := NewConfigStoreFromFile(filePath)
+	require.NoError(t, err)
+
+	workflows := store.GetWorkflows()
+	require.Len(t, workflows["timed"], 2)
+	require.Len(t, workflows["legacy"], 1)
+	require.Equal(t, 30*time.Minute, workflows["timed"][0]["step0"].Timeout)
+	require.Zero(t, workflows["timed"][1]["step1"].Timeout)
+
+	settings := store.GetSettings("timed")
+	require.Equal(t, time.Hour, settings.Deadline)
+	require.Equal(t, "https://example.com/timeout", settings.OnTimeout)
+
+	require.Equal(t, Settings{}, store.GetSettings("legacy"))
+	require.Equal(t, Settings{}, store.GetSettings("unknown"))
+}
+
+func TestNewStoreFromFile_InvalidWorkflow(t *testing.T) {
+	filePath := writeTempFile(t, `
+workflows:
+  broken: "not a workflow"
+`)
+
+	_, err := NewConfigStoreFromFile(filePath)
+	require.Error(t, err)
+}
+
+func TestNewStoreFromFile_ChildWorkflows(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		expectedErr string
+	}{
+		{
+			name: "valid child workflow",
+			yaml: `
+workflows:
+  onboarding:
+    - step0:
+        workflow: "verify"
+        onchildfailure: "continue"
+  verify:
+    - step0:
+        retryafter: "5s"
+`,
+		},
+		{
+			name: "unknown child workflow",
+			yaml: `
+workflows:
+  onboarding:
+    - step0:
+        workflow: "missing"
+`,
+			expectedErr: `starts unknown workflow "missing"`,
+		},
+		{
+			name: "invalid failure policy",
+			yaml: `
+workflows:
+  onboarding:
+    - step0:
+        workflow: "verify"
+        onchildfailure: "ignore"
+  verify:
+    - step0:
+        retryafter: "5s"
+`,
+			expectedErr: `invalid onchildfailure "ignore"`,
+		},
+		{
+			name: "workflow starting itself",
+			yaml: `
+workflows:
+  a:
+    - step0:
+        workflow: "b"
+  b:
+    - step0:
+        workflow: "a"
+`,
+			expectedErr: "starts itself",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewConfigStoreFromFile(writeTempFile(t, tt.yaml))
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNewStoreFromFile_Schedules(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    string
+		expectedErr string
+	}{
+		{name: "cron schedule", settings: `schedule: "0 2 * * *"
+    timezone: "Europe/London"
+    missed_runs: "all"`},
+		{name: "interval schedule", settings: `schedule: "@every 15m"`},
+		{name: "invalid schedule", settings: `schedule: "0 25 * * *"`, expectedErr: "hour field"},
+		{name: "invalid timezone", settings: `timezone: "Mars/Olympus_Mons"`, expectedErr: "invalid timezone"},
+		{name: "invalid missed runs policy", settings: `missed_runs: "sometimes"`, expectedErr: `invalid missed_runs "sometimes"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewConfigStoreFromFile(writeTempFile(t, `
+workflows:
+  nightly:
+    `+tt.settings+`
+    steps:
+      - step0:
+          retryafter: "1h"
+`))
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, store.GetSettings("nightly"))
+		})
+	}
+}
+
+func TestNewStoreFromFile_MaxConcurrentRuns(t *testing.T) {
+	store, err := NewConfigStoreFromFile(writeTempFile(t, `
+workflows:
+  export:
+    max_concurrent_runs: 5
+    steps:
+      - step0:
+          retryafter: "1h"
+`))
+	require.NoError(t, err)
+	require.Equal(t, 5, store.GetSettings("export").MaxConcurrentRuns)
+
+	_, err = NewConfigStoreFromFile(writeTempFile(t, `
+workflows:
+  export:
+    max_concurrent_runs: -1
+    steps:
+      - step0:
+          retryafter: "1h"
+`))
+	require.ErrorContains(t, err, "invalid max_concurrent_runs -1")
+}
+
+func TestNewStoreFromFile_Retention(t *testing.T) {
+	store, err := NewConfigStoreFromFile(writeTempFile(t, `
+workflows:
+  export:
+    retention:
+      completed: "168h"
+      timed_out: "24h"
+    steps:
+      - step0:
+          retryafter: "1h"
+`))
+	require.NoError(t, err)
+
+	retention := store.GetSettings("export").Retention
+	require.Equal(t, Retention{Completed: 168 * time.Hour, TimedOut: 24 * time.Hour}, retention)
+	require.Equal(t,
+		Retention{Completed: 168 * time.Hour, Failed: 720 * time.Hour, TimedOut: 24 * time.Hour},
+		retention.Or(Retention{Completed: time.Hour, Failed: 720 * time.Hour}),
+	)
+
+	_, err = NewConfigStoreFromFile(writeTempFile(t, `
+workflows:
+  export:
+    retention:
+      failed: "-1h"
+    steps:
+      - step0:
+          retryafter: "1h"
+`))
+	require.ErrorContains(t, err, "invalid failed retention -1h0m0s")
+}
+
+func TestNewStoreFromFile_Namespaces(t *testing.T) {
+	store, err := NewConfigStoreFromFile(writeTempFile(t, `
+namespaces:
+  billing:
+    max_active_runs: 10
+workflows:
+  invoice:
+    namespace: billing
+    steps:
+      - step0:
+          workflow: "charge"
+  charge:
+    namespace: billing
+    steps:
+      - step0:
+          retryafter: "1h"
+  export:
+    - step0:
+        retryafter: "1h"
+`))
+	require.NoError(t, err)
+	require.Equal(t, "billing", store.NamespaceOf("invoice"))
+	require.Equal(t, DefaultNamespace, store.NamespaceOf("export"))
+	require.Equal(t, DefaultNamespace, store.NamespaceOf("unknown"))
+	require.Equal(t, 10, store.GetNamespace("billing").MaxActiveRuns)
+	require.Zero(t, store.GetNamespace(DefaultNamespace).MaxActiveRuns)
+
+	tests := []struct {
+		name        string
+		yamlContent string
+		expectedErr string
+	}{
+		{
+			name: "child workflow of another namespace",
+			yamlContent: `
+workflows:
+  invoice:
+    namespace: billing
+    steps:
+      - step0:
+          workflow: "export"
+  export:
+    - step0:
+        retryafter: "1h"
+`,
+			expectedErr: `workflow invoice: step0 starts workflow "export" of another namespace`,
+		},
+		{
+			name: "invalid namespace name",
+			yamlContent: `
+workflows:
+  invoice:
+    namespace: Billing Team
+    steps:
+      - step0:
+          retryafter: "1h"
+`,
+			expectedErr: `invalid namespace "Billing Team"`,
+		},
+		{
+			name: "negative quota",
+			yamlContent: `
+namespaces:
+  billing:
+    max_active_runs: -1
+workflows:
+  invoice:
+    - step0:
+        retryafter: "1h"
+`,
+			expectedErr: "namespace billing: invalid max_active_runs -1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewConfigStoreFromFile(writeTempFile(t, tt.yamlContent))
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
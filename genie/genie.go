@@ -147,6 +147,24 @@ func (s *Store) Get(key string) (any, bool) {
 	return val, ok
 }
 
+// Delete removes the key and its value from the store. Deleting a key
+// that does not exist is a no-op. This operation is thread-safe and can
+// be called concurrently from multiple goroutines.
+//
+// Parameters:
+//   - key: The key to remove
+//
+// Example:
+//
+//	store.Delete("username")
+//	_, exists := store.Get("username") // exists is false
+func (s *Store) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, key)
+}
+
 // Backup creates a persistent backup of the current store data to disk.
 // The backup operation is atomic - it writes to a temporary file first,
 // then atomically renames it to the target file to prevent corruption
@@ -244,7 +262,9 @@ func (s *Store) StartAutoBackup(interval time.Duration) {
 		return // already running
 	}
 	s.autoMode = true
-	backupFunc := func() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
 		ticker := time.NewTicker(interval)
 		for {
 			select {
@@ -259,9 +279,7 @@ func (s *Store) StartAutoBackup(interval time.Duration) {
 				return
 			}
 		}
-	}
-
-	s.wg.Go(backupFunc)
+	}()
 }
 
 // StopAutoBackup stops the automatic backup process if it's currently running.
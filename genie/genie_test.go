@@ -36,6 +36,21 @@ func TestStore_SetGet(t *testing.T) {
 	require.Equal(t, expectedMap, val)
 }
 
+func TestStore_Delete(t *testing.T) {
+	s, err := NewStore()
+	require.NoError(t, err)
+
+	s.Set("foo", "bar")
+	s.Delete("foo")
+	_, ok := s.Get("foo")
+	require.False(t, ok)
+
+	// deleting a missing key is a no-op
+	s.Delete("missing")
+	_, ok = s.Get("missing")
+	require.False(t, ok)
+}
+
 func TestStore_BackupAndRestore(t *testing.T) {
 	s, err := NewStore()
 	require.NoError(t, err)
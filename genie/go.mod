@@ -1,6 +1,6 @@
 module github.com/windevkay/forge/genie/v2
 
-go 1.25.0
+go 1.24.1
 
 require github.com/stretchr/testify v1.10.0
 